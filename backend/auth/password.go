@@ -0,0 +1,43 @@
+package auth
+
+import (
+	"errors"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// MinPasswordLength is the shortest password accepted at registration
+	MinPasswordLength = 8
+	// MaxPasswordLength is bcrypt's input limit; longer passwords would be silently truncated
+	MaxPasswordLength = 72
+)
+
+// ErrPasswordLength is returned for passwords outside the accepted length range
+var ErrPasswordLength = errors.New("password must be between 8 and 72 characters")
+
+// dummyHash is compared against when a login names an unknown user, so that
+// response times don't reveal which emails are registered
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("kyarafit-dummy-password"), bcrypt.DefaultCost)
+
+// HashPassword hashes a password with bcrypt
+func HashPassword(password string) (string, error) {
+	if len(password) < MinPasswordLength || len(password) > MaxPasswordLength {
+		return "", ErrPasswordLength
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// CheckPassword reports whether password matches hash. A nil hash, as stored
+// for users who signed up through an external provider, never matches.
+func CheckPassword(hash *string, password string) bool {
+	if hash == nil {
+		bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+		return false
+	}
+	return bcrypt.CompareHashAndPassword([]byte(*hash), []byte(password)) == nil
+}
@@ -0,0 +1,82 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// TokenConfig holds the settings used to issue tokens
+type TokenConfig struct {
+	Secret          string
+	Issuer          string
+	Audience        string // optional
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+}
+
+// TokenIssuer signs access tokens and generates refresh tokens
+type TokenIssuer struct {
+	config TokenConfig
+}
+
+// NewTokenIssuer creates a new TokenIssuer
+func NewTokenIssuer(config TokenConfig) *TokenIssuer {
+	return &TokenIssuer{config: config}
+}
+
+// AccessTokenTTL returns how long issued access tokens are valid
+func (i *TokenIssuer) AccessTokenTTL() time.Duration {
+	return i.config.AccessTokenTTL
+}
+
+// RefreshTokenTTL returns how long issued refresh tokens are valid
+func (i *TokenIssuer) RefreshTokenTTL() time.Duration {
+	return i.config.RefreshTokenTTL
+}
+
+// IssueAccessToken signs a short-lived HS256 access token for a user. The
+// user ID goes in the sub claim, which is what the JWT middleware reads.
+func (i *TokenIssuer) IssueAccessToken(userID uuid.UUID) (string, error) {
+	now := time.Now()
+	claims := jwt.RegisteredClaims{
+		Subject:   userID.String(),
+		Issuer:    i.config.Issuer,
+		IssuedAt:  jwt.NewNumericDate(now),
+		NotBefore: jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(i.config.AccessTokenTTL)),
+		ID:        uuid.NewString(),
+	}
+	if i.config.Audience != "" {
+		claims.Audience = jwt.ClaimStrings{i.config.Audience}
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(i.config.Secret))
+	if err != nil {
+		return "", fmt.Errorf("failed to sign access token: %w", err)
+	}
+	return token, nil
+}
+
+// NewRefreshToken generates an opaque refresh token. Only its hash is stored
+// server-side; the token itself is handed to the client once.
+func NewRefreshToken() (token string, hash string, err error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+	token = base64.RawURLEncoding.EncodeToString(buf)
+	return token, HashRefreshToken(token), nil
+}
+
+// HashRefreshToken returns the hash a refresh token is stored under
+func HashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,308 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"kyarafit-backend/models"
+)
+
+// ErrBuildExpenseNotFound is returned when an expense doesn't exist on the build
+var ErrBuildExpenseNotFound = errors.New("build expense not found")
+
+// BuildExpenseRepository manages build expense ledgers. Writes to the ledger
+// update builds.spent_minor through a trigger, and entries for linked pieces are
+// created and kept up to date by triggers on build_pieces and pieces.
+type BuildExpenseRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewBuildExpenseRepository(db *pgxpool.Pool) *BuildExpenseRepository {
+	return &BuildExpenseRepository{db: db}
+}
+
+const expenseColumns = `id, build_id, source, amount_minor, currency, description, vendor, category, spent_on, piece_id, notes, created_at, updated_at`
+
+func scanExpense(row pgx.Row, expense *models.BuildExpense) error {
+	return row.Scan(
+		&expense.ID,
+		&expense.BuildID,
+		&expense.Source,
+		&expense.Amount.MinorUnits,
+		&expense.Amount.Currency,
+		&expense.Description,
+		&expense.Vendor,
+		&expense.Category,
+		&expense.SpentOn,
+		&expense.PieceID,
+		&expense.Notes,
+		&expense.CreatedAt,
+		&expense.UpdatedAt,
+	)
+}
+
+// CreateExpense records a manual expense on a build
+func (r *BuildExpenseRepository) CreateExpense(expense *models.BuildExpense) error {
+	ctx := context.Background()
+	query := `
+		INSERT INTO build_expenses (id, build_id, source, amount_minor, currency, description, vendor, category, spent_on, piece_id, notes, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		RETURNING created_at, updated_at`
+
+	err := r.db.QueryRow(
+		ctx,
+		query,
+		expense.ID,
+		expense.BuildID,
+		expense.Source,
+		expense.Amount.MinorUnits,
+		expense.Amount.Currency,
+		expense.Description,
+		expense.Vendor,
+		expense.Category,
+		expense.SpentOn,
+		expense.PieceID,
+		expense.Notes,
+		expense.CreatedAt,
+		expense.UpdatedAt,
+	).Scan(&expense.CreatedAt, &expense.UpdatedAt)
+
+	if err != nil {
+		return fmt.Errorf("failed to create build expense: %w", err)
+	}
+
+	return nil
+}
+
+// GetExpense retrieves an expense on a build
+func (r *BuildExpenseRepository) GetExpense(buildID, expenseID uuid.UUID) (*models.BuildExpense, error) {
+	ctx := context.Background()
+	query := `SELECT ` + expenseColumns + ` FROM build_expenses WHERE build_id = $1 AND id = $2`
+
+	expense := &models.BuildExpense{}
+	if err := scanExpense(r.db.QueryRow(ctx, query, buildID, expenseID), expense); err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrBuildExpenseNotFound
+		}
+		return nil, fmt.Errorf("failed to get build expense: %w", err)
+	}
+
+	return expense, nil
+}
+
+// ListExpenses retrieves a build's ledger, most recent first
+func (r *BuildExpenseRepository) ListExpenses(buildID uuid.UUID) ([]*models.BuildExpense, error) {
+	ctx := context.Background()
+	query := `
+		SELECT ` + expenseColumns + `
+		FROM build_expenses
+		WHERE build_id = $1
+		ORDER BY spent_on DESC, created_at DESC`
+
+	rows, err := r.db.Query(ctx, query, buildID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get build expenses: %w", err)
+	}
+	defer rows.Close()
+
+	var expenses []*models.BuildExpense
+	for rows.Next() {
+		expense := &models.BuildExpense{}
+		if err := scanExpense(rows, expense); err != nil {
+			return nil, fmt.Errorf("failed to scan build expense: %w", err)
+		}
+		expenses = append(expenses, expense)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get build expenses: %w", err)
+	}
+
+	return expenses, nil
+}
+
+// UpdateExpense updates every editable field of an expense
+func (r *BuildExpenseRepository) UpdateExpense(expense *models.BuildExpense) error {
+	ctx := context.Background()
+	query := `
+		UPDATE build_expenses
+		SET amount_minor = $3, currency = $4, description = $5, vendor = $6, category = $7, spent_on = $8, piece_id = $9, notes = $10, updated_at = $11
+		WHERE build_id = $1 AND id = $2
+		RETURNING updated_at`
+
+	err := r.db.QueryRow(
+		ctx,
+		query,
+		expense.BuildID,
+		expense.ID,
+		expense.Amount.MinorUnits,
+		expense.Amount.Currency,
+		expense.Description,
+		expense.Vendor,
+		expense.Category,
+		expense.SpentOn,
+		expense.PieceID,
+		expense.Notes,
+		expense.UpdatedAt,
+	).Scan(&expense.UpdatedAt)
+
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return ErrBuildExpenseNotFound
+		}
+		return fmt.Errorf("failed to update build expense: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteExpense removes an expense from a build's ledger
+func (r *BuildExpenseRepository) DeleteExpense(buildID, expenseID uuid.UUID) error {
+	ctx := context.Background()
+	query := `DELETE FROM build_expenses WHERE build_id = $1 AND id = $2`
+
+	result, err := r.db.Exec(ctx, query, buildID, expenseID)
+	if err != nil {
+		return fmt.Errorf("failed to delete build expense: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrBuildExpenseNotFound
+	}
+
+	return nil
+}
+
+// ExpenseFilter narrows an expense breakdown. Zero fields don't filter.
+type ExpenseFilter struct {
+	BuildID *uuid.UUID
+	From    *time.Time // inclusive
+	To      *time.Time // inclusive
+}
+
+// GetExpenseBreakdown totals a user's expenses by build, category, currency
+// and month, converted into currency at the rate of the day each was spent.
+// Months run from From, or the first month with an expense, to To, or the
+// last month with one, with empty months included.
+func (r *BuildExpenseRepository) GetExpenseBreakdown(userID uuid.UUID, filter ExpenseFilter, currency string) (*models.ExpenseBreakdown, error) {
+	ctx := context.Background()
+
+	// The grouping sets return one row per build, category, currency and
+	// month, and one over all entries. Each currency is also totalled as it
+	// is, unconverted.
+	query := `
+		SELECT GROUPING(build_id) = 0, GROUPING(category) = 0, GROUPING(currency) = 0, GROUPING(month) = 0,
+			build_id, build_name, category, currency, month,
+			COUNT(*), COALESCE(SUM(amount), 0)::bigint, COALESCE(SUM(amount_minor), 0)::bigint, COUNT(*) FILTER (WHERE amount IS NULL)
+		FROM (
+			SELECT e.build_id, b.name AS build_name, e.category, e.currency::text AS currency, e.amount_minor,
+				convert_minor(e.amount_minor, e.currency, $5, e.spent_on) AS amount,
+				date_trunc('month', e.spent_on)::date AS month
+			FROM build_expenses e
+			JOIN builds b ON b.id = e.build_id
+			WHERE b.user_id = $1
+				AND ($2::uuid IS NULL OR e.build_id = $2)
+				AND ($3::date IS NULL OR e.spent_on >= $3)
+				AND ($4::date IS NULL OR e.spent_on <= $4)
+		) e
+		GROUP BY GROUPING SETS ((build_id, build_name), (category), (currency), (month), ())
+		ORDER BY SUM(amount) DESC NULLS LAST, build_name, category, currency`
+
+	rows, err := r.db.Query(ctx, query, userID, filter.BuildID, filter.From, filter.To, currency)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get expense breakdown: %w", err)
+	}
+	defer rows.Close()
+
+	breakdown := &models.ExpenseBreakdown{
+		Total:      models.Money{Currency: currency},
+		ByCategory: []models.ExpenseGroup{},
+		ByCurrency: []models.ExpenseGroup{},
+	}
+	var byBuild []models.BuildExpenseTotal
+	monthly := make(map[string]models.MonthlyExpense)
+	var first, last *time.Time
+
+	for rows.Next() {
+		var (
+			groupedBuild, groupedCategory, groupedCurrency, groupedMonth bool
+			buildID                                                      *uuid.UUID
+			buildName, category, entryCurrency                           *string
+			month                                                        *time.Time
+			entries, unconverted                                         int
+			converted, native                                            int64
+		)
+		if err := rows.Scan(
+			&groupedBuild,
+			&groupedCategory,
+			&groupedCurrency,
+			&groupedMonth,
+			&buildID,
+			&buildName,
+			&category,
+			&entryCurrency,
+			&month,
+			&entries,
+			&converted,
+			&native,
+			&unconverted,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan expense breakdown: %w", err)
+		}
+
+		amount := models.Money{MinorUnits: converted, Currency: currency}
+		switch {
+		case groupedBuild:
+			byBuild = append(byBuild, models.BuildExpenseTotal{BuildID: *buildID, Name: *buildName, Entries: entries, Amount: amount})
+		case groupedCategory:
+			breakdown.ByCategory = append(breakdown.ByCategory, models.ExpenseGroup{Name: category, Entries: entries, Amount: amount})
+		case groupedCurrency:
+			breakdown.ByCurrency = append(breakdown.ByCurrency, models.ExpenseGroup{
+				Name:    entryCurrency,
+				Entries: entries,
+				Amount:  models.Money{MinorUnits: native, Currency: *entryCurrency},
+			})
+		case groupedMonth:
+			key := month.Format(monthFormat)
+			monthly[key] = models.MonthlyExpense{Month: key, Entries: entries, Amount: amount}
+			if first == nil || month.Before(*first) {
+				first = month
+			}
+			if last == nil || month.After(*last) {
+				last = month
+			}
+		default:
+			breakdown.Entries, breakdown.Total, breakdown.UnconvertedEntries = entries, amount, unconverted
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get expense breakdown: %w", err)
+	}
+
+	if filter.BuildID == nil {
+		breakdown.ByBuild = byBuild
+	}
+
+	if filter.From != nil {
+		first = filter.From
+	}
+	if filter.To != nil {
+		last = filter.To
+	}
+	breakdown.ByMonth = []models.MonthlyExpense{}
+	if first != nil && last != nil {
+		for _, key := range monthKeys(*first, *last) {
+			month, ok := monthly[key]
+			if !ok {
+				month = models.MonthlyExpense{Month: key, Amount: models.Money{Currency: currency}}
+			}
+			breakdown.ByMonth = append(breakdown.ByMonth, month)
+		}
+	}
+
+	return breakdown, nil
+}
@@ -0,0 +1,239 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"kyarafit-backend/models"
+)
+
+var (
+	// ErrPieceAlreadyInBuild is returned when a piece is attached to a build twice
+	ErrPieceAlreadyInBuild = errors.New("piece is already linked to this build")
+	// ErrInvalidPieceOrder is returned when a reorder request doesn't list exactly the build's pieces
+	ErrInvalidPieceOrder = errors.New("piece order must list every piece in the build exactly once")
+)
+
+type BuildPieceRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewBuildPieceRepository(db *pgxpool.Pool) *BuildPieceRepository {
+	return &BuildPieceRepository{db: db}
+}
+
+// AddPieceToBuild links a piece to a build. A nil sort order appends the piece at the end.
+func (r *BuildPieceRepository) AddPieceToBuild(bp *models.BuildPiece, sortOrder *int) error {
+	ctx := context.Background()
+	query := `
+		INSERT INTO build_pieces (id, build_id, piece_id, role, quantity, sort_order, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5,
+			COALESCE($6, (SELECT COALESCE(MAX(sort_order) + 1, 0) FROM build_pieces WHERE build_id = $2)),
+			$7, $8)
+		RETURNING id, sort_order, created_at, updated_at`
+
+	err := r.db.QueryRow(
+		ctx,
+		query,
+		bp.ID,
+		bp.BuildID,
+		bp.PieceID,
+		bp.Role,
+		bp.Quantity,
+		sortOrder,
+		bp.CreatedAt,
+		bp.UpdatedAt,
+	).Scan(&bp.ID, &bp.SortOrder, &bp.CreatedAt, &bp.UpdatedAt)
+
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return ErrPieceAlreadyInBuild
+		}
+		return fmt.Errorf("failed to add piece to build: %w", err)
+	}
+
+	return nil
+}
+
+// GetBuildPieces retrieves every piece linked to a build, with the piece itself, in sort order
+func (r *BuildPieceRepository) GetBuildPieces(buildID uuid.UUID) ([]*models.BuildPiece, error) {
+	ctx := context.Background()
+	query := `
+		SELECT bp.id, bp.build_id, bp.piece_id, bp.role, bp.quantity, bp.sort_order, bp.created_at, bp.updated_at,
+			p.id, p.user_id, p.name, p.description, p.image_url, p.image_bg_removed_url, p.thumbnail_url, p.image_key, p.image_bg_removed_key, p.thumbnail_key, p.category, p.tags, p.source_link, p.purchase_date, p.price_minor, p.price_currency, p.created_at, p.updated_at
+		FROM build_pieces bp
+		JOIN pieces p ON p.id = bp.piece_id
+		WHERE bp.build_id = $1
+		ORDER BY bp.sort_order ASC, bp.created_at ASC`
+
+	rows, err := r.db.Query(ctx, query, buildID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get build pieces: %w", err)
+	}
+	defer rows.Close()
+
+	var buildPieces []*models.BuildPiece
+	for rows.Next() {
+		bp := &models.BuildPiece{Piece: &models.Piece{}}
+		var price nullMoney
+		err := rows.Scan(
+			&bp.ID,
+			&bp.BuildID,
+			&bp.PieceID,
+			&bp.Role,
+			&bp.Quantity,
+			&bp.SortOrder,
+			&bp.CreatedAt,
+			&bp.UpdatedAt,
+			&bp.Piece.ID,
+			&bp.Piece.UserID,
+			&bp.Piece.Name,
+			&bp.Piece.Description,
+			&bp.Piece.ImageURL,
+			&bp.Piece.CutoutURL,
+			&bp.Piece.ThumbnailURL,
+			&bp.Piece.ImageKey,
+			&bp.Piece.CutoutKey,
+			&bp.Piece.ThumbnailKey,
+			&bp.Piece.Category,
+			&bp.Piece.Tags,
+			&bp.Piece.SourceLink,
+			&bp.Piece.PurchaseDate,
+			&price.minorUnits,
+			&price.currency,
+			&bp.Piece.CreatedAt,
+			&bp.Piece.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan build piece: %w", err)
+		}
+		bp.Piece.Price = price.money()
+		buildPieces = append(buildPieces, bp)
+	}
+
+	return buildPieces, nil
+}
+
+// GetBuildPiece retrieves the link between a build and a piece
+func (r *BuildPieceRepository) GetBuildPiece(buildID, pieceID uuid.UUID) (*models.BuildPiece, error) {
+	ctx := context.Background()
+	query := `
+		SELECT id, build_id, piece_id, role, quantity, sort_order, created_at, updated_at
+		FROM build_pieces
+		WHERE build_id = $1 AND piece_id = $2`
+
+	bp := &models.BuildPiece{}
+	err := r.db.QueryRow(ctx, query, buildID, pieceID).Scan(
+		&bp.ID,
+		&bp.BuildID,
+		&bp.PieceID,
+		&bp.Role,
+		&bp.Quantity,
+		&bp.SortOrder,
+		&bp.CreatedAt,
+		&bp.UpdatedAt,
+	)
+
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, fmt.Errorf("build piece not found")
+		}
+		return nil, fmt.Errorf("failed to get build piece: %w", err)
+	}
+
+	return bp, nil
+}
+
+// UpdateBuildPiece updates the role, quantity and sort order of a build piece link
+func (r *BuildPieceRepository) UpdateBuildPiece(bp *models.BuildPiece) error {
+	ctx := context.Background()
+	query := `
+		UPDATE build_pieces
+		SET role = $3, quantity = $4, sort_order = $5, updated_at = $6
+		WHERE build_id = $1 AND piece_id = $2
+		RETURNING updated_at`
+
+	err := r.db.QueryRow(
+		ctx,
+		query,
+		bp.BuildID,
+		bp.PieceID,
+		bp.Role,
+		bp.Quantity,
+		bp.SortOrder,
+		bp.UpdatedAt,
+	).Scan(&bp.UpdatedAt)
+
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return fmt.Errorf("build piece not found")
+		}
+		return fmt.Errorf("failed to update build piece: %w", err)
+	}
+
+	return nil
+}
+
+// RemovePieceFromBuild unlinks a piece from a build
+func (r *BuildPieceRepository) RemovePieceFromBuild(buildID, pieceID uuid.UUID) error {
+	ctx := context.Background()
+	query := `DELETE FROM build_pieces WHERE build_id = $1 AND piece_id = $2`
+
+	result, err := r.db.Exec(ctx, query, buildID, pieceID)
+	if err != nil {
+		return fmt.Errorf("failed to remove piece from build: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("build piece not found")
+	}
+
+	return nil
+}
+
+// ReorderBuildPieces sets each piece's sort order to its position in pieceIDs.
+// pieceIDs must list every piece linked to the build exactly once.
+func (r *BuildPieceRepository) ReorderBuildPieces(buildID uuid.UUID, pieceIDs []uuid.UUID) error {
+	ctx := context.Background()
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var linked int
+	err = tx.QueryRow(ctx, `SELECT COUNT(*) FROM build_pieces WHERE build_id = $1`, buildID).Scan(&linked)
+	if err != nil {
+		return fmt.Errorf("failed to count build pieces: %w", err)
+	}
+	if linked != len(pieceIDs) {
+		return ErrInvalidPieceOrder
+	}
+
+	query := `
+		UPDATE build_pieces bp
+		SET sort_order = o.position - 1, updated_at = NOW()
+		FROM unnest($2::uuid[]) WITH ORDINALITY AS o(piece_id, position)
+		WHERE bp.build_id = $1 AND bp.piece_id = o.piece_id`
+
+	result, err := tx.Exec(ctx, query, buildID, pieceIDs)
+	if err != nil {
+		return fmt.Errorf("failed to reorder build pieces: %w", err)
+	}
+	if result.RowsAffected() != int64(len(pieceIDs)) {
+		return ErrInvalidPieceOrder
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
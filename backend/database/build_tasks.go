@@ -0,0 +1,313 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"kyarafit-backend/models"
+)
+
+var (
+	// ErrBuildTaskNotFound is returned when a task doesn't exist on the build
+	ErrBuildTaskNotFound = errors.New("build task not found")
+	// ErrInvalidTaskOrder is returned when a reorder request doesn't list exactly the build's tasks
+	ErrInvalidTaskOrder = errors.New("task order must list every task in the build exactly once")
+)
+
+type BuildTaskRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewBuildTaskRepository(db *pgxpool.Pool) *BuildTaskRepository {
+	return &BuildTaskRepository{db: db}
+}
+
+// buildTaskColumns selects a task with the name of its build. Queries join
+// build_tasks as t with builds as b.
+const buildTaskColumns = `t.id, t.build_id, b.name, t.kind, t.title, t.due_date, t.done, t.done_at, t.sort_order, t.piece_id, t.created_at, t.updated_at`
+
+func scanBuildTask(row pgx.Row, task *models.BuildTask) error {
+	return row.Scan(
+		&task.ID,
+		&task.BuildID,
+		&task.BuildName,
+		&task.Kind,
+		&task.Title,
+		&task.DueDate,
+		&task.Done,
+		&task.DoneAt,
+		&task.SortOrder,
+		&task.PieceID,
+		&task.CreatedAt,
+		&task.UpdatedAt,
+	)
+}
+
+// CreateTask adds a task to a build. A nil sort order appends the task at the end.
+func (r *BuildTaskRepository) CreateTask(task *models.BuildTask, sortOrder *int) error {
+	ctx := context.Background()
+	query := `
+		INSERT INTO build_tasks (id, build_id, kind, title, due_date, done, done_at, sort_order, piece_id, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7,
+			COALESCE($8, (SELECT COALESCE(MAX(sort_order) + 1, 0) FROM build_tasks WHERE build_id = $2)),
+			$9, $10, $11)
+		RETURNING sort_order, created_at, updated_at`
+
+	err := r.db.QueryRow(
+		ctx,
+		query,
+		task.ID,
+		task.BuildID,
+		task.Kind,
+		task.Title,
+		task.DueDate,
+		task.Done,
+		task.DoneAt,
+		sortOrder,
+		task.PieceID,
+		task.CreatedAt,
+		task.UpdatedAt,
+	).Scan(&task.SortOrder, &task.CreatedAt, &task.UpdatedAt)
+
+	if err != nil {
+		return fmt.Errorf("failed to create build task: %w", err)
+	}
+
+	return nil
+}
+
+// GetBuildTasks retrieves every task on a build in sort order
+func (r *BuildTaskRepository) GetBuildTasks(buildID uuid.UUID) ([]*models.BuildTask, error) {
+	ctx := context.Background()
+	query := `
+		SELECT ` + buildTaskColumns + `
+		FROM build_tasks t
+		JOIN builds b ON b.id = t.build_id
+		WHERE t.build_id = $1
+		ORDER BY t.sort_order ASC, t.created_at ASC`
+
+	rows, err := r.db.Query(ctx, query, buildID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get build tasks: %w", err)
+	}
+	defer rows.Close()
+
+	var tasks []*models.BuildTask
+	for rows.Next() {
+		task := &models.BuildTask{}
+		if err := scanBuildTask(rows, task); err != nil {
+			return nil, fmt.Errorf("failed to scan build task: %w", err)
+		}
+		tasks = append(tasks, task)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get build tasks: %w", err)
+	}
+
+	return tasks, nil
+}
+
+// GetTask retrieves a task on a build
+func (r *BuildTaskRepository) GetTask(buildID, taskID uuid.UUID) (*models.BuildTask, error) {
+	ctx := context.Background()
+	query := `
+		SELECT ` + buildTaskColumns + `
+		FROM build_tasks t
+		JOIN builds b ON b.id = t.build_id
+		WHERE t.build_id = $1 AND t.id = $2`
+
+	task := &models.BuildTask{}
+	if err := scanBuildTask(r.db.QueryRow(ctx, query, buildID, taskID), task); err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrBuildTaskNotFound
+		}
+		return nil, fmt.Errorf("failed to get build task: %w", err)
+	}
+
+	return task, nil
+}
+
+// UpdateTask updates every editable field of a task
+func (r *BuildTaskRepository) UpdateTask(task *models.BuildTask) error {
+	ctx := context.Background()
+	query := `
+		UPDATE build_tasks
+		SET kind = $3, title = $4, due_date = $5, done = $6, done_at = $7, sort_order = $8, piece_id = $9, updated_at = $10
+		WHERE build_id = $1 AND id = $2
+		RETURNING updated_at`
+
+	err := r.db.QueryRow(
+		ctx,
+		query,
+		task.BuildID,
+		task.ID,
+		task.Kind,
+		task.Title,
+		task.DueDate,
+		task.Done,
+		task.DoneAt,
+		task.SortOrder,
+		task.PieceID,
+		task.UpdatedAt,
+	).Scan(&task.UpdatedAt)
+
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return ErrBuildTaskNotFound
+		}
+		return fmt.Errorf("failed to update build task: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteTask removes a task from a build
+func (r *BuildTaskRepository) DeleteTask(buildID, taskID uuid.UUID) error {
+	ctx := context.Background()
+	query := `DELETE FROM build_tasks WHERE build_id = $1 AND id = $2`
+
+	result, err := r.db.Exec(ctx, query, buildID, taskID)
+	if err != nil {
+		return fmt.Errorf("failed to delete build task: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrBuildTaskNotFound
+	}
+
+	return nil
+}
+
+// ReorderTasks sets each task's sort order to its position in taskIDs.
+// taskIDs must list every task on the build exactly once.
+func (r *BuildTaskRepository) ReorderTasks(buildID uuid.UUID, taskIDs []uuid.UUID) error {
+	ctx := context.Background()
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var total int
+	err = tx.QueryRow(ctx, `SELECT COUNT(*) FROM build_tasks WHERE build_id = $1`, buildID).Scan(&total)
+	if err != nil {
+		return fmt.Errorf("failed to count build tasks: %w", err)
+	}
+	if total != len(taskIDs) {
+		return ErrInvalidTaskOrder
+	}
+
+	query := `
+		UPDATE build_tasks t
+		SET sort_order = o.position - 1, updated_at = NOW()
+		FROM unnest($2::uuid[]) WITH ORDINALITY AS o(task_id, position)
+		WHERE t.build_id = $1 AND t.id = o.task_id`
+
+	result, err := tx.Exec(ctx, query, buildID, taskIDs)
+	if err != nil {
+		return fmt.Errorf("failed to reorder build tasks: %w", err)
+	}
+	if result.RowsAffected() != int64(len(taskIDs)) {
+		return ErrInvalidTaskOrder
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// GetTaskProgress returns how many tasks each build has and how many are done
+func (r *BuildTaskRepository) GetTaskProgress(buildIDs []uuid.UUID) (map[uuid.UUID]models.TaskProgress, error) {
+	progress := make(map[uuid.UUID]models.TaskProgress, len(buildIDs))
+	if len(buildIDs) == 0 {
+		return progress, nil
+	}
+
+	ctx := context.Background()
+	query := `
+		SELECT build_id, COUNT(*), COUNT(*) FILTER (WHERE done)
+		FROM build_tasks
+		WHERE build_id = ANY($1::uuid[])
+		GROUP BY build_id`
+
+	rows, err := r.db.Query(ctx, query, buildIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get task progress: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id uuid.UUID
+		var p models.TaskProgress
+		if err := rows.Scan(&id, &p.Total, &p.Done); err != nil {
+			return nil, fmt.Errorf("failed to scan task progress: %w", err)
+		}
+		progress[id] = p
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get task progress: %w", err)
+	}
+
+	return progress, nil
+}
+
+// TaskFilter narrows the task feed. Zero fields don't filter.
+type TaskFilter struct {
+	DueBefore *time.Time // exclusive
+	Done      *bool
+	Kind      *models.BuildTaskKind
+	BuildID   *uuid.UUID
+}
+
+// ListTasks lists tasks across all of a user's builds, soonest due first with
+// undated tasks last, and the total number of matching tasks
+func (r *BuildTaskRepository) ListTasks(userID uuid.UUID, filter TaskFilter, limit, offset int) ([]*models.BuildTask, int, error) {
+	ctx := context.Background()
+	where := `
+		FROM build_tasks t
+		JOIN builds b ON b.id = t.build_id
+		WHERE b.user_id = $1
+			AND ($2::date IS NULL OR t.due_date < $2)
+			AND ($3::boolean IS NULL OR t.done = $3)
+			AND ($4::text IS NULL OR t.kind = $4)
+			AND ($5::uuid IS NULL OR t.build_id = $5)`
+	args := []interface{}{userID, filter.DueBefore, filter.Done, filter.Kind, filter.BuildID}
+
+	var total int
+	if err := r.db.QueryRow(ctx, `SELECT COUNT(*)`+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count tasks: %w", err)
+	}
+
+	query := `
+		SELECT ` + buildTaskColumns + where + `
+		ORDER BY t.due_date ASC NULLS LAST, b.name ASC, t.sort_order ASC, t.id ASC
+		LIMIT $6 OFFSET $7`
+
+	rows, err := r.db.Query(ctx, query, append(args, limit, offset)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list tasks: %w", err)
+	}
+	defer rows.Close()
+
+	var tasks []*models.BuildTask
+	for rows.Next() {
+		task := &models.BuildTask{}
+		if err := scanBuildTask(rows, task); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan build task: %w", err)
+		}
+		tasks = append(tasks, task)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to list tasks: %w", err)
+	}
+
+	return tasks, total, nil
+}
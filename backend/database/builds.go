@@ -2,7 +2,9 @@ package database
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
@@ -10,6 +12,10 @@ import (
 	"kyarafit-backend/models"
 )
 
+// ErrBuildStatusChanged is returned when a build's status changed after it was
+// read for an update, so the update was checked against a stale status
+var ErrBuildStatusChanged = errors.New("build status changed since it was read")
+
 type BuildRepository struct {
 	db *pgxpool.Pool
 }
@@ -18,15 +24,53 @@ func NewBuildRepository(db *pgxpool.Pool) *BuildRepository {
 	return &BuildRepository{db: db}
 }
 
-// CreateBuild creates a new build in the database
+const buildColumns = `id, user_id, name, description, character, series, status, priority, currency, budget_minor, spent_minor,
+			start_date, target_date, completed_date, tags, notes, created_at, updated_at`
+
+func scanBuild(row pgx.Row, build *models.Build) error {
+	var budget, spent *int64
+	err := row.Scan(
+		&build.ID,
+		&build.UserID,
+		&build.Name,
+		&build.Description,
+		&build.Character,
+		&build.Series,
+		&build.Status,
+		&build.Priority,
+		&build.Currency,
+		&budget,
+		&spent,
+		&build.StartDate,
+		&build.TargetDate,
+		&build.CompletedDate,
+		&build.Tags,
+		&build.Notes,
+		&build.CreatedAt,
+		&build.UpdatedAt,
+	)
+	build.Budget = moneyIn(budget, build.Currency)
+	build.Spent = moneyIn(spent, build.Currency)
+	return err
+}
+
+// CreateBuild creates a new build in the database, starting its status history
 func (r *BuildRepository) CreateBuild(build *models.Build) error {
 	ctx := context.Background()
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
 	query := `
-		INSERT INTO builds (id, user_id, name, description, character, series, status, priority, budget, spent, start_date, target_date, completed_date, tags, notes, created_at, updated_at)
+		INSERT INTO builds (id, user_id, name, description, character, series, status, priority, currency, budget_minor, start_date, target_date, completed_date, tags, notes, created_at, updated_at)
 		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)
-		RETURNING id, created_at, updated_at`
+		RETURNING id, tags, created_at, updated_at`
 
-	err := r.db.QueryRow(
+	// tags are read back in the vocabulary spelling the triggers store
+	err = tx.QueryRow(
 		ctx,
 		query,
 		build.ID,
@@ -37,8 +81,8 @@ func (r *BuildRepository) CreateBuild(build *models.Build) error {
 		build.Series,
 		build.Status,
 		build.Priority,
-		build.Budget,
-		build.Spent,
+		build.Currency,
+		minorUnits(build.Budget),
 		build.StartDate,
 		build.TargetDate,
 		build.CompletedDate,
@@ -46,45 +90,36 @@ func (r *BuildRepository) CreateBuild(build *models.Build) error {
 		build.Notes,
 		build.CreatedAt,
 		build.UpdatedAt,
-	).Scan(&build.ID, &build.CreatedAt, &build.UpdatedAt)
+	).Scan(&build.ID, &build.Tags, &build.CreatedAt, &build.UpdatedAt)
 
 	if err != nil {
 		return fmt.Errorf("failed to create build: %w", err)
 	}
 
+	err = insertStatusChange(ctx, tx, &models.BuildStatusChange{
+		ID:        uuid.New(),
+		BuildID:   build.ID,
+		ToStatus:  build.Status,
+		ChangedAt: build.CreatedAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
 	return nil
 }
 
 // GetBuildByID retrieves a build by its ID
 func (r *BuildRepository) GetBuildByID(id uuid.UUID) (*models.Build, error) {
 	ctx := context.Background()
-	query := `
-		SELECT id, user_id, name, description, character, series, status, priority, budget, spent, start_date, target_date, completed_date, tags, notes, created_at, updated_at
-		FROM builds
-		WHERE id = $1`
+	query := `SELECT ` + buildColumns + ` FROM builds WHERE id = $1`
 
 	build := &models.Build{}
-	err := r.db.QueryRow(ctx, query, id).Scan(
-		&build.ID,
-		&build.UserID,
-		&build.Name,
-		&build.Description,
-		&build.Character,
-		&build.Series,
-		&build.Status,
-		&build.Priority,
-		&build.Budget,
-		&build.Spent,
-		&build.StartDate,
-		&build.TargetDate,
-		&build.CompletedDate,
-		&build.Tags,
-		&build.Notes,
-		&build.CreatedAt,
-		&build.UpdatedAt,
-	)
-
-	if err != nil {
+	if err := scanBuild(r.db.QueryRow(ctx, query, id), build); err != nil {
 		if err == pgx.ErrNoRows {
 			return nil, fmt.Errorf("build not found")
 		}
@@ -94,110 +129,38 @@ func (r *BuildRepository) GetBuildByID(id uuid.UUID) (*models.Build, error) {
 	return build, nil
 }
 
-// GetBuildsByUserID retrieves all builds for a specific user
-func (r *BuildRepository) GetBuildsByUserID(userID uuid.UUID, limit, offset int) ([]*models.Build, error) {
-	ctx := context.Background()
-	query := `
-		SELECT id, user_id, name, description, character, series, status, priority, budget, spent, start_date, target_date, completed_date, tags, notes, created_at, updated_at
-		FROM builds
-		WHERE user_id = $1
-		ORDER BY created_at DESC
-		LIMIT $2 OFFSET $3`
-
-	rows, err := r.db.Query(ctx, query, userID, limit, offset)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get builds: %w", err)
-	}
-	defer rows.Close()
-
-	var builds []*models.Build
-	for rows.Next() {
-		build := &models.Build{}
-		err := rows.Scan(
-			&build.ID,
-			&build.UserID,
-			&build.Name,
-			&build.Description,
-			&build.Character,
-			&build.Series,
-			&build.Status,
-			&build.Priority,
-			&build.Budget,
-			&build.Spent,
-			&build.StartDate,
-			&build.TargetDate,
-			&build.CompletedDate,
-			&build.Tags,
-			&build.Notes,
-			&build.CreatedAt,
-			&build.UpdatedAt,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan build: %w", err)
-		}
-		builds = append(builds, build)
-	}
-
-	return builds, nil
-}
-
-// GetBuildsByStatus retrieves builds by status for a specific user
-func (r *BuildRepository) GetBuildsByStatus(userID uuid.UUID, status models.BuildStatus, limit, offset int) ([]*models.Build, error) {
+// UpdateBuild updates an existing build. A status change from
+// Build.TransitionTo is recorded in its history in the same transaction.
+// Spent is left to the expense ledger and read back, totalled in the build's
+// currency. The budget must be in that currency.
+//
+// The update only applies while the build still has the status it was read
+// with, the change's from status or else its current one, so concurrent
+// updates can't make a transition from a status the build no longer has. It
+// returns ErrBuildStatusChanged when another update got there first.
+func (r *BuildRepository) UpdateBuild(build *models.Build, change *models.BuildStatusChange) error {
 	ctx := context.Background()
-	query := `
-		SELECT id, user_id, name, description, character, series, status, priority, budget, spent, start_date, target_date, completed_date, tags, notes, created_at, updated_at
-		FROM builds
-		WHERE user_id = $1 AND status = $2
-		ORDER BY created_at DESC
-		LIMIT $3 OFFSET $4`
 
-	rows, err := r.db.Query(ctx, query, userID, status, limit, offset)
+	tx, err := r.db.Begin(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get builds by status: %w", err)
-	}
-	defer rows.Close()
-
-	var builds []*models.Build
-	for rows.Next() {
-		build := &models.Build{}
-		err := rows.Scan(
-			&build.ID,
-			&build.UserID,
-			&build.Name,
-			&build.Description,
-			&build.Character,
-			&build.Series,
-			&build.Status,
-			&build.Priority,
-			&build.Budget,
-			&build.Spent,
-			&build.StartDate,
-			&build.TargetDate,
-			&build.CompletedDate,
-			&build.Tags,
-			&build.Notes,
-			&build.CreatedAt,
-			&build.UpdatedAt,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan build: %w", err)
-		}
-		builds = append(builds, build)
+		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
+	defer tx.Rollback(ctx)
 
-	return builds, nil
-}
-
-// UpdateBuild updates an existing build
-func (r *BuildRepository) UpdateBuild(build *models.Build) error {
-	ctx := context.Background()
+	var spent *int64
 	query := `
 		UPDATE builds
-		SET name = $2, description = $3, character = $4, series = $5, status = $6, priority = $7, budget = $8, spent = $9, start_date = $10, target_date = $11, completed_date = $12, tags = $13, notes = $14, updated_at = $15
-		WHERE id = $1 AND user_id = $16
-		RETURNING updated_at`
+		SET name = $2, description = $3, character = $4, series = $5, status = $6, priority = $7, currency = $8, budget_minor = $9,
+			start_date = $10, target_date = $11, completed_date = $12, tags = $13, notes = $14, updated_at = $15
+		WHERE id = $1 AND user_id = $16 AND status = $17
+		RETURNING spent_minor, tags, updated_at`
+
+	readStatus := build.Status
+	if change != nil && change.FromStatus != nil {
+		readStatus = *change.FromStatus
+	}
 
-	err := r.db.QueryRow(
+	err = tx.QueryRow(
 		ctx,
 		query,
 		build.ID,
@@ -207,8 +170,8 @@ func (r *BuildRepository) UpdateBuild(build *models.Build) error {
 		build.Series,
 		build.Status,
 		build.Priority,
-		build.Budget,
-		build.Spent,
+		build.Currency,
+		minorUnits(build.Budget),
 		build.StartDate,
 		build.TargetDate,
 		build.CompletedDate,
@@ -216,18 +179,77 @@ func (r *BuildRepository) UpdateBuild(build *models.Build) error {
 		build.Notes,
 		build.UpdatedAt,
 		build.UserID,
-	).Scan(&build.UpdatedAt)
+		readStatus,
+	).Scan(&spent, &build.Tags, &build.UpdatedAt)
 
 	if err != nil {
 		if err == pgx.ErrNoRows {
+			var exists bool
+			if err := tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM builds WHERE id = $1 AND user_id = $2)`, build.ID, build.UserID).Scan(&exists); err == nil && exists {
+				return ErrBuildStatusChanged
+			}
 			return fmt.Errorf("build not found or access denied")
 		}
 		return fmt.Errorf("failed to update build: %w", err)
 	}
+	build.Spent = moneyIn(spent, build.Currency)
+
+	if change != nil {
+		if err := insertStatusChange(ctx, tx, change); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
 
 	return nil
 }
 
+// insertStatusChange records a change in a build's status history
+func insertStatusChange(ctx context.Context, tx pgx.Tx, change *models.BuildStatusChange) error {
+	query := `
+		INSERT INTO build_status_history (id, build_id, from_status, to_status, note, changed_at)
+		VALUES ($1, $2, $3, $4, $5, $6)`
+
+	_, err := tx.Exec(ctx, query, change.ID, change.BuildID, change.FromStatus, change.ToStatus, change.Note, change.ChangedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record build status change: %w", err)
+	}
+	return nil
+}
+
+// GetBuildStatusHistory retrieves every status change of a build, oldest first
+func (r *BuildRepository) GetBuildStatusHistory(buildID uuid.UUID) ([]*models.BuildStatusChange, error) {
+	ctx := context.Background()
+	query := `
+		SELECT id, build_id, from_status, to_status, note, changed_at
+		FROM build_status_history
+		WHERE build_id = $1
+		ORDER BY changed_at, id`
+
+	rows, err := r.db.Query(ctx, query, buildID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get build status history: %w", err)
+	}
+	defer rows.Close()
+
+	var history []*models.BuildStatusChange
+	for rows.Next() {
+		change := &models.BuildStatusChange{}
+		if err := rows.Scan(&change.ID, &change.BuildID, &change.FromStatus, &change.ToStatus, &change.Note, &change.ChangedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan build status change: %w", err)
+		}
+		history = append(history, change)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get build status history: %w", err)
+	}
+
+	return history, nil
+}
+
 // DeleteBuild deletes a build by ID
 func (r *BuildRepository) DeleteBuild(id uuid.UUID, userID uuid.UUID) error {
 	ctx := context.Background()
@@ -246,67 +268,149 @@ func (r *BuildRepository) DeleteBuild(id uuid.UUID, userID uuid.UUID) error {
 	return nil
 }
 
-// SearchBuilds searches builds by name, description, character, or series
-func (r *BuildRepository) SearchBuilds(userID uuid.UUID, searchTerm string, limit, offset int) ([]*models.Build, error) {
-	ctx := context.Background()
-	query := `
-		SELECT id, user_id, name, description, character, series, status, priority, budget, spent, start_date, target_date, completed_date, tags, notes, created_at, updated_at
-		FROM builds
-		WHERE user_id = $1 AND (
-			name ILIKE $2 OR 
-			description ILIKE $2 OR 
-			character ILIKE $2 OR
-			series ILIKE $2 OR
-			$2 = ANY(tags)
-		)
-		ORDER BY created_at DESC
-		LIMIT $3 OFFSET $4`
+// GetBuildCount returns the total count of builds for a user
+func (r *BuildRepository) GetBuildCount(userID uuid.UUID) (int, error) {
+	return r.CountBuilds(userID, BuildFilter{})
+}
+
+// BuildFilter narrows and orders a list of builds. Zero fields don't filter.
+type BuildFilter struct {
+	// Statuses keeps builds with any of the statuses
+	Statuses    []models.BuildStatus
+	MinPriority *int
+	MaxPriority *int
+	// Tags keeps builds with any of the tags, or all of them with MatchAllTags
+	Tags         []string
+	MatchAllTags bool
+	// Series and Character match case-insensitively
+	Series     string
+	Character  string
+	StartFrom  *time.Time
+	StartTo    *time.Time
+	TargetFrom *time.Time
+	TargetTo   *time.Time
+	// DueWithinDays keeps unfinished builds with a target date at most this
+	// many days away, including overdue ones
+	DueWithinDays *int
+	// OverBudget keeps builds that have spent more than their budget when
+	// true, and builds with a budget they are still within when false
+	OverBudget *bool
+	// Search matches words anywhere in the build's text, including stemmed
+	// forms, and close misspellings of its name, character or series
+	Search string
+	// Sort is a key of BuildSortKeys, prefixed with "-" to sort descending.
+	// It defaults to "-created_at", or "target_date" with DueWithinDays.
+	Sort string
+}
+
+// buildSortKeys are the columns builds may be sorted by. Amounts sort as
+// stored, in the build's currency, so rows don't shift between pages as
+// today's exchange rate moves.
+var buildSortKeys = map[string]sortKey{
+	"created_at":  {column: "created_at", sqlType: "timestamptz"},
+	"updated_at":  {column: "updated_at", sqlType: "timestamptz"},
+	"name":        {column: "name", sqlType: "text"},
+	"priority":    {column: "priority", sqlType: "numeric", nullable: true},
+	"budget":      {column: "budget_minor", sqlType: "numeric", nullable: true, then: "currency"},
+	"spent":       {column: "spent_minor", sqlType: "numeric", nullable: true, then: "currency"},
+	"start_date":  {column: "start_date", sqlType: "date", nullable: true},
+	"target_date": {column: "target_date", sqlType: "date", nullable: true},
+}
+
+// BuildSortKeys lists the keys BuildFilter.Sort accepts
+func BuildSortKeys() []string {
+	return sortKeyNames(buildSortKeys)
+}
+
+// where adds the filter's conditions to a query over a user's builds
+func (f BuildFilter) where(q *listQuery, userID uuid.UUID) {
+	q.where("user_id = ?", userID)
 
-	searchPattern := "%" + searchTerm + "%"
-	rows, err := r.db.Query(ctx, query, userID, searchPattern, limit, offset)
+	if len(f.Statuses) > 0 {
+		statuses := make([]string, len(f.Statuses))
+		for i, status := range f.Statuses {
+			statuses[i] = string(status)
+		}
+		q.where("status = ANY(?::text[])", statuses)
+	}
+	if f.MinPriority != nil {
+		q.where("priority >= ?", *f.MinPriority)
+	}
+	if f.MaxPriority != nil {
+		q.where("priority <= ?", *f.MaxPriority)
+	}
+	if len(f.Tags) > 0 {
+		if f.MatchAllTags {
+			q.where("tags @> ?::text[]", f.Tags)
+		} else {
+			q.where("tags && ?::text[]", f.Tags)
+		}
+	}
+	if f.Series != "" {
+		q.where("LOWER(series) = LOWER(?)", f.Series)
+	}
+	if f.Character != "" {
+		q.where("LOWER(character) = LOWER(?)", f.Character)
+	}
+	if f.StartFrom != nil {
+		q.where("start_date >= ?::date", *f.StartFrom)
+	}
+	if f.StartTo != nil {
+		q.where("start_date <= ?::date", *f.StartTo)
+	}
+	if f.TargetFrom != nil {
+		q.where("target_date >= ?::date", *f.TargetFrom)
+	}
+	if f.TargetTo != nil {
+		q.where("target_date <= ?::date", *f.TargetTo)
+	}
+	if f.DueWithinDays != nil {
+		q.where("target_date <= CURRENT_DATE + ?::int AND status NOT IN ('complete', 'cancelled')", *f.DueWithinDays)
+	}
+	if f.OverBudget != nil {
+		if *f.OverBudget {
+			q.where("spent_minor > budget_minor")
+		} else {
+			q.where("budget_minor IS NOT NULL AND COALESCE(spent_minor, 0) <= budget_minor")
+		}
+	}
+	if f.Search != "" {
+		q.where(`search_vector @@ websearch_to_tsquery('english', ?)
+			OR ?::text <% name OR ?::text <% character OR ?::text <% series`,
+			f.Search, f.Search, f.Search, f.Search)
+	}
+}
+
+// ListBuilds retrieves a page of a user's builds matching the filter, starting
+// after the given cursor. The returned cursor is nil on the last page.
+func (r *BuildRepository) ListBuilds(userID uuid.UUID, filter BuildFilter, after *Cursor, limit int) ([]*models.Build, *Cursor, error) {
+	fallback := "-created_at"
+	if filter.DueWithinDays != nil {
+		fallback = "target_date"
+	}
+	order, err := parseSort(filter.Sort, buildSortKeys, fallback)
 	if err != nil {
-		return nil, fmt.Errorf("failed to search builds: %w", err)
+		return nil, nil, err
 	}
-	defer rows.Close()
 
-	var builds []*models.Build
-	for rows.Next() {
-		build := &models.Build{}
-		err := rows.Scan(
-			&build.ID,
-			&build.UserID,
-			&build.Name,
-			&build.Description,
-			&build.Character,
-			&build.Series,
-			&build.Status,
-			&build.Priority,
-			&build.Budget,
-			&build.Spent,
-			&build.StartDate,
-			&build.TargetDate,
-			&build.CompletedDate,
-			&build.Tags,
-			&build.Notes,
-			&build.CreatedAt,
-			&build.UpdatedAt,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan build: %w", err)
-		}
-		builds = append(builds, build)
+	q := &listQuery{}
+	filter.where(q, userID)
+
+	builds, next, err := queryPage(r.db, q, "builds", buildColumns, order, after, limit, scanBuild,
+		func(b *models.Build) uuid.UUID { return b.ID })
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get builds: %w", err)
 	}
 
-	return builds, nil
+	return builds, next, nil
 }
 
-// GetBuildCount returns the total count of builds for a user
-func (r *BuildRepository) GetBuildCount(userID uuid.UUID) (int, error) {
-	ctx := context.Background()
-	query := `SELECT COUNT(*) FROM builds WHERE user_id = $1`
+// CountBuilds returns how many of a user's builds match the filter
+func (r *BuildRepository) CountBuilds(userID uuid.UUID, filter BuildFilter) (int, error) {
+	q := &listQuery{}
+	filter.where(q, userID)
 
-	var count int
-	err := r.db.QueryRow(ctx, query, userID).Scan(&count)
+	count, err := q.count(r.db, "builds")
 	if err != nil {
 		return 0, fmt.Errorf("failed to get build count: %w", err)
 	}
@@ -314,96 +418,134 @@ func (r *BuildRepository) GetBuildCount(userID uuid.UUID) (int, error) {
 	return count, nil
 }
 
-// GetBuildsByPriority retrieves builds by priority for a specific user
-func (r *BuildRepository) GetBuildsByPriority(userID uuid.UUID, priority int, limit, offset int) ([]*models.Build, error) {
+// GetBuildStats aggregates a user's builds in a single query: counts per
+// status, budget against spend, completions against target dates, and
+// completions per month over the last year with empty months included.
+// Budgets and spend are converted into the user's home currency at today's
+// rate.
+func (r *BuildRepository) GetBuildStats(userID uuid.UUID) (*models.BuildStats, error) {
 	ctx := context.Background()
-	query := `
-		SELECT id, user_id, name, description, character, series, status, priority, budget, spent, start_date, target_date, completed_date, tags, notes, created_at, updated_at
-		FROM builds
-		WHERE user_id = $1 AND priority = $2
-		ORDER BY created_at DESC
-		LIMIT $3 OFFSET $4`
 
-	rows, err := r.db.Query(ctx, query, userID, priority, limit, offset)
+	currency, err := homeCurrency(ctx, r.db, userID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get builds by priority: %w", err)
-	}
-	defer rows.Close()
-
-	var builds []*models.Build
-	for rows.Next() {
-		build := &models.Build{}
-		err := rows.Scan(
-			&build.ID,
-			&build.UserID,
-			&build.Name,
-			&build.Description,
-			&build.Character,
-			&build.Series,
-			&build.Status,
-			&build.Priority,
-			&build.Budget,
-			&build.Spent,
-			&build.StartDate,
-			&build.TargetDate,
-			&build.CompletedDate,
-			&build.Tags,
-			&build.Notes,
-			&build.CreatedAt,
-			&build.UpdatedAt,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan build: %w", err)
-		}
-		builds = append(builds, build)
+		return nil, err
 	}
+	since, months := trendMonthKeys()
 
-	return builds, nil
-}
-
-// GetUpcomingBuilds retrieves builds with target dates approaching
-func (r *BuildRepository) GetUpcomingBuilds(userID uuid.UUID, days int, limit, offset int) ([]*models.Build, error) {
-	ctx := context.Background()
+	// The grouping sets return one row per status, one per month with
+	// completions (plus one for builds outside the window, with a NULL
+	// month), and one over all builds
 	query := `
-		SELECT id, user_id, name, description, character, series, status, priority, budget, spent, start_date, target_date, completed_date, tags, notes, created_at, updated_at
-		FROM builds
-		WHERE user_id = $1 AND target_date IS NOT NULL AND target_date <= NOW() + INTERVAL '%d days' AND status != 'complete' AND status != 'cancelled'
-		ORDER BY target_date ASC
-		LIMIT $2 OFFSET $3`
-
-	rows, err := r.db.Query(ctx, fmt.Sprintf(query, days), userID, limit, offset)
+		SELECT GROUPING(status) = 0, GROUPING(month) = 0, status, month,
+			COUNT(*),
+			COUNT(budget_minor),
+			COALESCE(SUM(budget), 0)::bigint,
+			COALESCE(SUM(spent), 0)::bigint,
+			ROUND(AVG(budget))::bigint,
+			ROUND(AVG(spent))::bigint,
+			COUNT(*) FILTER (WHERE spent_minor > budget_minor),
+			COALESCE(SUM(overspent), 0)::bigint,
+			COUNT(*) FILTER (WHERE (budget_minor IS NOT NULL AND budget IS NULL) OR (spent_minor IS NOT NULL AND spent IS NULL)),
+			COUNT(*) FILTER (WHERE status = 'complete' AND completed_date <= target_date),
+			COUNT(*) FILTER (WHERE status = 'complete' AND completed_date > target_date),
+			COUNT(*) FILTER (WHERE status NOT IN ('complete', 'cancelled') AND target_date < CURRENT_DATE),
+			COUNT(*) FILTER (WHERE status NOT IN ('complete', 'cancelled') AND target_date <= CURRENT_DATE + 30)
+		FROM (
+			SELECT status, budget_minor, spent_minor, target_date, completed_date,
+				convert_minor(budget_minor, currency, $3, CURRENT_DATE) AS budget,
+				convert_minor(spent_minor, currency, $3, CURRENT_DATE) AS spent,
+				CASE WHEN spent_minor > budget_minor
+					THEN convert_minor(spent_minor - budget_minor, currency, $3, CURRENT_DATE)
+				END AS overspent,
+				CASE WHEN status = 'complete' AND completed_date >= $2::date
+					THEN date_trunc('month', completed_date)::date
+				END AS month
+			FROM builds
+			WHERE user_id = $1
+		) b
+		GROUP BY GROUPING SETS ((status), (month), ())`
+
+	rows, err := r.db.Query(ctx, query, userID, since, currency)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get upcoming builds: %w", err)
+		return nil, fmt.Errorf("failed to get build stats: %w", err)
 	}
 	defer rows.Close()
 
-	var builds []*models.Build
+	stats := &models.BuildStats{ByStatus: make(map[models.BuildStatus]int, len(models.BuildStatuses))}
+	for _, status := range models.BuildStatuses {
+		stats.ByStatus[status] = 0
+	}
+	completions := make(map[string]models.MonthlyCompletion)
+
 	for rows.Next() {
-		build := &models.Build{}
-		err := rows.Scan(
-			&build.ID,
-			&build.UserID,
-			&build.Name,
-			&build.Description,
-			&build.Character,
-			&build.Series,
-			&build.Status,
-			&build.Priority,
-			&build.Budget,
-			&build.Spent,
-			&build.StartDate,
-			&build.TargetDate,
-			&build.CompletedDate,
-			&build.Tags,
-			&build.Notes,
-			&build.CreatedAt,
-			&build.UpdatedAt,
+		var (
+			byStatus, byMonth bool
+			status            *string
+			month             *time.Time
+			count             int
+			budget            = models.BuildBudgetStats{
+				TotalBudget:      models.Money{Currency: currency},
+				TotalSpent:       models.Money{Currency: currency},
+				OverBudgetAmount: models.Money{Currency: currency},
+			}
+			averageBudget, averageSpent *int64
+			schedule                    models.BuildScheduleStats
+			upcoming                    int
 		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan build: %w", err)
+		if err := rows.Scan(
+			&byStatus,
+			&byMonth,
+			&status,
+			&month,
+			&count,
+			&budget.BudgetedBuilds,
+			&budget.TotalBudget.MinorUnits,
+			&budget.TotalSpent.MinorUnits,
+			&averageBudget,
+			&averageSpent,
+			&budget.OverBudgetBuilds,
+			&budget.OverBudgetAmount.MinorUnits,
+			&budget.UnconvertedBuilds,
+			&schedule.CompletedOnTime,
+			&schedule.CompletedLate,
+			&schedule.OverdueBuilds,
+			&upcoming,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan build stats: %w", err)
+		}
+
+		switch {
+		case byStatus && status != nil:
+			stats.ByStatus[models.BuildStatus(*status)] = count
+		case byMonth && month != nil:
+			key := month.Format(monthFormat)
+			completions[key] = models.MonthlyCompletion{
+				Month:     key,
+				Completed: count,
+				OnTime:    schedule.CompletedOnTime,
+				Late:      schedule.CompletedLate,
+			}
+		case !byStatus && !byMonth:
+			stats.TotalBuilds = count
+			stats.UpcomingBuilds = upcoming
+			budget.AverageBudget = moneyIn(averageBudget, currency)
+			budget.AverageSpent = moneyIn(averageSpent, currency)
+			stats.Budget = budget
+			stats.Schedule = schedule
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get build stats: %w", err)
+	}
+
+	stats.Completions = make([]models.MonthlyCompletion, 0, len(months))
+	for _, key := range months {
+		completion, ok := completions[key]
+		if !ok {
+			completion = models.MonthlyCompletion{Month: key}
 		}
-		builds = append(builds, build)
+		stats.Completions = append(stats.Completions, completion)
 	}
 
-	return builds, nil
+	return stats, nil
 }
@@ -0,0 +1,281 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"kyarafit-backend/models"
+)
+
+var (
+	// ErrCategoryNotFound is returned when a category doesn't exist
+	ErrCategoryNotFound = errors.New("category not found")
+	// ErrCategoryExists is returned when a user already has a category by a name, in any casing
+	ErrCategoryExists = errors.New("a category with this name already exists")
+	// ErrCategoryCycle is returned when a category would be nested under itself or one of its subcategories
+	ErrCategoryCycle = errors.New("a category can't be nested under itself or its subcategories")
+)
+
+type CategoryRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewCategoryRepository(db *pgxpool.Pool) *CategoryRepository {
+	return &CategoryRepository{db: db}
+}
+
+// categoryColumns selects a category with how many of its user's pieces are in it
+const categoryColumns = `c.id, c.user_id, c.name, c.color, c.parent_id, c.created_at, c.updated_at,
+			(SELECT COUNT(*) FROM pieces p WHERE p.user_id = c.user_id AND p.category = c.name)`
+
+// categoryAncestry selects the IDs of the category with ID $1 and all of its ancestors
+const categoryAncestry = `
+	WITH RECURSIVE ancestors AS (
+		SELECT id, parent_id FROM categories WHERE id = $1
+		UNION
+		SELECT c.id, c.parent_id FROM categories c JOIN ancestors a ON c.id = a.parent_id
+	)
+	SELECT id FROM ancestors`
+
+func scanCategory(row pgx.Row, category *models.Category) error {
+	return row.Scan(
+		&category.ID,
+		&category.UserID,
+		&category.Name,
+		&category.Color,
+		&category.ParentID,
+		&category.CreatedAt,
+		&category.UpdatedAt,
+		&category.PieceCount,
+	)
+}
+
+// isUniqueViolation reports whether err is a unique constraint violation
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+// CreateCategory adds a category to a user's vocabulary
+func (r *CategoryRepository) CreateCategory(category *models.Category) error {
+	ctx := context.Background()
+	query := `
+		INSERT INTO categories (id, user_id, name, color, parent_id, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at, updated_at`
+
+	err := r.db.QueryRow(
+		ctx,
+		query,
+		category.ID,
+		category.UserID,
+		category.Name,
+		category.Color,
+		category.ParentID,
+		category.CreatedAt,
+		category.UpdatedAt,
+	).Scan(&category.ID, &category.CreatedAt, &category.UpdatedAt)
+
+	if err != nil {
+		if isUniqueViolation(err) {
+			return ErrCategoryExists
+		}
+		return fmt.Errorf("failed to create category: %w", err)
+	}
+
+	return nil
+}
+
+// GetCategoryByID retrieves a category by its ID
+func (r *CategoryRepository) GetCategoryByID(id uuid.UUID) (*models.Category, error) {
+	ctx := context.Background()
+	query := `SELECT ` + categoryColumns + ` FROM categories c WHERE c.id = $1`
+
+	category := &models.Category{}
+	if err := scanCategory(r.db.QueryRow(ctx, query, id), category); err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrCategoryNotFound
+		}
+		return nil, fmt.Errorf("failed to get category: %w", err)
+	}
+
+	return category, nil
+}
+
+// ListCategories retrieves a user's categories by name
+func (r *CategoryRepository) ListCategories(userID uuid.UUID) ([]*models.Category, error) {
+	ctx := context.Background()
+	query := `SELECT ` + categoryColumns + ` FROM categories c WHERE c.user_id = $1 ORDER BY lower(c.name)`
+
+	rows, err := r.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get categories: %w", err)
+	}
+	defer rows.Close()
+
+	var categories []*models.Category
+	for rows.Next() {
+		category := &models.Category{}
+		if err := scanCategory(rows, category); err != nil {
+			return nil, fmt.Errorf("failed to scan category: %w", err)
+		}
+		categories = append(categories, category)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get categories: %w", err)
+	}
+
+	return categories, nil
+}
+
+// UpdateCategory saves a category. If it was renamed from previousName, its
+// pieces are moved to the new name in the same transaction. It returns how
+// many pieces were moved.
+func (r *CategoryRepository) UpdateCategory(category *models.Category, previousName string) (int64, error) {
+	ctx := context.Background()
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if category.ParentID != nil {
+		// Walking up from the new parent and reaching the category would make a loop
+		query := `SELECT $2 IN (` + categoryAncestry + `)`
+
+		var cycle bool
+		if err := tx.QueryRow(ctx, query, *category.ParentID, category.ID).Scan(&cycle); err != nil {
+			return 0, fmt.Errorf("failed to check category parent: %w", err)
+		}
+		if cycle {
+			return 0, ErrCategoryCycle
+		}
+	}
+
+	query := `
+		UPDATE categories
+		SET name = $3, color = $4, parent_id = $5, updated_at = $6
+		WHERE id = $1 AND user_id = $2
+		RETURNING updated_at`
+
+	err = tx.QueryRow(
+		ctx,
+		query,
+		category.ID,
+		category.UserID,
+		category.Name,
+		category.Color,
+		category.ParentID,
+		category.UpdatedAt,
+	).Scan(&category.UpdatedAt)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return 0, ErrCategoryNotFound
+		}
+		if isUniqueViolation(err) {
+			return 0, ErrCategoryExists
+		}
+		return 0, fmt.Errorf("failed to update category: %w", err)
+	}
+
+	var moved int64
+	if category.Name != previousName {
+		result, err := tx.Exec(ctx, `UPDATE pieces SET category = $3 WHERE user_id = $1 AND category = $2`,
+			category.UserID, previousName, category.Name)
+		if err != nil {
+			return 0, fmt.Errorf("failed to rename category on pieces: %w", err)
+		}
+		moved = result.RowsAffected()
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return moved, nil
+}
+
+// DeleteCategory deletes a category, clearing it from its pieces. Its
+// subcategories are left without a parent.
+func (r *CategoryRepository) DeleteCategory(category *models.Category) error {
+	ctx := context.Background()
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `UPDATE pieces SET category = NULL WHERE user_id = $1 AND category = $2`,
+		category.UserID, category.Name)
+	if err != nil {
+		return fmt.Errorf("failed to clear category from pieces: %w", err)
+	}
+
+	result, err := tx.Exec(ctx, `DELETE FROM categories WHERE id = $1 AND user_id = $2`, category.ID, category.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to delete category: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrCategoryNotFound
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// MergeCategory moves every piece and subcategory of source into target,
+// then deletes source, in one transaction. It returns how many pieces were
+// moved.
+func (r *CategoryRepository) MergeCategory(source, target *models.Category) (int64, error) {
+	ctx := context.Background()
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	result, err := tx.Exec(ctx, `UPDATE pieces SET category = $3 WHERE user_id = $1 AND category = $2`,
+		source.UserID, source.Name, target.Name)
+	if err != nil {
+		return 0, fmt.Errorf("failed to move pieces to category: %w", err)
+	}
+	moved := result.RowsAffected()
+
+	// A target nested under source takes source's place in the tree first, so
+	// moving source's subcategories under it can't make a loop
+	_, err = tx.Exec(ctx, `UPDATE categories SET parent_id = $2 WHERE id = $1 AND $3 IN (`+categoryAncestry+`)`,
+		target.ID, source.ParentID, source.ID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to move merged category: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `UPDATE categories SET parent_id = $2 WHERE parent_id = $1`, source.ID, target.ID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to move subcategories: %w", err)
+	}
+
+	result, err = tx.Exec(ctx, `DELETE FROM categories WHERE id = $1 AND user_id = $2`, source.ID, source.UserID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete merged category: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return 0, ErrCategoryNotFound
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return moved, nil
+}
@@ -77,6 +77,11 @@ func RunMigrations() error {
 		return fmt.Errorf("failed to run migrations: %w", err)
 	}
 
+	// Refuse to serve against a schema the repositories can't use
+	if err := VerifySchema(SQLDB); err != nil {
+		return err
+	}
+
 	log.Println("Database migrations completed successfully")
 	return nil
 }
@@ -0,0 +1,105 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"kyarafit-backend/models"
+)
+
+// scheduledBuildsCTE selects the builds on convention $1's schedule, whether
+// an entry names the build directly or through one of its coords
+const scheduledBuildsCTE = `
+		WITH scheduled_builds AS (
+			SELECT DISTINCT COALESCE(e.build_id, co.build_id) AS build_id
+			FROM convention_schedule_entries e
+			LEFT JOIN coords co ON co.id = e.coord_id
+			WHERE e.convention_id = $1 AND COALESCE(e.build_id, co.build_id) IS NOT NULL
+		)`
+
+// GetPackingList gathers every piece linked to the builds on a convention's
+// schedule, once per piece, ordered by category and name
+func (r *ConventionRepository) GetPackingList(conventionID uuid.UUID) ([]*models.PackingItem, error) {
+	ctx := context.Background()
+	query := scheduledBuildsCTE + `
+		SELECT p.id, p.user_id, p.name, p.description, p.image_url, p.image_bg_removed_url, p.thumbnail_url, p.image_key, p.image_bg_removed_key, p.thumbnail_key, p.category, p.tags, p.source_link, p.purchase_date, p.price_minor, p.price_currency, p.created_at, p.updated_at,
+			MAX(bp.quantity), array_agg(DISTINCT bp.build_id), COALESCE(pi.packed, FALSE), pi.packed_at
+		FROM scheduled_builds sb
+		JOIN build_pieces bp ON bp.build_id = sb.build_id
+		JOIN pieces p ON p.id = bp.piece_id
+		LEFT JOIN convention_packing_items pi ON pi.convention_id = $1 AND pi.piece_id = p.id
+		GROUP BY p.id, pi.packed, pi.packed_at
+		ORDER BY NULLIF(p.category, '') ASC NULLS LAST, p.name ASC`
+
+	rows, err := r.db.Query(ctx, query, conventionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get packing list: %w", err)
+	}
+	defer rows.Close()
+
+	var items []*models.PackingItem
+	for rows.Next() {
+		item := &models.PackingItem{Piece: &models.Piece{}}
+		var price nullMoney
+		err := rows.Scan(
+			&item.Piece.ID,
+			&item.Piece.UserID,
+			&item.Piece.Name,
+			&item.Piece.Description,
+			&item.Piece.ImageURL,
+			&item.Piece.CutoutURL,
+			&item.Piece.ThumbnailURL,
+			&item.Piece.ImageKey,
+			&item.Piece.CutoutKey,
+			&item.Piece.ThumbnailKey,
+			&item.Piece.Category,
+			&item.Piece.Tags,
+			&item.Piece.SourceLink,
+			&item.Piece.PurchaseDate,
+			&price.minorUnits,
+			&price.currency,
+			&item.Piece.CreatedAt,
+			&item.Piece.UpdatedAt,
+			&item.Quantity,
+			&item.BuildIDs,
+			&item.Packed,
+			&item.PackedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan packing item: %w", err)
+		}
+		item.Piece.Price = price.money()
+		items = append(items, item)
+	}
+
+	return items, nil
+}
+
+// SetPacked records whether a piece on a convention's packing list has been
+// packed. It fails if the piece isn't on the list.
+func (r *ConventionRepository) SetPacked(conventionID, pieceID uuid.UUID, packed bool) error {
+	ctx := context.Background()
+	query := scheduledBuildsCTE + `
+		INSERT INTO convention_packing_items (convention_id, piece_id, packed, packed_at)
+		SELECT $1::uuid, $2::uuid, $3::boolean, CASE WHEN $3::boolean THEN NOW() END
+		WHERE EXISTS (
+			SELECT 1 FROM build_pieces bp
+			JOIN scheduled_builds sb ON sb.build_id = bp.build_id
+			WHERE bp.piece_id = $2
+		)
+		ON CONFLICT (convention_id, piece_id) DO UPDATE
+		SET packed = EXCLUDED.packed,
+			packed_at = CASE WHEN EXCLUDED.packed THEN COALESCE(convention_packing_items.packed_at, NOW()) END`
+
+	result, err := r.db.Exec(ctx, query, conventionID, pieceID, packed)
+	if err != nil {
+		return fmt.Errorf("failed to update packing list: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("piece not on packing list")
+	}
+
+	return nil
+}
@@ -0,0 +1,542 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"kyarafit-backend/models"
+)
+
+var (
+	// ErrScheduleConflict is returned when a schedule entry would put two different
+	// outfits in overlapping time slots
+	ErrScheduleConflict = errors.New("another build or coord is already scheduled in this time slot")
+	// ErrScheduleOutsideDates is returned when a convention's new dates would leave
+	// schedule entries outside of it
+	ErrScheduleOutsideDates = errors.New("schedule has entries outside the convention dates")
+)
+
+type ConventionRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewConventionRepository(db *pgxpool.Pool) *ConventionRepository {
+	return &ConventionRepository{db: db}
+}
+
+// CreateConvention creates a new convention in the database
+func (r *ConventionRepository) CreateConvention(convention *models.Convention) error {
+	ctx := context.Background()
+	query := `
+		INSERT INTO conventions (id, user_id, name, venue, city, start_date, end_date, timezone, hotel_name, hotel_address, hotel_confirmation, website, notes, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+		RETURNING id, created_at, updated_at`
+
+	err := r.db.QueryRow(
+		ctx,
+		query,
+		convention.ID,
+		convention.UserID,
+		convention.Name,
+		convention.Venue,
+		convention.City,
+		convention.StartDate,
+		convention.EndDate,
+		convention.Timezone,
+		convention.HotelName,
+		convention.HotelAddress,
+		convention.HotelConfirmation,
+		convention.Website,
+		convention.Notes,
+		convention.CreatedAt,
+		convention.UpdatedAt,
+	).Scan(&convention.ID, &convention.CreatedAt, &convention.UpdatedAt)
+
+	if err != nil {
+		return fmt.Errorf("failed to create convention: %w", err)
+	}
+
+	return nil
+}
+
+// GetConventionByID retrieves a convention by its ID
+func (r *ConventionRepository) GetConventionByID(id uuid.UUID) (*models.Convention, error) {
+	ctx := context.Background()
+	query := `
+		SELECT id, user_id, name, venue, city, start_date, end_date, timezone, hotel_name, hotel_address, hotel_confirmation, website, notes, created_at, updated_at
+		FROM conventions
+		WHERE id = $1`
+
+	convention := &models.Convention{}
+	err := r.db.QueryRow(ctx, query, id).Scan(
+		&convention.ID,
+		&convention.UserID,
+		&convention.Name,
+		&convention.Venue,
+		&convention.City,
+		&convention.StartDate,
+		&convention.EndDate,
+		&convention.Timezone,
+		&convention.HotelName,
+		&convention.HotelAddress,
+		&convention.HotelConfirmation,
+		&convention.Website,
+		&convention.Notes,
+		&convention.CreatedAt,
+		&convention.UpdatedAt,
+	)
+
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, fmt.Errorf("convention not found")
+		}
+		return nil, fmt.Errorf("failed to get convention: %w", err)
+	}
+
+	return convention, nil
+}
+
+// GetConventionsByUserID retrieves all conventions for a specific user, soonest first
+func (r *ConventionRepository) GetConventionsByUserID(userID uuid.UUID, limit, offset int) ([]*models.Convention, error) {
+	ctx := context.Background()
+	query := `
+		SELECT id, user_id, name, venue, city, start_date, end_date, timezone, hotel_name, hotel_address, hotel_confirmation, website, notes, created_at, updated_at
+		FROM conventions
+		WHERE user_id = $1
+		ORDER BY start_date ASC, created_at ASC
+		LIMIT $2 OFFSET $3`
+
+	rows, err := r.db.Query(ctx, query, userID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get conventions: %w", err)
+	}
+	defer rows.Close()
+
+	var conventions []*models.Convention
+	for rows.Next() {
+		convention := &models.Convention{}
+		err := rows.Scan(
+			&convention.ID,
+			&convention.UserID,
+			&convention.Name,
+			&convention.Venue,
+			&convention.City,
+			&convention.StartDate,
+			&convention.EndDate,
+			&convention.Timezone,
+			&convention.HotelName,
+			&convention.HotelAddress,
+			&convention.HotelConfirmation,
+			&convention.Website,
+			&convention.Notes,
+			&convention.CreatedAt,
+			&convention.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan convention: %w", err)
+		}
+		conventions = append(conventions, convention)
+	}
+
+	return conventions, nil
+}
+
+// GetConventionCount returns the total count of conventions for a user
+func (r *ConventionRepository) GetConventionCount(userID uuid.UUID) (int, error) {
+	ctx := context.Background()
+	query := `SELECT COUNT(*) FROM conventions WHERE user_id = $1`
+
+	var count int
+	err := r.db.QueryRow(ctx, query, userID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get convention count: %w", err)
+	}
+
+	return count, nil
+}
+
+// UpdateConvention updates an existing convention. The update is refused if the
+// new dates would leave schedule entries outside the convention.
+func (r *ConventionRepository) UpdateConvention(convention *models.Convention) error {
+	ctx := context.Background()
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		UPDATE conventions
+		SET name = $2, venue = $3, city = $4, start_date = $5, end_date = $6, timezone = $7, hotel_name = $8,
+			hotel_address = $9, hotel_confirmation = $10, website = $11, notes = $12, updated_at = $13
+		WHERE id = $1 AND user_id = $14
+		RETURNING updated_at`
+
+	err = tx.QueryRow(
+		ctx,
+		query,
+		convention.ID,
+		convention.Name,
+		convention.Venue,
+		convention.City,
+		convention.StartDate,
+		convention.EndDate,
+		convention.Timezone,
+		convention.HotelName,
+		convention.HotelAddress,
+		convention.HotelConfirmation,
+		convention.Website,
+		convention.Notes,
+		convention.UpdatedAt,
+		convention.UserID,
+	).Scan(&convention.UpdatedAt)
+
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return fmt.Errorf("convention not found or access denied")
+		}
+		return fmt.Errorf("failed to update convention: %w", err)
+	}
+
+	var outside bool
+	err = tx.QueryRow(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM convention_schedule_entries
+			WHERE convention_id = $1 AND (day < $2 OR day > $3)
+		)`, convention.ID, convention.StartDate, convention.EndDate).Scan(&outside)
+	if err != nil {
+		return fmt.Errorf("failed to check convention schedule: %w", err)
+	}
+	if outside {
+		return ErrScheduleOutsideDates
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteConvention deletes a convention, and its schedule, by ID
+func (r *ConventionRepository) DeleteConvention(id uuid.UUID, userID uuid.UUID) error {
+	ctx := context.Background()
+	query := `DELETE FROM conventions WHERE id = $1 AND user_id = $2`
+
+	result, err := r.db.Exec(ctx, query, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete convention: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("convention not found or access denied")
+	}
+
+	return nil
+}
+
+// GetScheduleEntries retrieves a convention's schedule ordered by day and start time
+func (r *ConventionRepository) GetScheduleEntries(conventionID uuid.UUID) ([]*models.ScheduleEntry, error) {
+	ctx := context.Background()
+	query := `
+		SELECT id, convention_id, entry_type, day, to_char(start_time, 'HH24:MI'), to_char(end_time, 'HH24:MI'),
+			build_id, coord_id, title, location, notes, created_at, updated_at
+		FROM convention_schedule_entries
+		WHERE convention_id = $1
+		ORDER BY day ASC, start_time ASC, end_time ASC`
+
+	rows, err := r.db.Query(ctx, query, conventionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get schedule entries: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []*models.ScheduleEntry
+	for rows.Next() {
+		entry := &models.ScheduleEntry{}
+		err := rows.Scan(
+			&entry.ID,
+			&entry.ConventionID,
+			&entry.EntryType,
+			&entry.Day,
+			&entry.StartTime,
+			&entry.EndTime,
+			&entry.BuildID,
+			&entry.CoordID,
+			&entry.Title,
+			&entry.Location,
+			&entry.Notes,
+			&entry.CreatedAt,
+			&entry.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan schedule entry: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// GetScheduleEntryByID retrieves a schedule entry of a convention by its ID
+func (r *ConventionRepository) GetScheduleEntryByID(conventionID, entryID uuid.UUID) (*models.ScheduleEntry, error) {
+	ctx := context.Background()
+	query := `
+		SELECT id, convention_id, entry_type, day, to_char(start_time, 'HH24:MI'), to_char(end_time, 'HH24:MI'),
+			build_id, coord_id, title, location, notes, created_at, updated_at
+		FROM convention_schedule_entries
+		WHERE id = $1 AND convention_id = $2`
+
+	entry := &models.ScheduleEntry{}
+	err := r.db.QueryRow(ctx, query, entryID, conventionID).Scan(
+		&entry.ID,
+		&entry.ConventionID,
+		&entry.EntryType,
+		&entry.Day,
+		&entry.StartTime,
+		&entry.EndTime,
+		&entry.BuildID,
+		&entry.CoordID,
+		&entry.Title,
+		&entry.Location,
+		&entry.Notes,
+		&entry.CreatedAt,
+		&entry.UpdatedAt,
+	)
+
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, fmt.Errorf("schedule entry not found")
+		}
+		return nil, fmt.Errorf("failed to get schedule entry: %w", err)
+	}
+
+	return entry, nil
+}
+
+// CreateScheduleEntry adds an entry to a convention's schedule, rejecting it
+// with ErrScheduleConflict if it overlaps an entry wearing a different outfit
+func (r *ConventionRepository) CreateScheduleEntry(entry *models.ScheduleEntry) error {
+	ctx := context.Background()
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := checkScheduleConflict(ctx, tx, entry); err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO convention_schedule_entries (id, convention_id, entry_type, day, start_time, end_time, build_id, coord_id, title, location, notes, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5::time, $6::time, $7, $8, $9, $10, $11, $12, $13)
+		RETURNING id, created_at, updated_at`
+
+	err = tx.QueryRow(
+		ctx,
+		query,
+		entry.ID,
+		entry.ConventionID,
+		entry.EntryType,
+		entry.Day,
+		entry.StartTime,
+		entry.EndTime,
+		entry.BuildID,
+		entry.CoordID,
+		entry.Title,
+		entry.Location,
+		entry.Notes,
+		entry.CreatedAt,
+		entry.UpdatedAt,
+	).Scan(&entry.ID, &entry.CreatedAt, &entry.UpdatedAt)
+
+	if err != nil {
+		return fmt.Errorf("failed to create schedule entry: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateScheduleEntry updates an existing schedule entry with the same conflict
+// check as CreateScheduleEntry
+func (r *ConventionRepository) UpdateScheduleEntry(entry *models.ScheduleEntry) error {
+	ctx := context.Background()
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := checkScheduleConflict(ctx, tx, entry); err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE convention_schedule_entries
+		SET entry_type = $3, day = $4, start_time = $5::time, end_time = $6::time, build_id = $7, coord_id = $8,
+			title = $9, location = $10, notes = $11, updated_at = $12
+		WHERE id = $1 AND convention_id = $2
+		RETURNING updated_at`
+
+	err = tx.QueryRow(
+		ctx,
+		query,
+		entry.ID,
+		entry.ConventionID,
+		entry.EntryType,
+		entry.Day,
+		entry.StartTime,
+		entry.EndTime,
+		entry.BuildID,
+		entry.CoordID,
+		entry.Title,
+		entry.Location,
+		entry.Notes,
+		entry.UpdatedAt,
+	).Scan(&entry.UpdatedAt)
+
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return fmt.Errorf("schedule entry not found")
+		}
+		return fmt.Errorf("failed to update schedule entry: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// checkScheduleConflict locks the convention so concurrent schedule writes are
+// serialized, then looks for an overlapping entry wearing a different outfit.
+// Entries without a build or coord, like meetups in street clothes, never conflict.
+func checkScheduleConflict(ctx context.Context, tx pgx.Tx, entry *models.ScheduleEntry) error {
+	if _, err := tx.Exec(ctx, `SELECT 1 FROM conventions WHERE id = $1 FOR UPDATE`, entry.ConventionID); err != nil {
+		return fmt.Errorf("failed to lock convention: %w", err)
+	}
+
+	if !entry.Outfit() {
+		return nil
+	}
+
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM convention_schedule_entries
+			WHERE convention_id = $1 AND id <> $2 AND day = $3
+				AND start_time < $5::time AND end_time > $4::time
+				AND (build_id IS NOT NULL OR coord_id IS NOT NULL)
+				AND NOT (build_id IS NOT DISTINCT FROM $6 AND coord_id IS NOT DISTINCT FROM $7)
+		)`
+
+	var conflict bool
+	err := tx.QueryRow(
+		ctx,
+		query,
+		entry.ConventionID,
+		entry.ID,
+		entry.Day,
+		entry.StartTime,
+		entry.EndTime,
+		entry.BuildID,
+		entry.CoordID,
+	).Scan(&conflict)
+	if err != nil {
+		return fmt.Errorf("failed to check schedule conflicts: %w", err)
+	}
+	if conflict {
+		return ErrScheduleConflict
+	}
+
+	return nil
+}
+
+// DeleteScheduleEntry removes an entry from a convention's schedule
+func (r *ConventionRepository) DeleteScheduleEntry(conventionID, entryID uuid.UUID) error {
+	ctx := context.Background()
+	query := `DELETE FROM convention_schedule_entries WHERE id = $1 AND convention_id = $2`
+
+	result, err := r.db.Exec(ctx, query, entryID, conventionID)
+	if err != nil {
+		return fmt.Errorf("failed to delete schedule entry: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("schedule entry not found")
+	}
+
+	return nil
+}
+
+// GetAtRiskBuilds returns the builds scheduled for a convention, directly or
+// through a coord, that are not complete, or were completed after the
+// convention started
+func (r *ConventionRepository) GetAtRiskBuilds(conventionID uuid.UUID) ([]*models.AtRiskBuild, error) {
+	ctx := context.Background()
+	query := `
+		SELECT b.id, b.user_id, b.name, b.description, b.character, b.series, b.status, b.priority, b.currency, b.budget_minor, b.spent_minor,
+			b.start_date, b.target_date, b.completed_date, b.tags, b.notes, b.created_at, b.updated_at, MIN(e.day)
+		FROM convention_schedule_entries e
+		JOIN conventions c ON c.id = e.convention_id
+		LEFT JOIN coords co ON co.id = e.coord_id
+		JOIN builds b ON b.id = COALESCE(e.build_id, co.build_id)
+		WHERE e.convention_id = $1
+			AND (b.status <> 'complete' OR b.completed_date > c.start_date)
+		GROUP BY b.id
+		ORDER BY MIN(e.day) ASC, b.name ASC`
+
+	rows, err := r.db.Query(ctx, query, conventionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get at-risk builds: %w", err)
+	}
+	defer rows.Close()
+
+	var builds []*models.AtRiskBuild
+	for rows.Next() {
+		build := &models.Build{}
+		atRisk := &models.AtRiskBuild{Build: build}
+		var budget, spent *int64
+		err := rows.Scan(
+			&build.ID,
+			&build.UserID,
+			&build.Name,
+			&build.Description,
+			&build.Character,
+			&build.Series,
+			&build.Status,
+			&build.Priority,
+			&build.Currency,
+			&budget,
+			&spent,
+			&build.StartDate,
+			&build.TargetDate,
+			&build.CompletedDate,
+			&build.Tags,
+			&build.Notes,
+			&build.CreatedAt,
+			&build.UpdatedAt,
+			&atRisk.FirstScheduledDay,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan at-risk build: %w", err)
+		}
+		build.Budget = moneyIn(budget, build.Currency)
+		build.Spent = moneyIn(spent, build.Currency)
+		builds = append(builds, atRisk)
+	}
+
+	return builds, nil
+}
@@ -0,0 +1,334 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"kyarafit-backend/models"
+)
+
+type CoordRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewCoordRepository(db *pgxpool.Pool) *CoordRepository {
+	return &CoordRepository{db: db}
+}
+
+// CreateCoord creates a new coord and its layers in one transaction
+func (r *CoordRepository) CreateCoord(coord *models.Coord) error {
+	ctx := context.Background()
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		INSERT INTO coords (id, user_id, name, description, build_id, canvas_width, canvas_height, background_color, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING id, created_at, updated_at`
+
+	err = tx.QueryRow(
+		ctx,
+		query,
+		coord.ID,
+		coord.UserID,
+		coord.Name,
+		coord.Description,
+		coord.BuildID,
+		coord.CanvasWidth,
+		coord.CanvasHeight,
+		coord.BackgroundColor,
+		coord.CreatedAt,
+		coord.UpdatedAt,
+	).Scan(&coord.ID, &coord.CreatedAt, &coord.UpdatedAt)
+
+	if err != nil {
+		return fmt.Errorf("failed to create coord: %w", err)
+	}
+
+	if err := insertCoordLayers(ctx, tx, coord); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// GetCoordByID retrieves a coord and its layers by the coord's ID
+func (r *CoordRepository) GetCoordByID(id uuid.UUID) (*models.Coord, error) {
+	ctx := context.Background()
+	query := `
+		SELECT id, user_id, name, description, build_id, canvas_width, canvas_height, background_color, created_at, updated_at
+		FROM coords
+		WHERE id = $1`
+
+	coord := &models.Coord{}
+	err := r.db.QueryRow(ctx, query, id).Scan(
+		&coord.ID,
+		&coord.UserID,
+		&coord.Name,
+		&coord.Description,
+		&coord.BuildID,
+		&coord.CanvasWidth,
+		&coord.CanvasHeight,
+		&coord.BackgroundColor,
+		&coord.CreatedAt,
+		&coord.UpdatedAt,
+	)
+
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, fmt.Errorf("coord not found")
+		}
+		return nil, fmt.Errorf("failed to get coord: %w", err)
+	}
+
+	if err := r.loadLayers([]*models.Coord{coord}); err != nil {
+		return nil, err
+	}
+
+	return coord, nil
+}
+
+// GetCoordsByUserID retrieves all coords, with their layers, for a specific user
+func (r *CoordRepository) GetCoordsByUserID(userID uuid.UUID, limit, offset int) ([]*models.Coord, error) {
+	ctx := context.Background()
+	query := `
+		SELECT id, user_id, name, description, build_id, canvas_width, canvas_height, background_color, created_at, updated_at
+		FROM coords
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2 OFFSET $3`
+
+	rows, err := r.db.Query(ctx, query, userID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get coords: %w", err)
+	}
+	defer rows.Close()
+
+	var coords []*models.Coord
+	for rows.Next() {
+		coord := &models.Coord{}
+		err := rows.Scan(
+			&coord.ID,
+			&coord.UserID,
+			&coord.Name,
+			&coord.Description,
+			&coord.BuildID,
+			&coord.CanvasWidth,
+			&coord.CanvasHeight,
+			&coord.BackgroundColor,
+			&coord.CreatedAt,
+			&coord.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan coord: %w", err)
+		}
+		coords = append(coords, coord)
+	}
+	rows.Close()
+
+	if err := r.loadLayers(coords); err != nil {
+		return nil, err
+	}
+
+	return coords, nil
+}
+
+// loadLayers fills in the layers, with their pieces, of each coord
+func (r *CoordRepository) loadLayers(coords []*models.Coord) error {
+	if len(coords) == 0 {
+		return nil
+	}
+
+	byID := make(map[uuid.UUID]*models.Coord, len(coords))
+	ids := make([]uuid.UUID, 0, len(coords))
+	for _, coord := range coords {
+		coord.Layers = []models.CoordLayer{}
+		byID[coord.ID] = coord
+		ids = append(ids, coord.ID)
+	}
+
+	ctx := context.Background()
+	query := `
+		SELECT l.id, l.coord_id, l.piece_id, l.z_index, l.position_x, l.position_y, l.scale, l.rotation, l.created_at, l.updated_at,
+			p.id, p.user_id, p.name, p.description, p.image_url, p.image_bg_removed_url, p.thumbnail_url, p.image_key, p.image_bg_removed_key, p.thumbnail_key, p.category, p.tags, p.source_link, p.purchase_date, p.price_minor, p.price_currency, p.created_at, p.updated_at
+		FROM coord_layers l
+		JOIN pieces p ON p.id = l.piece_id
+		WHERE l.coord_id = ANY($1::uuid[])
+		ORDER BY l.z_index ASC, l.created_at ASC`
+
+	rows, err := r.db.Query(ctx, query, ids)
+	if err != nil {
+		return fmt.Errorf("failed to get coord layers: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		layer := models.CoordLayer{Piece: &models.Piece{}}
+		var price nullMoney
+		err := rows.Scan(
+			&layer.ID,
+			&layer.CoordID,
+			&layer.PieceID,
+			&layer.ZIndex,
+			&layer.PositionX,
+			&layer.PositionY,
+			&layer.Scale,
+			&layer.Rotation,
+			&layer.CreatedAt,
+			&layer.UpdatedAt,
+			&layer.Piece.ID,
+			&layer.Piece.UserID,
+			&layer.Piece.Name,
+			&layer.Piece.Description,
+			&layer.Piece.ImageURL,
+			&layer.Piece.CutoutURL,
+			&layer.Piece.ThumbnailURL,
+			&layer.Piece.ImageKey,
+			&layer.Piece.CutoutKey,
+			&layer.Piece.ThumbnailKey,
+			&layer.Piece.Category,
+			&layer.Piece.Tags,
+			&layer.Piece.SourceLink,
+			&layer.Piece.PurchaseDate,
+			&price.minorUnits,
+			&price.currency,
+			&layer.Piece.CreatedAt,
+			&layer.Piece.UpdatedAt,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to scan coord layer: %w", err)
+		}
+		layer.Piece.Price = price.money()
+		coord := byID[layer.CoordID]
+		coord.Layers = append(coord.Layers, layer)
+	}
+
+	return nil
+}
+
+// UpdateCoord updates an existing coord. When replaceLayers is set, the coord's
+// layers are replaced with coord.Layers in the same transaction.
+func (r *CoordRepository) UpdateCoord(coord *models.Coord, replaceLayers bool) error {
+	ctx := context.Background()
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		UPDATE coords
+		SET name = $2, description = $3, build_id = $4, canvas_width = $5, canvas_height = $6, background_color = $7, updated_at = $8
+		WHERE id = $1 AND user_id = $9
+		RETURNING updated_at`
+
+	err = tx.QueryRow(
+		ctx,
+		query,
+		coord.ID,
+		coord.Name,
+		coord.Description,
+		coord.BuildID,
+		coord.CanvasWidth,
+		coord.CanvasHeight,
+		coord.BackgroundColor,
+		coord.UpdatedAt,
+		coord.UserID,
+	).Scan(&coord.UpdatedAt)
+
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return fmt.Errorf("coord not found or access denied")
+		}
+		return fmt.Errorf("failed to update coord: %w", err)
+	}
+
+	if replaceLayers {
+		if _, err := tx.Exec(ctx, `DELETE FROM coord_layers WHERE coord_id = $1`, coord.ID); err != nil {
+			return fmt.Errorf("failed to clear coord layers: %w", err)
+		}
+		if err := insertCoordLayers(ctx, tx, coord); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+func insertCoordLayers(ctx context.Context, tx pgx.Tx, coord *models.Coord) error {
+	query := `
+		INSERT INTO coord_layers (id, coord_id, piece_id, z_index, position_x, position_y, scale, rotation, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING created_at, updated_at`
+
+	for i := range coord.Layers {
+		layer := &coord.Layers[i]
+		layer.CoordID = coord.ID
+		err := tx.QueryRow(
+			ctx,
+			query,
+			layer.ID,
+			layer.CoordID,
+			layer.PieceID,
+			layer.ZIndex,
+			layer.PositionX,
+			layer.PositionY,
+			layer.Scale,
+			layer.Rotation,
+			layer.CreatedAt,
+			layer.UpdatedAt,
+		).Scan(&layer.CreatedAt, &layer.UpdatedAt)
+		if err != nil {
+			return fmt.Errorf("failed to create coord layer: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// DeleteCoord deletes a coord, and its layers, by ID
+func (r *CoordRepository) DeleteCoord(id uuid.UUID, userID uuid.UUID) error {
+	ctx := context.Background()
+	query := `DELETE FROM coords WHERE id = $1 AND user_id = $2`
+
+	result, err := r.db.Exec(ctx, query, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete coord: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("coord not found or access denied")
+	}
+
+	return nil
+}
+
+// GetCoordCount returns the total count of coords for a user
+func (r *CoordRepository) GetCoordCount(userID uuid.UUID) (int, error) {
+	ctx := context.Background()
+	query := `SELECT COUNT(*) FROM coords WHERE user_id = $1`
+
+	var count int
+	err := r.db.QueryRow(ctx, query, userID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get coord count: %w", err)
+	}
+
+	return count, nil
+}
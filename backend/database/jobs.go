@@ -0,0 +1,204 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"kyarafit-backend/models"
+)
+
+// ErrJobLeaseLost is returned when a worker reports on a job it no longer
+// holds: the job was taken for abandoned and requeued, and may be running again
+var ErrJobLeaseLost = errors.New("job lease lost")
+
+type JobRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewJobRepository(db *pgxpool.Pool) *JobRepository {
+	return &JobRepository{db: db}
+}
+
+const jobColumns = `id, user_id, kind, status, payload, result, progress, attempts, max_attempts,
+			run_at, locked_at, last_error, completed_at, created_at, updated_at`
+
+func scanJob(row pgx.Row, job *models.Job) error {
+	return row.Scan(
+		&job.ID,
+		&job.UserID,
+		&job.Kind,
+		&job.Status,
+		&job.Payload,
+		&job.Result,
+		&job.Progress,
+		&job.Attempts,
+		&job.MaxAttempts,
+		&job.RunAt,
+		&job.LockedAt,
+		&job.LastError,
+		&job.CompletedAt,
+		&job.CreatedAt,
+		&job.UpdatedAt,
+	)
+}
+
+// CreateJob queues a new job to run as soon as a worker is free
+func (r *JobRepository) CreateJob(job *models.Job) error {
+	ctx := context.Background()
+	query := `
+		INSERT INTO jobs (id, user_id, kind, payload, max_attempts)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING ` + jobColumns
+
+	err := scanJob(r.db.QueryRow(
+		ctx,
+		query,
+		job.ID,
+		job.UserID,
+		job.Kind,
+		job.Payload,
+		job.MaxAttempts,
+	), job)
+
+	if err != nil {
+		return fmt.Errorf("failed to create job: %w", err)
+	}
+
+	return nil
+}
+
+// GetJobByID retrieves a job by its ID
+func (r *JobRepository) GetJobByID(id uuid.UUID) (*models.Job, error) {
+	ctx := context.Background()
+	query := `SELECT ` + jobColumns + ` FROM jobs WHERE id = $1`
+
+	job := &models.Job{}
+	if err := scanJob(r.db.QueryRow(ctx, query, id), job); err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, fmt.Errorf("job not found")
+		}
+		return nil, fmt.Errorf("failed to get job: %w", err)
+	}
+
+	return job, nil
+}
+
+// ClaimJob marks the oldest due job of one of the given kinds as running and
+// returns it, or returns nil when there is none. SKIP LOCKED lets several
+// workers claim jobs at once without waiting on each other.
+func (r *JobRepository) ClaimJob(kinds []string) (*models.Job, error) {
+	ctx := context.Background()
+	query := `
+		UPDATE jobs
+		SET status = 'running', attempts = attempts + 1, progress = 0, locked_at = NOW(), updated_at = NOW()
+		WHERE id = (
+			SELECT id FROM jobs
+			WHERE status = 'queued' AND run_at <= NOW() AND kind = ANY($1)
+			ORDER BY run_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + jobColumns
+
+	job := &models.Job{}
+	if err := scanJob(r.db.QueryRow(ctx, query, kinds), job); err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to claim job: %w", err)
+	}
+
+	return job, nil
+}
+
+// The updates below are fenced on the attempt a worker claimed, so a worker
+// that overran its lease can't overwrite the run that replaced it. They
+// return ErrJobLeaseLost when the job has moved on.
+
+// UpdateJobProgress records a running job's progress. It also renews the
+// job's lock, so a job that keeps reporting is never taken for abandoned.
+func (r *JobRepository) UpdateJobProgress(id uuid.UUID, attempt int, progress int) error {
+	ctx := context.Background()
+	query := `
+		UPDATE jobs
+		SET progress = $3, locked_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND status = 'running' AND attempts = $2`
+
+	result, err := r.db.Exec(ctx, query, id, attempt, progress)
+	if err != nil {
+		return fmt.Errorf("failed to update job progress: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrJobLeaseLost
+	}
+
+	return nil
+}
+
+// CompleteJob marks a running job as succeeded with its result
+func (r *JobRepository) CompleteJob(id uuid.UUID, attempt int, result json.RawMessage) error {
+	ctx := context.Background()
+	query := `
+		UPDATE jobs
+		SET status = 'succeeded', result = $3, progress = 100, locked_at = NULL, completed_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND status = 'running' AND attempts = $2`
+
+	tag, err := r.db.Exec(ctx, query, id, attempt, result)
+	if err != nil {
+		return fmt.Errorf("failed to complete job: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrJobLeaseLost
+	}
+
+	return nil
+}
+
+// FailJob records a failed attempt. The job is queued again to run at retryAt,
+// or moved to the dead-letter state when retryAt is nil.
+func (r *JobRepository) FailJob(id uuid.UUID, attempt int, lastError string, retryAt *time.Time) error {
+	ctx := context.Background()
+	query := `
+		UPDATE jobs
+		SET status = CASE WHEN $4::timestamptz IS NULL THEN 'dead' ELSE 'queued' END,
+			run_at = COALESCE($4::timestamptz, run_at),
+			completed_at = CASE WHEN $4::timestamptz IS NULL THEN NOW() END,
+			last_error = $3, locked_at = NULL, updated_at = NOW()
+		WHERE id = $1 AND status = 'running' AND attempts = $2`
+
+	result, err := r.db.Exec(ctx, query, id, attempt, lastError, retryAt)
+	if err != nil {
+		return fmt.Errorf("failed to fail job: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrJobLeaseLost
+	}
+
+	return nil
+}
+
+// RequeueStaleJobs releases running jobs whose worker hasn't reported within
+// lease, which happens when the server stops mid-job. Jobs with attempts left
+// are queued again; the rest are dead. It returns how many jobs it released.
+func (r *JobRepository) RequeueStaleJobs(lease time.Duration) (int, error) {
+	ctx := context.Background()
+	query := `
+		UPDATE jobs
+		SET status = CASE WHEN attempts >= max_attempts THEN 'dead' ELSE 'queued' END,
+			completed_at = CASE WHEN attempts >= max_attempts THEN NOW() END,
+			run_at = NOW(), locked_at = NULL, last_error = 'worker stopped responding', updated_at = NOW()
+		WHERE status = 'running' AND locked_at < NOW() - make_interval(secs => $1)`
+
+	result, err := r.db.Exec(ctx, query, lease.Seconds())
+	if err != nil {
+		return 0, fmt.Errorf("failed to requeue stale jobs: %w", err)
+	}
+
+	return int(result.RowsAffected()), nil
+}
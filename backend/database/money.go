@@ -0,0 +1,182 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"kyarafit-backend/models"
+)
+
+// ErrUserNotFound is returned when a user has no account row
+var ErrUserNotFound = errors.New("user not found")
+
+// CurrencyRepository manages supported currencies, exchange rates and each
+// user's home currency
+type CurrencyRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewCurrencyRepository(db *pgxpool.Pool) *CurrencyRepository {
+	return &CurrencyRepository{db: db}
+}
+
+// nullMoney scans an amount in minor units and its currency, which are NULL
+// together
+type nullMoney struct {
+	minorUnits *int64
+	currency   *string
+}
+
+func (m nullMoney) money() *models.Money {
+	if m.currency == nil {
+		return nil
+	}
+	return moneyIn(m.minorUnits, *m.currency)
+}
+
+// moneyIn pairs an amount in minor units with its currency, or returns nil
+// for a NULL amount
+func moneyIn(minorUnits *int64, currency string) *models.Money {
+	if minorUnits == nil {
+		return nil
+	}
+	return &models.Money{MinorUnits: *minorUnits, Currency: currency}
+}
+
+// minorUnits and currencyOf split optional money into its columns
+func minorUnits(m *models.Money) *int64 {
+	if m == nil {
+		return nil
+	}
+	return &m.MinorUnits
+}
+
+func currencyOf(m *models.Money) *string {
+	if m == nil {
+		return nil
+	}
+	return &m.Currency
+}
+
+// LoadCurrencies registers every currency in the currencies table with the
+// models package, so currencies added there are accepted and formatted
+func (r *CurrencyRepository) LoadCurrencies() error {
+	ctx := context.Background()
+	query := `SELECT code, exponent FROM currencies`
+
+	rows, err := r.db.Query(ctx, query)
+	if err != nil {
+		return fmt.Errorf("failed to get currencies: %w", err)
+	}
+	defer rows.Close()
+
+	exponents := make(map[string]int)
+	for rows.Next() {
+		var code string
+		var exponent int
+		if err := rows.Scan(&code, &exponent); err != nil {
+			return fmt.Errorf("failed to scan currency: %w", err)
+		}
+		exponents[code] = exponent
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to get currencies: %w", err)
+	}
+
+	models.RegisterCurrencies(exponents)
+	return nil
+}
+
+// GetHomeCurrency returns the currency a user's amounts default to and stats
+// are reported in
+func (r *CurrencyRepository) GetHomeCurrency(userID uuid.UUID) (string, error) {
+	return homeCurrency(context.Background(), r.db, userID)
+}
+
+func homeCurrency(ctx context.Context, q rowQuerier, userID uuid.UUID) (string, error) {
+	query := `SELECT home_currency FROM users WHERE id = $1`
+
+	var currency string
+	if err := q.QueryRow(ctx, query, userID).Scan(&currency); err != nil {
+		// Users known only to an external auth provider have no row yet
+		if err == pgx.ErrNoRows {
+			return models.DefaultCurrency, nil
+		}
+		return "", fmt.Errorf("failed to get home currency: %w", err)
+	}
+
+	return currency, nil
+}
+
+// SetHomeCurrency changes a user's home currency. Amounts already recorded
+// keep their own currency.
+func (r *CurrencyRepository) SetHomeCurrency(userID uuid.UUID, currency string) error {
+	ctx := context.Background()
+	query := `UPDATE users SET home_currency = $2 WHERE id = $1`
+
+	result, err := r.db.Exec(ctx, query, userID, currency)
+	if err != nil {
+		return fmt.Errorf("failed to set home currency: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+// UpsertExchangeRates stores exchange rates, replacing any already loaded
+// for the same pair and day, and totals again the spending of every build
+// with expenses in another currency. It returns how many builds changed.
+func (r *CurrencyRepository) UpsertExchangeRates(rates []models.ExchangeRate) (int, error) {
+	ctx := context.Background()
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	bases := make([]string, len(rates))
+	quotes := make([]string, len(rates))
+	days := make([]string, len(rates))
+	values := make([]string, len(rates))
+	for i, rate := range rates {
+		bases[i] = rate.Base
+		quotes[i] = rate.Quote
+		days[i] = rate.EffectiveOn.Format("2006-01-02")
+		values[i] = rate.Rate
+	}
+
+	// Rates go through text so they're stored exactly as written
+	upsertQuery := `
+		INSERT INTO exchange_rates (base, quote, effective_on, rate)
+		SELECT base, quote, effective_on::date, rate::numeric
+		FROM unnest($1::text[], $2::text[], $3::text[], $4::text[]) AS r(base, quote, effective_on, rate)
+		ON CONFLICT (base, quote, effective_on) DO UPDATE
+		SET rate = EXCLUDED.rate, loaded_at = NOW()`
+	if _, err := tx.Exec(ctx, upsertQuery, bases, quotes, days, values); err != nil {
+		return 0, fmt.Errorf("failed to store exchange rates: %w", err)
+	}
+
+	refreshQuery := `
+		UPDATE builds b
+		SET spent_minor = ledger_total(b.id, b.currency)
+		WHERE EXISTS (SELECT 1 FROM build_expenses e WHERE e.build_id = b.id AND e.currency <> b.currency)
+			AND b.spent_minor IS DISTINCT FROM ledger_total(b.id, b.currency)`
+	result, err := tx.Exec(ctx, refreshQuery)
+	if err != nil {
+		return 0, fmt.Errorf("failed to refresh build spending: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return int(result.RowsAffected()), nil
+}
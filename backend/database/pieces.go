@@ -2,31 +2,79 @@ package database
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"kyarafit-backend/models"
+	"kyarafit-backend/storage"
 )
 
+// ErrPieceNotFound is returned when a piece doesn't exist
+var ErrPieceNotFound = errors.New("piece not found")
+
 type PieceRepository struct {
-	db *pgxpool.Pool
+	db    *pgxpool.Pool
+	blobs storage.BlobStore
 }
 
-func NewPieceRepository(db *pgxpool.Pool) *PieceRepository {
-	return &PieceRepository{db: db}
+// NewPieceRepository creates a piece repository. blobs is where uploaded piece
+// images live; their blobs are deleted once no piece references them.
+func NewPieceRepository(db *pgxpool.Pool, blobs storage.BlobStore) *PieceRepository {
+	return &PieceRepository{db: db, blobs: blobs}
+}
+
+const pieceColumns = `id, user_id, name, description, image_url, image_bg_removed_url, thumbnail_url, image_key, image_bg_removed_key,
+			thumbnail_key, category, tags, source_link, purchase_date, price_minor, price_currency, created_at, updated_at`
+
+func scanPiece(row pgx.Row, piece *models.Piece) error {
+	var price nullMoney
+	err := row.Scan(
+		&piece.ID,
+		&piece.UserID,
+		&piece.Name,
+		&piece.Description,
+		&piece.ImageURL,
+		&piece.CutoutURL,
+		&piece.ThumbnailURL,
+		&piece.ImageKey,
+		&piece.CutoutKey,
+		&piece.ThumbnailKey,
+		&piece.Category,
+		&piece.Tags,
+		&piece.SourceLink,
+		&piece.PurchaseDate,
+		&price.minorUnits,
+		&price.currency,
+		&piece.CreatedAt,
+		&piece.UpdatedAt,
+	)
+	piece.Price = price.money()
+	return err
+}
+
+// rowQuerier is satisfied by both the connection pool and a transaction
+type rowQuerier interface {
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
 }
 
 // CreatePiece creates a new piece in the database
 func (r *PieceRepository) CreatePiece(piece *models.Piece) error {
-	ctx := context.Background()
+	return insertPiece(context.Background(), r.db, piece)
+}
+
+func insertPiece(ctx context.Context, q rowQuerier, piece *models.Piece) error {
 	query := `
-		INSERT INTO pieces (id, user_id, name, description, image_url, thumbnail_url, category, tags, source_link, purchase_date, price, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
-		RETURNING id, created_at, updated_at`
+		INSERT INTO pieces (id, user_id, name, description, image_url, thumbnail_url, category, tags, source_link, purchase_date, price_minor, price_currency, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+		RETURNING id, category, tags, created_at, updated_at`
 
-	err := r.db.QueryRow(
+	// category and tags are read back in the vocabulary spelling the triggers store
+	err := q.QueryRow(
 		ctx,
 		query,
 		piece.ID,
@@ -39,10 +87,11 @@ func (r *PieceRepository) CreatePiece(piece *models.Piece) error {
 		piece.Tags,
 		piece.SourceLink,
 		piece.PurchaseDate,
-		piece.Price,
+		minorUnits(piece.Price),
+		currencyOf(piece.Price),
 		piece.CreatedAt,
 		piece.UpdatedAt,
-	).Scan(&piece.ID, &piece.CreatedAt, &piece.UpdatedAt)
+	).Scan(&piece.ID, &piece.Category, &piece.Tags, &piece.CreatedAt, &piece.UpdatedAt)
 
 	if err != nil {
 		return fmt.Errorf("failed to create piece: %w", err)
@@ -54,31 +103,12 @@ func (r *PieceRepository) CreatePiece(piece *models.Piece) error {
 // GetPieceByID retrieves a piece by its ID
 func (r *PieceRepository) GetPieceByID(id uuid.UUID) (*models.Piece, error) {
 	ctx := context.Background()
-	query := `
-		SELECT id, user_id, name, description, image_url, thumbnail_url, category, tags, source_link, purchase_date, price, created_at, updated_at
-		FROM pieces
-		WHERE id = $1`
+	query := `SELECT ` + pieceColumns + ` FROM pieces WHERE id = $1`
 
 	piece := &models.Piece{}
-	err := r.db.QueryRow(ctx, query, id).Scan(
-		&piece.ID,
-		&piece.UserID,
-		&piece.Name,
-		&piece.Description,
-		&piece.ImageURL,
-		&piece.ThumbnailURL,
-		&piece.Category,
-		&piece.Tags,
-		&piece.SourceLink,
-		&piece.PurchaseDate,
-		&piece.Price,
-		&piece.CreatedAt,
-		&piece.UpdatedAt,
-	)
-
-	if err != nil {
+	if err := scanPiece(r.db.QueryRow(ctx, query, id), piece); err != nil {
 		if err == pgx.ErrNoRows {
-			return nil, fmt.Errorf("piece not found")
+			return nil, ErrPieceNotFound
 		}
 		return nil, fmt.Errorf("failed to get piece: %w", err)
 	}
@@ -86,101 +116,139 @@ func (r *PieceRepository) GetPieceByID(id uuid.UUID) (*models.Piece, error) {
 	return piece, nil
 }
 
-// GetPiecesByUserID retrieves all pieces for a specific user
-func (r *PieceRepository) GetPiecesByUserID(userID uuid.UUID, limit, offset int) ([]*models.Piece, error) {
-	ctx := context.Background()
-	query := `
-		SELECT id, user_id, name, description, image_url, thumbnail_url, category, tags, source_link, purchase_date, price, created_at, updated_at
-		FROM pieces
-		WHERE user_id = $1
-		ORDER BY created_at DESC
-		LIMIT $2 OFFSET $3`
+// PieceFilter narrows and orders a list of pieces. Zero fields don't filter.
+type PieceFilter struct {
+	// Categories keeps pieces in any of the categories
+	Categories []string
+	// Tags keeps pieces with any of the tags, or all of them with MatchAllTags
+	Tags         []string
+	MatchAllTags bool
+	// MinPrice and MaxPrice compare prices converted into the home currency,
+	// which they must be in
+	MinPrice      *models.Money
+	MaxPrice      *models.Money
+	PurchasedFrom *time.Time
+	PurchasedTo   *time.Time
+	// Unlinked keeps pieces that aren't part of any build
+	Unlinked bool
+	// HasImage keeps pieces with an image when true and without one when false
+	HasImage *bool
+	// Search matches words anywhere in the piece's text, including stemmed
+	// forms, and close misspellings of its name
+	Search string
+	// Sort is a key of PieceSortKeys, prefixed with "-" to sort descending.
+	// It defaults to "-created_at".
+	Sort string
+}
 
-	rows, err := r.db.Query(ctx, query, userID, limit, offset)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get pieces: %w", err)
-	}
-	defer rows.Close()
+// pieceHomePrice is a piece's price in its owner's home currency, converted
+// at the rate of its purchase date, or today's when it has none. Sorts use
+// the stored price instead, as this one moves with the date and the rates
+// loaded, which would shift rows between pages.
+const pieceHomePrice = `home_minor(user_id, price_minor, price_currency, COALESCE(purchase_date, CURRENT_DATE))`
+
+// pieceSortKeys are the columns pieces may be sorted by
+var pieceSortKeys = map[string]sortKey{
+	"created_at":    {column: "created_at", sqlType: "timestamptz"},
+	"updated_at":    {column: "updated_at", sqlType: "timestamptz"},
+	"name":          {column: "name", sqlType: "text"},
+	"price":         {column: "price_minor", sqlType: "numeric", nullable: true, then: "price_currency"},
+	"purchase_date": {column: "purchase_date", sqlType: "date", nullable: true},
+}
 
-	var pieces []*models.Piece
-	for rows.Next() {
-		piece := &models.Piece{}
-		err := rows.Scan(
-			&piece.ID,
-			&piece.UserID,
-			&piece.Name,
-			&piece.Description,
-			&piece.ImageURL,
-			&piece.ThumbnailURL,
-			&piece.Category,
-			&piece.Tags,
-			&piece.SourceLink,
-			&piece.PurchaseDate,
-			&piece.Price,
-			&piece.CreatedAt,
-			&piece.UpdatedAt,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan piece: %w", err)
+// PieceSortKeys lists the keys PieceFilter.Sort accepts
+func PieceSortKeys() []string {
+	return sortKeyNames(pieceSortKeys)
+}
+
+// where adds the filter's conditions to a query over a user's pieces
+func (f PieceFilter) where(q *listQuery, userID uuid.UUID) {
+	q.where("user_id = ?", userID)
+
+	if len(f.Categories) > 0 {
+		q.where("category = ANY(?::text[])", f.Categories)
+	}
+	if len(f.Tags) > 0 {
+		if f.MatchAllTags {
+			q.where("tags @> ?::text[]", f.Tags)
+		} else {
+			q.where("tags && ?::text[]", f.Tags)
 		}
-		pieces = append(pieces, piece)
 	}
-
-	return pieces, nil
+	if f.MinPrice != nil {
+		q.where(pieceHomePrice+" >= ?", f.MinPrice.MinorUnits)
+	}
+	if f.MaxPrice != nil {
+		q.where(pieceHomePrice+" <= ?", f.MaxPrice.MinorUnits)
+	}
+	if f.PurchasedFrom != nil {
+		q.where("purchase_date >= ?::date", *f.PurchasedFrom)
+	}
+	if f.PurchasedTo != nil {
+		q.where("purchase_date <= ?::date", *f.PurchasedTo)
+	}
+	if f.Unlinked {
+		q.where("NOT EXISTS (SELECT 1 FROM build_pieces bp WHERE bp.piece_id = pieces.id)")
+	}
+	if f.HasImage != nil {
+		if *f.HasImage {
+			q.where("image_key IS NOT NULL OR image_url IS NOT NULL")
+		} else {
+			q.where("image_key IS NULL AND image_url IS NULL")
+		}
+	}
+	if f.Search != "" {
+		q.where(`search_vector @@ websearch_to_tsquery('english', ?) OR ?::text <% name`, f.Search, f.Search)
+	}
 }
 
-// GetPiecesByCategory retrieves pieces by category for a specific user
-func (r *PieceRepository) GetPiecesByCategory(userID uuid.UUID, category string, limit, offset int) ([]*models.Piece, error) {
-	ctx := context.Background()
-	query := `
-		SELECT id, user_id, name, description, image_url, thumbnail_url, category, tags, source_link, purchase_date, price, created_at, updated_at
-		FROM pieces
-		WHERE user_id = $1 AND category = $2
-		ORDER BY created_at DESC
-		LIMIT $3 OFFSET $4`
+// ListPieces retrieves a page of a user's pieces matching the filter, starting
+// after the given cursor. The returned cursor is nil on the last page.
+func (r *PieceRepository) ListPieces(userID uuid.UUID, filter PieceFilter, after *Cursor, limit int) ([]*models.Piece, *Cursor, error) {
+	order, err := parseSort(filter.Sort, pieceSortKeys, "-created_at")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	q := &listQuery{}
+	filter.where(q, userID)
 
-	rows, err := r.db.Query(ctx, query, userID, category, limit, offset)
+	pieces, next, err := queryPage(r.db, q, "pieces", pieceColumns, order, after, limit, scanPiece,
+		func(p *models.Piece) uuid.UUID { return p.ID })
 	if err != nil {
-		return nil, fmt.Errorf("failed to get pieces by category: %w", err)
+		return nil, nil, fmt.Errorf("failed to get pieces: %w", err)
 	}
-	defer rows.Close()
 
-	var pieces []*models.Piece
-	for rows.Next() {
-		piece := &models.Piece{}
-		err := rows.Scan(
-			&piece.ID,
-			&piece.UserID,
-			&piece.Name,
-			&piece.Description,
-			&piece.ImageURL,
-			&piece.ThumbnailURL,
-			&piece.Category,
-			&piece.Tags,
-			&piece.SourceLink,
-			&piece.PurchaseDate,
-			&piece.Price,
-			&piece.CreatedAt,
-			&piece.UpdatedAt,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan piece: %w", err)
-		}
-		pieces = append(pieces, piece)
+	return pieces, next, nil
+}
+
+// CountPieces returns how many of a user's pieces match the filter
+func (r *PieceRepository) CountPieces(userID uuid.UUID, filter PieceFilter) (int, error) {
+	q := &listQuery{}
+	filter.where(q, userID)
+
+	count, err := q.count(r.db, "pieces")
+	if err != nil {
+		return 0, fmt.Errorf("failed to get piece count: %w", err)
 	}
 
-	return pieces, nil
+	return count, nil
 }
 
-// UpdatePiece updates an existing piece
+// UpdatePiece updates an existing piece. Uploaded images the update replaces
+// are deleted from storage once no piece references them.
 func (r *PieceRepository) UpdatePiece(piece *models.Piece) error {
 	ctx := context.Background()
 	query := `
-		UPDATE pieces
-		SET name = $2, description = $3, image_url = $4, thumbnail_url = $5, category = $6, tags = $7, source_link = $8, purchase_date = $9, price = $10, updated_at = $11
-		WHERE id = $1 AND user_id = $12
-		RETURNING updated_at`
-
+		UPDATE pieces p
+		SET name = $2, description = $3, image_url = $4, image_bg_removed_url = $5, thumbnail_url = $6,
+			image_key = $7, image_bg_removed_key = $8, thumbnail_key = $9, category = $10, tags = $11,
+			source_link = $12, purchase_date = $13, price_minor = $14, price_currency = $15, updated_at = $16
+		FROM (SELECT id, image_key, image_bg_removed_key, thumbnail_key FROM pieces WHERE id = $1 AND user_id = $17 FOR UPDATE) old
+		WHERE p.id = old.id
+		RETURNING p.category, p.tags, p.updated_at, old.image_key, old.image_bg_removed_key, old.thumbnail_key`
+
+	old := &models.Piece{}
 	err := r.db.QueryRow(
 		ctx,
 		query,
@@ -188,15 +256,20 @@ func (r *PieceRepository) UpdatePiece(piece *models.Piece) error {
 		piece.Name,
 		piece.Description,
 		piece.ImageURL,
+		piece.CutoutURL,
 		piece.ThumbnailURL,
+		piece.ImageKey,
+		piece.CutoutKey,
+		piece.ThumbnailKey,
 		piece.Category,
 		piece.Tags,
 		piece.SourceLink,
 		piece.PurchaseDate,
-		piece.Price,
+		minorUnits(piece.Price),
+		currencyOf(piece.Price),
 		piece.UpdatedAt,
 		piece.UserID,
-	).Scan(&piece.UpdatedAt)
+	).Scan(&piece.Category, &piece.Tags, &piece.UpdatedAt, &old.ImageKey, &old.CutoutKey, &old.ThumbnailKey)
 
 	if err != nil {
 		if err == pgx.ErrNoRows {
@@ -205,86 +278,397 @@ func (r *PieceRepository) UpdatePiece(piece *models.Piece) error {
 		return fmt.Errorf("failed to update piece: %w", err)
 	}
 
+	r.releaseBlobs(ctx, old.BlobKeys())
 	return nil
 }
 
-// DeletePiece deletes a piece by ID
-func (r *PieceRepository) DeletePiece(id uuid.UUID, userID uuid.UUID) error {
+// UpdatePieceImages sets a piece's uploaded image, its background-removed
+// cutout and its thumbnail. The images they replace are deleted from storage
+// once no piece references them.
+func (r *PieceRepository) UpdatePieceImages(piece *models.Piece) error {
 	ctx := context.Background()
-	query := `DELETE FROM pieces WHERE id = $1 AND user_id = $2`
+	query := `
+		UPDATE pieces p
+		SET image_url = $3, image_bg_removed_url = $4, thumbnail_url = $5,
+			image_key = $6, image_bg_removed_key = $7, thumbnail_key = $8, updated_at = NOW()
+		FROM (SELECT id, image_key, image_bg_removed_key, thumbnail_key FROM pieces WHERE id = $1 AND user_id = $2 FOR UPDATE) old
+		WHERE p.id = old.id
+		RETURNING p.updated_at, old.image_key, old.image_bg_removed_key, old.thumbnail_key`
+
+	old := &models.Piece{}
+	err := r.db.QueryRow(
+		ctx,
+		query,
+		piece.ID,
+		piece.UserID,
+		piece.ImageURL,
+		piece.CutoutURL,
+		piece.ThumbnailURL,
+		piece.ImageKey,
+		piece.CutoutKey,
+		piece.ThumbnailKey,
+	).Scan(&piece.UpdatedAt, &old.ImageKey, &old.CutoutKey, &old.ThumbnailKey)
 
-	result, err := r.db.Exec(ctx, query, id, userID)
 	if err != nil {
-		return fmt.Errorf("failed to delete piece: %w", err)
+		if err == pgx.ErrNoRows {
+			return fmt.Errorf("piece not found or access denied")
+		}
+		return fmt.Errorf("failed to update piece images: %w", err)
 	}
 
-	rowsAffected := result.RowsAffected()
-	if rowsAffected == 0 {
-		return fmt.Errorf("piece not found or access denied")
+	r.releaseBlobs(ctx, old.BlobKeys())
+	return nil
+}
+
+// SetPieceCutout stores the background-removed cutout of a piece's image and
+// the thumbnail made from it. It does nothing and returns false if the piece
+// is gone or its image is no longer imageKey, so a slow job can't overwrite a
+// newer upload.
+func (r *PieceRepository) SetPieceCutout(id uuid.UUID, imageKey, cutoutKey, thumbnailKey string) (bool, error) {
+	ctx := context.Background()
+	query := `
+		UPDATE pieces p
+		SET image_bg_removed_url = NULL, image_bg_removed_key = $3, thumbnail_url = NULL, thumbnail_key = $4, updated_at = NOW()
+		FROM (SELECT id, image_bg_removed_key, thumbnail_key FROM pieces WHERE id = $1 AND image_key = $2 FOR UPDATE) old
+		WHERE p.id = old.id
+		RETURNING old.image_bg_removed_key, old.thumbnail_key`
+
+	old := &models.Piece{}
+	err := r.db.QueryRow(ctx, query, id, imageKey, cutoutKey, thumbnailKey).Scan(&old.CutoutKey, &old.ThumbnailKey)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to update piece images: %w", err)
 	}
 
-	return nil
+	r.releaseBlobs(ctx, old.BlobKeys())
+	return true, nil
 }
 
-// SearchPieces searches pieces by name, description, or tags
-func (r *PieceRepository) SearchPieces(userID uuid.UUID, searchTerm string, limit, offset int) ([]*models.Piece, error) {
+// DeletePiece deletes a piece by ID, along with any uploaded images no other
+// piece references
+func (r *PieceRepository) DeletePiece(id uuid.UUID, userID uuid.UUID) error {
 	ctx := context.Background()
 	query := `
-		SELECT id, user_id, name, description, image_url, thumbnail_url, category, tags, source_link, purchase_date, price, created_at, updated_at
-		FROM pieces
-		WHERE user_id = $1 AND (
-			name ILIKE $2 OR 
-			description ILIKE $2 OR 
-			category ILIKE $2 OR
-			$2 = ANY(tags)
-		)
-		ORDER BY created_at DESC
-		LIMIT $3 OFFSET $4`
-
-	searchPattern := "%" + searchTerm + "%"
-	rows, err := r.db.Query(ctx, query, userID, searchPattern, limit, offset)
+		DELETE FROM pieces
+		WHERE id = $1 AND user_id = $2
+		RETURNING image_key, image_bg_removed_key, thumbnail_key`
+
+	deleted := &models.Piece{}
+	err := r.db.QueryRow(ctx, query, id, userID).Scan(&deleted.ImageKey, &deleted.CutoutKey, &deleted.ThumbnailKey)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return fmt.Errorf("piece not found or access denied")
+		}
+		return fmt.Errorf("failed to delete piece: %w", err)
+	}
+
+	r.releaseBlobs(ctx, deleted.BlobKeys())
+	return nil
+}
+
+// releaseBlobs deletes the blobs among keys that no piece references any more.
+// Failures are only logged: the database change they follow has already
+// happened, and an orphaned blob is harmless.
+func (r *PieceRepository) releaseBlobs(ctx context.Context, keys []string) {
+	if len(keys) == 0 {
+		return
+	}
+
+	query := `
+		SELECT DISTINCT k
+		FROM unnest($1::text[]) AS k
+		WHERE NOT EXISTS (
+			SELECT 1 FROM pieces
+			WHERE image_key = k OR image_bg_removed_key = k OR thumbnail_key = k
+		)`
+
+	rows, err := r.db.Query(ctx, query, keys)
 	if err != nil {
-		return nil, fmt.Errorf("failed to search pieces: %w", err)
+		log.Printf("Failed to find unreferenced blobs: %v", err)
+		return
 	}
 	defer rows.Close()
 
-	var pieces []*models.Piece
+	var unreferenced []string
 	for rows.Next() {
-		piece := &models.Piece{}
-		err := rows.Scan(
-			&piece.ID,
-			&piece.UserID,
-			&piece.Name,
-			&piece.Description,
-			&piece.ImageURL,
-			&piece.ThumbnailURL,
-			&piece.Category,
-			&piece.Tags,
-			&piece.SourceLink,
-			&piece.PurchaseDate,
-			&piece.Price,
-			&piece.CreatedAt,
-			&piece.UpdatedAt,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan piece: %w", err)
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			log.Printf("Failed to scan blob key: %v", err)
+			return
 		}
-		pieces = append(pieces, piece)
+		unreferenced = append(unreferenced, key)
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("Failed to find unreferenced blobs: %v", err)
+		return
 	}
 
-	return pieces, nil
+	for _, key := range unreferenced {
+		if err := r.blobs.Delete(ctx, key); err != nil {
+			log.Printf("Failed to delete blob %s: %v", key, err)
+		}
+	}
 }
 
 // GetPieceCount returns the total count of pieces for a user
 func (r *PieceRepository) GetPieceCount(userID uuid.UUID) (int, error) {
+	return r.CountPieces(userID, PieceFilter{})
+}
+
+// CountOwnedPieces returns how many of the given pieces belong to the user
+func (r *PieceRepository) CountOwnedPieces(userID uuid.UUID, pieceIDs []uuid.UUID) (int, error) {
 	ctx := context.Background()
-	query := `SELECT COUNT(*) FROM pieces WHERE user_id = $1`
+	query := `SELECT COUNT(*) FROM pieces WHERE user_id = $1 AND id = ANY($2::uuid[])`
 
 	var count int
-	err := r.db.QueryRow(ctx, query, userID).Scan(&count)
+	err := r.db.QueryRow(ctx, query, userID, pieceIDs).Scan(&count)
 	if err != nil {
-		return 0, fmt.Errorf("failed to get piece count: %w", err)
+		return 0, fmt.Errorf("failed to count owned pieces: %w", err)
 	}
 
 	return count, nil
 }
+
+// pieceStatsListLimit is how many pieces each ranking in piece stats lists
+const pieceStatsListLimit = 5
+
+// pieceHomePrices selects a user's pieces with their price converted into
+// the currency $2 as home_price
+const pieceHomePrices = `(
+			SELECT *, convert_minor(price_minor, price_currency, $2, COALESCE(purchase_date, CURRENT_DATE)) AS home_price
+			FROM pieces
+			WHERE user_id = $1
+		) pieces`
+
+// GetPieceStats summarizes a user's pieces: counts and spend by category, tag
+// and purchase month, and how often pieces are reused across builds. Spend is
+// reported in the user's home currency.
+func (r *PieceRepository) GetPieceStats(userID uuid.UUID) (*models.PieceStats, error) {
+	ctx := context.Background()
+
+	currency, err := homeCurrency(ctx, r.db, userID)
+	if err != nil {
+		return nil, err
+	}
+	stats := &models.PieceStats{}
+
+	if stats.ByCategory, err = r.getPieceGroupStats(ctx, userID, currency, stats); err != nil {
+		return nil, err
+	}
+	if stats.ByTag, err = r.getPieceTagStats(ctx, userID, currency); err != nil {
+		return nil, err
+	}
+	if stats.SpendByMonth, err = r.getPieceSpendByMonth(ctx, userID, currency); err != nil {
+		return nil, err
+	}
+	if err := r.getPieceReuse(ctx, userID, stats); err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// getPieceGroupStats returns piece counts and spend by category, filling in
+// the totals of piece stats from the same query. The empty grouping set adds
+// a row over all pieces, told apart from the uncategorized group by GROUPING.
+func (r *PieceRepository) getPieceGroupStats(ctx context.Context, userID uuid.UUID, currency string, stats *models.PieceStats) ([]models.PieceGroupStats, error) {
+	query := `
+		SELECT GROUPING(category) = 0, category, COUNT(*), COALESCE(SUM(home_price), 0)::bigint,
+			COUNT(*) FILTER (WHERE price_minor IS NOT NULL AND home_price IS NULL)
+		FROM ` + pieceHomePrices + `
+		GROUP BY GROUPING SETS ((category), ())
+		ORDER BY COUNT(*) DESC, category`
+
+	rows, err := r.db.Query(ctx, query, userID, currency)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get piece category stats: %w", err)
+	}
+	defer rows.Close()
+
+	groups := []models.PieceGroupStats{}
+	for rows.Next() {
+		var grouped bool
+		var unconverted int
+		group := models.PieceGroupStats{Spent: models.Money{Currency: currency}}
+		if err := rows.Scan(&grouped, &group.Name, &group.Pieces, &group.Spent.MinorUnits, &unconverted); err != nil {
+			return nil, fmt.Errorf("failed to scan piece category stats: %w", err)
+		}
+		if grouped {
+			groups = append(groups, group)
+		} else {
+			stats.TotalPieces, stats.TotalSpent, stats.UnconvertedPieces = group.Pieces, group.Spent, unconverted
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get piece category stats: %w", err)
+	}
+
+	return groups, nil
+}
+
+// getPieceTagStats returns piece counts and spend by tag
+func (r *PieceRepository) getPieceTagStats(ctx context.Context, userID uuid.UUID, currency string) ([]models.PieceGroupStats, error) {
+	query := `
+		SELECT tag, COUNT(*), COALESCE(SUM(home_price), 0)::bigint
+		FROM ` + pieceHomePrices + `, unnest(tags) AS tag
+		GROUP BY tag
+		ORDER BY COUNT(*) DESC, tag`
+
+	rows, err := r.db.Query(ctx, query, userID, currency)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get piece tag stats: %w", err)
+	}
+	defer rows.Close()
+
+	groups := []models.PieceGroupStats{}
+	for rows.Next() {
+		group := models.PieceGroupStats{Spent: models.Money{Currency: currency}}
+		if err := rows.Scan(&group.Name, &group.Pieces, &group.Spent.MinorUnits); err != nil {
+			return nil, fmt.Errorf("failed to scan piece tag stats: %w", err)
+		}
+		groups = append(groups, group)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get piece tag stats: %w", err)
+	}
+
+	return groups, nil
+}
+
+// getPieceSpendByMonth returns what was spent on pieces each month over the
+// last year by purchase date, with empty months included
+func (r *PieceRepository) getPieceSpendByMonth(ctx context.Context, userID uuid.UUID, currency string) ([]models.MonthlySpend, error) {
+	since, months := trendMonthKeys()
+	query := `
+		SELECT to_char(purchase_date, 'YYYY-MM'), COUNT(*), COALESCE(SUM(home_price), 0)::bigint
+		FROM ` + pieceHomePrices + `
+		WHERE purchase_date >= $3::date
+		GROUP BY 1`
+
+	rows, err := r.db.Query(ctx, query, userID, currency, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get piece spend stats: %w", err)
+	}
+	defer rows.Close()
+
+	spent := make(map[string]models.MonthlySpend)
+	for rows.Next() {
+		month := models.MonthlySpend{Spent: models.Money{Currency: currency}}
+		if err := rows.Scan(&month.Month, &month.Pieces, &month.Spent.MinorUnits); err != nil {
+			return nil, fmt.Errorf("failed to scan piece spend stats: %w", err)
+		}
+		spent[month.Month] = month
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get piece spend stats: %w", err)
+	}
+
+	spendByMonth := make([]models.MonthlySpend, 0, len(months))
+	for _, key := range months {
+		month, ok := spent[key]
+		if !ok {
+			month = models.MonthlySpend{Month: key, Spent: models.Money{Currency: currency}}
+		}
+		spendByMonth = append(spendByMonth, month)
+	}
+
+	return spendByMonth, nil
+}
+
+// getPieceReuse fills in the reuse rankings of piece stats. Each piece is
+// ranked from most to least used and, separately among used and unused
+// pieces, from least to most used, so one query returns the head of every list.
+func (r *PieceRepository) getPieceReuse(ctx context.Context, userID uuid.UUID, stats *models.PieceStats) error {
+	query := `
+		SELECT id, name, category, builds, most_rank, least_rank, unused
+		FROM (
+			SELECT id, name, category, builds,
+				ROW_NUMBER() OVER (ORDER BY builds DESC, name, id) AS most_rank,
+				ROW_NUMBER() OVER (PARTITION BY builds = 0 ORDER BY builds, name, id) AS least_rank,
+				COUNT(*) FILTER (WHERE builds = 0) OVER () AS unused
+			FROM (
+				SELECT p.id, p.name, p.category, COUNT(DISTINCT bp.build_id) AS builds
+				FROM pieces p
+				LEFT JOIN build_pieces bp ON bp.piece_id = p.id
+				WHERE p.user_id = $1
+				GROUP BY p.id
+			) usage
+		) ranked
+		WHERE most_rank <= $2 OR least_rank <= $2
+		ORDER BY least_rank`
+
+	rows, err := r.db.Query(ctx, query, userID, pieceStatsListLimit)
+	if err != nil {
+		return fmt.Errorf("failed to get piece reuse stats: %w", err)
+	}
+	defer rows.Close()
+
+	var mostReused []models.PieceReuse
+	var mostRanks []int
+	stats.LeastReused = []models.PieceReuse{}
+	stats.Unused = []models.PieceReuse{}
+	for rows.Next() {
+		var piece models.PieceReuse
+		var mostRank, leastRank int
+		if err := rows.Scan(&piece.ID, &piece.Name, &piece.Category, &piece.Builds, &mostRank, &leastRank, &stats.UnusedPieces); err != nil {
+			return fmt.Errorf("failed to scan piece reuse stats: %w", err)
+		}
+		if piece.Builds == 0 {
+			if leastRank <= pieceStatsListLimit {
+				stats.Unused = append(stats.Unused, piece)
+			}
+			continue
+		}
+		if leastRank <= pieceStatsListLimit {
+			stats.LeastReused = append(stats.LeastReused, piece)
+		}
+		if mostRank <= pieceStatsListLimit {
+			mostReused = append(mostReused, piece)
+			mostRanks = append(mostRanks, mostRank)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to get piece reuse stats: %w", err)
+	}
+
+	// Rows come least used first, so most used pieces are placed by rank
+	stats.MostReused = make([]models.PieceReuse, len(mostReused))
+	for i, piece := range mostReused {
+		stats.MostReused[mostRanks[i]-1] = piece
+	}
+
+	return nil
+}
+
+// GetCategories returns the categories a user's pieces are in, most used first
+func (r *PieceRepository) GetCategories(userID uuid.UUID) ([]models.CategoryCount, error) {
+	ctx := context.Background()
+	query := `
+		SELECT category, COUNT(*)
+		FROM pieces
+		WHERE user_id = $1 AND category IS NOT NULL AND category <> ''
+		GROUP BY category
+		ORDER BY COUNT(*) DESC, category`
+
+	rows, err := r.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get categories: %w", err)
+	}
+	defer rows.Close()
+
+	categories := []models.CategoryCount{}
+	for rows.Next() {
+		var category models.CategoryCount
+		if err := rows.Scan(&category.Category, &category.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan category: %w", err)
+		}
+		categories = append(categories, category)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get categories: %w", err)
+	}
+
+	return categories, nil
+}
@@ -0,0 +1,242 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ErrInvalidSort is returned for a sort that isn't one a list allows
+var ErrInvalidSort = errors.New("invalid sort")
+
+// sortKey is a column a list may be sorted by
+type sortKey struct {
+	column string
+	// sqlType is what a cursor's value is cast back to: timestamptz, date,
+	// numeric or text
+	sqlType string
+	// nullable columns are sorted through COALESCE so NULLs compare like any
+	// other value and come last in either direction
+	nullable bool
+	// then is a text column breaking ties before the row's ID, such as the
+	// currency of an amount. It's NULL only where column is.
+	then string
+}
+
+// nullSentinels are the lowest and highest values of each sortable type,
+// standing in for NULL in sort keys
+var nullSentinels = map[string][2]string{
+	"timestamptz": {"'-infinity'::timestamptz", "'infinity'::timestamptz"},
+	"date":        {"'-infinity'::date", "'infinity'::date"},
+	"numeric":     {"-1e15", "1e15"},
+}
+
+// sortKeyNames returns the names of a list's sort keys in order
+func sortKeyNames(keys map[string]sortKey) []string {
+	names := make([]string, 0, len(keys))
+	for name := range keys {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// listSort is a parsed sort parameter such as "-created_at", where a leading
+// "-" sorts descending
+type listSort struct {
+	name string
+	key  sortKey
+	desc bool
+}
+
+// parseSort checks a sort parameter against the keys a list allows. An empty
+// sort falls back to fallback.
+func parseSort(param string, keys map[string]sortKey, fallback string) (listSort, error) {
+	if param == "" {
+		param = fallback
+	}
+	key, ok := keys[strings.TrimPrefix(param, "-")]
+	if !ok {
+		return listSort{}, ErrInvalidSort
+	}
+	return listSort{name: param, key: key, desc: strings.HasPrefix(param, "-")}, nil
+}
+
+// expr returns the SQL expression rows are ordered by
+func (s listSort) expr() string {
+	if !s.key.nullable {
+		return s.key.column
+	}
+	sentinel := nullSentinels[s.key.sqlType][1]
+	if s.desc {
+		sentinel = nullSentinels[s.key.sqlType][0]
+	}
+	return "COALESCE(" + s.key.column + ", " + sentinel + ")"
+}
+
+// thenExpr returns the SQL expression ties are broken by, or "" without one
+func (s listSort) thenExpr() string {
+	if s.key.then == "" || !s.key.nullable {
+		return s.key.then
+	}
+	return "COALESCE(" + s.key.then + ", '')"
+}
+
+// listQuery builds the WHERE clause of a filtered list from conditions
+// written with ? placeholders, numbering the arguments as it goes
+type listQuery struct {
+	conditions []string
+	args       []interface{}
+}
+
+// where adds a condition. Each ? in it is bound to the next of args.
+func (q *listQuery) where(condition string, args ...interface{}) {
+	var b strings.Builder
+	for _, arg := range args {
+		i := strings.IndexByte(condition, '?')
+		if i < 0 {
+			panic("listQuery: more arguments than placeholders in " + condition)
+		}
+		q.args = append(q.args, arg)
+		b.WriteString(condition[:i])
+		b.WriteString("$" + strconv.Itoa(len(q.args)))
+		condition = condition[i+1:]
+	}
+	b.WriteString(condition)
+	q.conditions = append(q.conditions, "("+b.String()+")")
+}
+
+// whereClause returns the conditions joined into a WHERE clause
+func (q *listQuery) whereClause() string {
+	if len(q.conditions) == 0 {
+		return ""
+	}
+	return "WHERE " + strings.Join(q.conditions, " AND ")
+}
+
+// count returns how many rows of table match the conditions
+func (q *listQuery) count(db *pgxpool.Pool, table string) (int, error) {
+	ctx := context.Background()
+	query := `SELECT COUNT(*) FROM ` + table + ` ` + q.whereClause()
+
+	var count int
+	if err := db.QueryRow(ctx, query, q.args...).Scan(&count); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// page returns the query for one page of matching rows, sorted by order and
+// starting after the cursor. It selects one row more than limit, so the caller
+// can tell whether another page follows, and the row's sort key as text last,
+// followed by its tiebreak when the sort has one.
+func (q listQuery) page(table, columns string, order listSort, after *Cursor, limit int) (string, []interface{}, error) {
+	// q is a copy, so the keyset condition doesn't leak into later counts
+	q.conditions = append([]string(nil), q.conditions...)
+	q.args = append([]interface{}(nil), q.args...)
+
+	expr, then := order.expr(), order.thenExpr()
+	direction, comparison := "ASC", ">"
+	if order.desc {
+		direction, comparison = "DESC", "<"
+	}
+
+	keys, selected, ordering := expr, "("+expr+")::text", expr+" "+direction
+	if then != "" {
+		keys += ", " + then
+		selected += ", " + then
+		ordering += ", " + then + " " + direction
+	}
+
+	if after != nil {
+		if after.Sort != order.name || !after.valid(order.key.sqlType) {
+			return "", nil, ErrInvalidCursor
+		}
+		if then == "" {
+			q.where("("+keys+", id) "+comparison+" (?::"+order.key.sqlType+", ?)", after.Value, after.ID)
+		} else {
+			q.where("("+keys+", id) "+comparison+" (?::"+order.key.sqlType+", ?::text, ?)", after.Value, after.Then, after.ID)
+		}
+	}
+
+	query := `
+		SELECT ` + columns + `, ` + selected + `
+		FROM ` + table + `
+		` + q.whereClause() + `
+		ORDER BY ` + ordering + `, id ` + direction + `
+		LIMIT ` + strconv.Itoa(limit+1)
+
+	return query, q.args, nil
+}
+
+// sortKeyRow appends the sort key selected by listQuery.page, and its
+// tiebreak when then is set, to a row's scan
+type sortKeyRow struct {
+	pgx.Row
+	sortKey *string
+	then    *string
+}
+
+func (r sortKeyRow) Scan(dest ...interface{}) error {
+	dest = append(dest, r.sortKey)
+	if r.then != nil {
+		dest = append(dest, r.then)
+	}
+	return r.Row.Scan(dest...)
+}
+
+// queryPage runs a page query from listQuery.page, scanning each row with
+// scan. It returns the cursor of the next page, or nil on the last page.
+func queryPage[T any](db *pgxpool.Pool, q *listQuery, table, columns string, order listSort, after *Cursor, limit int,
+	scan func(pgx.Row, *T) error, id func(*T) uuid.UUID) ([]*T, *Cursor, error) {
+	ctx := context.Background()
+
+	query, args, err := q.page(table, columns, order, after, limit)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rows, err := db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	var items []*T
+	var keys, thens []string
+	for rows.Next() {
+		item := new(T)
+		row := sortKeyRow{Row: rows, sortKey: new(string)}
+		if order.key.then != "" {
+			row.then = new(string)
+		}
+		if err := scan(row, item); err != nil {
+			return nil, nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		items = append(items, item)
+		keys = append(keys, *row.sortKey)
+		if row.then != nil {
+			thens = append(thens, *row.then)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	if len(items) <= limit {
+		return items, nil, nil
+	}
+	items = items[:limit]
+	next := &Cursor{Sort: order.name, Value: keys[limit-1], ID: id(items[limit-1])}
+	if thens != nil {
+		next.Then = thens[limit-1]
+	}
+	return items, next, nil
+}
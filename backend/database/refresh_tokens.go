@@ -0,0 +1,157 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"kyarafit-backend/models"
+)
+
+var (
+	// ErrInvalidRefreshToken is returned for unknown, expired or revoked refresh tokens
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	// ErrRefreshTokenReused is returned when an already rotated refresh token is
+	// presented again. The token's whole family is revoked when this happens.
+	ErrRefreshTokenReused = errors.New("refresh token reuse detected")
+)
+
+type RefreshTokenRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewRefreshTokenRepository(db *pgxpool.Pool) *RefreshTokenRepository {
+	return &RefreshTokenRepository{db: db}
+}
+
+// CreateRefreshToken stores a newly issued refresh token
+func (r *RefreshTokenRepository) CreateRefreshToken(token *models.RefreshToken) error {
+	return insertRefreshToken(context.Background(), r.db, token)
+}
+
+func insertRefreshToken(ctx context.Context, q rowQuerier, token *models.RefreshToken) error {
+	query := `
+		INSERT INTO refresh_tokens (id, user_id, family_id, token_hash, expires_at, user_agent, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at`
+
+	err := q.QueryRow(
+		ctx,
+		query,
+		token.ID,
+		token.UserID,
+		token.FamilyID,
+		token.TokenHash,
+		token.ExpiresAt,
+		token.UserAgent,
+		token.CreatedAt,
+	).Scan(&token.ID, &token.CreatedAt)
+
+	if err != nil {
+		return fmt.Errorf("failed to create refresh token: %w", err)
+	}
+
+	return nil
+}
+
+// RotateRefreshToken exchanges the refresh token stored under oldHash for next,
+// which joins the same family. Presenting a token that was already rotated or
+// revoked revokes its whole family and returns ErrRefreshTokenReused.
+func (r *RefreshTokenRepository) RotateRefreshToken(oldHash string, next *models.RefreshToken) error {
+	ctx := context.Background()
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		SELECT id, user_id, family_id, expires_at, revoked_at
+		FROM refresh_tokens
+		WHERE token_hash = $1
+		FOR UPDATE`
+
+	var current models.RefreshToken
+	err = tx.QueryRow(ctx, query, oldHash).Scan(
+		&current.ID,
+		&current.UserID,
+		&current.FamilyID,
+		&current.ExpiresAt,
+		&current.RevokedAt,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return ErrInvalidRefreshToken
+		}
+		return fmt.Errorf("failed to get refresh token: %w", err)
+	}
+
+	if current.RevokedAt != nil {
+		if err := revokeFamily(ctx, tx, current.FamilyID); err != nil {
+			return err
+		}
+		if err := tx.Commit(ctx); err != nil {
+			return fmt.Errorf("failed to commit transaction: %w", err)
+		}
+		return ErrRefreshTokenReused
+	}
+	if time.Now().After(current.ExpiresAt) {
+		return ErrInvalidRefreshToken
+	}
+
+	next.UserID = current.UserID
+	next.FamilyID = current.FamilyID
+	if err := insertRefreshToken(ctx, tx, next); err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `UPDATE refresh_tokens SET revoked_at = NOW(), replaced_by = $2 WHERE id = $1`, current.ID, next.ID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke refresh token: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// RevokeRefreshTokenFamily revokes the family of the refresh token stored under
+// hash, ending that login session on every rotation of it
+func (r *RefreshTokenRepository) RevokeRefreshTokenFamily(hash string) error {
+	ctx := context.Background()
+
+	var familyID uuid.UUID
+	err := r.db.QueryRow(ctx, `SELECT family_id FROM refresh_tokens WHERE token_hash = $1`, hash).Scan(&familyID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return ErrInvalidRefreshToken
+		}
+		return fmt.Errorf("failed to get refresh token: %w", err)
+	}
+
+	result, err := r.db.Exec(ctx, `UPDATE refresh_tokens SET revoked_at = NOW() WHERE family_id = $1 AND revoked_at IS NULL`, familyID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke refresh tokens: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrInvalidRefreshToken
+	}
+
+	return nil
+}
+
+func revokeFamily(ctx context.Context, tx pgx.Tx, familyID uuid.UUID) error {
+	_, err := tx.Exec(ctx, `UPDATE refresh_tokens SET revoked_at = NOW() WHERE family_id = $1 AND revoked_at IS NULL`, familyID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke refresh tokens: %w", err)
+	}
+	return nil
+}
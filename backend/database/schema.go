@@ -0,0 +1,134 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// requiredColumns lists the columns each repository reads or writes.
+// VerifySchema checks them at startup so a missing migration fails fast
+// instead of surfacing as errors on every CRUD call.
+var requiredColumns = map[string][]string{
+	"users": {
+		"id", "email", "username", "display_name", "avatar_url", "password_hash", "home_currency", "created_at", "updated_at",
+	},
+	"refresh_tokens": {
+		"id", "user_id", "family_id", "token_hash", "expires_at", "revoked_at", "replaced_by",
+		"user_agent", "created_at",
+	},
+	"pieces": {
+		"id", "user_id", "name", "description", "image_url", "image_bg_removed_url", "thumbnail_url",
+		"image_key", "image_bg_removed_key", "thumbnail_key",
+		"category", "tags", "source_link", "purchase_date", "price_minor", "price_currency", "search_vector", "created_at", "updated_at",
+	},
+	"builds": {
+		"id", "user_id", "name", "description", "character", "series", "status", "priority",
+		"currency", "budget_minor", "spent_minor", "start_date", "target_date", "completed_date", "tags", "notes",
+		"search_vector", "created_at", "updated_at",
+	},
+	"build_pieces": {
+		"id", "build_id", "piece_id", "role", "quantity", "sort_order", "created_at", "updated_at",
+	},
+	"build_status_history": {
+		"id", "build_id", "from_status", "to_status", "note", "changed_at",
+	},
+	"build_tasks": {
+		"id", "build_id", "kind", "title", "due_date", "done", "done_at", "sort_order", "piece_id", "created_at", "updated_at",
+	},
+	"build_expenses": {
+		"id", "build_id", "source", "build_piece_id", "amount_minor", "currency", "description", "vendor", "category",
+		"spent_on", "piece_id", "notes", "created_at", "updated_at",
+	},
+	"wear_logs": {
+		"id", "user_id", "piece_id", "build_id", "worn_on", "location", "event_name",
+		"duration_minutes", "notes", "created_at", "updated_at",
+	},
+	"coords": {
+		"id", "user_id", "name", "description", "build_id", "canvas_width", "canvas_height",
+		"background_color", "search_vector", "created_at", "updated_at",
+	},
+	"coord_layers": {
+		"id", "coord_id", "piece_id", "z_index", "position_x", "position_y", "scale", "rotation",
+		"created_at", "updated_at",
+	},
+	"wishlist_items": {
+		"id", "user_id", "name", "description", "category", "tags", "source_link", "image_url",
+		"target_price_minor", "target_price_currency", "current_price_minor", "current_price_currency",
+		"priority", "build_id", "status", "acquired_piece_id",
+		"acquired_at", "search_vector", "created_at", "updated_at",
+	},
+	"wishlist_price_history": {
+		"id", "wishlist_item_id", "price_minor", "price_currency", "recorded_at",
+	},
+	"conventions": {
+		"id", "user_id", "name", "venue", "city", "start_date", "end_date", "timezone", "hotel_name",
+		"hotel_address", "hotel_confirmation", "website", "notes", "search_vector", "created_at", "updated_at",
+	},
+	"convention_schedule_entries": {
+		"id", "convention_id", "entry_type", "day", "start_time", "end_time", "build_id", "coord_id",
+		"title", "location", "notes", "created_at", "updated_at",
+	},
+	"convention_packing_items": {
+		"convention_id", "piece_id", "packed", "packed_at", "created_at", "updated_at",
+	},
+	"categories": {
+		"id", "user_id", "name", "color", "parent_id", "created_at", "updated_at",
+	},
+	"tags": {
+		"id", "user_id", "name", "color", "created_at", "updated_at",
+	},
+	"jobs": {
+		"id", "user_id", "kind", "status", "payload", "result", "progress", "attempts", "max_attempts",
+		"run_at", "locked_at", "last_error", "completed_at", "created_at", "updated_at",
+	},
+	"currencies": {
+		"code", "exponent",
+	},
+	"exchange_rates": {
+		"base", "quote", "effective_on", "rate", "loaded_at",
+	},
+}
+
+// VerifySchema checks that every column the repositories expect exists
+func VerifySchema(db *sql.DB) error {
+	query := `
+		SELECT table_name, column_name
+		FROM information_schema.columns
+		WHERE table_schema = current_schema()`
+
+	rows, err := db.Query(query)
+	if err != nil {
+		return fmt.Errorf("failed to read schema: %w", err)
+	}
+	defer rows.Close()
+
+	existing := make(map[string]bool)
+	for rows.Next() {
+		var table, column string
+		if err := rows.Scan(&table, &column); err != nil {
+			return fmt.Errorf("failed to scan schema column: %w", err)
+		}
+		existing[table+"."+column] = true
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read schema: %w", err)
+	}
+
+	var missing []string
+	for table, columns := range requiredColumns {
+		for _, column := range columns {
+			if !existing[table+"."+column] {
+				missing = append(missing, table+"."+column)
+			}
+		}
+	}
+
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return fmt.Errorf("database schema is missing columns: %s", strings.Join(missing, ", "))
+	}
+
+	return nil
+}
@@ -0,0 +1,141 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"kyarafit-backend/models"
+)
+
+// searchSource describes how to search one kind of entity
+type searchSource struct {
+	table string
+	// document is the text headlines are cut from
+	document string
+	// fuzzy are the columns matched by trigram similarity, to catch typos
+	fuzzy []string
+}
+
+// searchSources are the entities Search covers, by result type
+var searchSources = map[string]searchSource{
+	"pieces": {
+		table:    "pieces",
+		document: "concat_ws(' ', name, category, tags_to_text(tags), description)",
+		fuzzy:    []string{"name"},
+	},
+	"builds": {
+		table:    "builds",
+		document: "concat_ws(' ', name, character, series, tags_to_text(tags), description, notes)",
+		fuzzy:    []string{"name", "character", "series"},
+	},
+	"coords": {
+		table:    "coords",
+		document: "concat_ws(' ', name, description)",
+		fuzzy:    []string{"name"},
+	},
+	"wishlist": {
+		table:    "wishlist_items",
+		document: "concat_ws(' ', name, category, tags_to_text(tags), description)",
+		fuzzy:    []string{"name"},
+	},
+	"conventions": {
+		table:    "conventions",
+		document: "concat_ws(' ', name, venue, city, hotel_name, notes)",
+		fuzzy:    []string{"name"},
+	},
+}
+
+// SearchTypes lists the result types Search accepts
+func SearchTypes() []string {
+	return []string{"pieces", "builds", "coords", "wishlist", "conventions"}
+}
+
+type SearchRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewSearchRepository(db *pgxpool.Pool) *SearchRepository {
+	return &SearchRepository{db: db}
+}
+
+// headlineOptions wraps matched words in <mark> and keeps excerpts short
+const headlineOptions = "StartSel=<mark>, StopSel=</mark>, MaxWords=20, MinWords=8, MaxFragments=2, FragmentDelimiter=\" … \""
+
+// escapeHTML returns a SQL expression HTML-escaping text. Documents are
+// escaped before ts_headline so <mark> is the only markup in a headline; the
+// parser reads entities as single tokens, so an excerpt never splits one.
+func escapeHTML(text string) string {
+	return `replace(replace(replace(replace(replace(` + text +
+		`, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'), '"', '&quot;'), '''', '&#39;')`
+}
+
+// Search finds a user's entities of the given types matching a query. Words
+// match anywhere in an entity's text through full-text search, stemmed so
+// plurals and other forms match, and names also match through trigram
+// similarity so misspellings are found. Each type returns at most limit hits,
+// best first.
+func (r *SearchRepository) Search(userID uuid.UUID, query string, types []string, limit int) (map[string][]models.SearchHit, error) {
+	ctx := context.Background()
+
+	parts := make([]string, 0, len(types))
+	for _, kind := range types {
+		source, ok := searchSources[kind]
+		if !ok {
+			return nil, fmt.Errorf("unknown search type %q", kind)
+		}
+
+		similarity := make([]string, len(source.fuzzy))
+		fuzzyMatch := make([]string, len(source.fuzzy))
+		for i, column := range source.fuzzy {
+			similarity[i] = "COALESCE(word_similarity($2, " + column + "), 0)"
+			fuzzyMatch[i] = "$2 <% " + column
+		}
+
+		// Hits are ranked and limited before their headlines are built, since
+		// ts_headline is the expensive part
+		parts = append(parts, `
+			(SELECT '`+kind+`' AS type, hit.id, hit.name, ts_headline('english', `+escapeHTML("hit.document")+`, q, $4), hit.rank
+			FROM (
+				SELECT id, name, `+source.document+` AS document,
+					GREATEST(ts_rank_cd(search_vector, q), `+strings.Join(similarity, ", ")+`) AS rank
+				FROM `+source.table+`, websearch_to_tsquery('english', $2) q
+				WHERE user_id = $1 AND (search_vector @@ q OR `+strings.Join(fuzzyMatch, " OR ")+`)
+				ORDER BY rank DESC, id
+				LIMIT $3
+			) hit, websearch_to_tsquery('english', $2) q)`)
+	}
+
+	results := make(map[string][]models.SearchHit, len(types))
+	for _, kind := range types {
+		results[kind] = []models.SearchHit{}
+	}
+	if len(parts) == 0 {
+		return results, nil
+	}
+
+	sql := `SELECT * FROM (` + strings.Join(parts, "\n\t\t\tUNION ALL") + `
+		) hits
+		ORDER BY rank DESC, id`
+
+	rows, err := r.db.Query(ctx, sql, userID, query, limit, headlineOptions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var hit models.SearchHit
+		if err := rows.Scan(&hit.Type, &hit.ID, &hit.Title, &hit.Headline, &hit.Rank); err != nil {
+			return nil, fmt.Errorf("failed to scan search hit: %w", err)
+		}
+		results[hit.Type] = append(results[hit.Type], hit)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to search: %w", err)
+	}
+
+	return results, nil
+}
@@ -0,0 +1,32 @@
+package database
+
+import "time"
+
+// trendMonths is how many months, including the current one, stats break
+// trends down by
+const trendMonths = 12
+
+// monthFormat is how trend months are keyed and reported
+const monthFormat = "2006-01"
+
+// trendMonthKeys returns the first day of a trend's earliest month, and the
+// keys of each month of the trend, oldest first
+func trendMonthKeys() (time.Time, []string) {
+	now := time.Now()
+	thisMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	since := thisMonth.AddDate(0, 1-trendMonths, 0)
+	return since, monthKeys(since, thisMonth)
+}
+
+// monthKeys returns the keys of each month from the month of from to the
+// month of to, oldest first
+func monthKeys(from, to time.Time) []string {
+	first := time.Date(from.Year(), from.Month(), 1, 0, 0, 0, 0, time.UTC)
+	last := time.Date(to.Year(), to.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	var keys []string
+	for m := first; !m.After(last); m = m.AddDate(0, 1, 0) {
+		keys = append(keys, m.Format(monthFormat))
+	}
+	return keys
+}
@@ -0,0 +1,281 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"kyarafit-backend/models"
+)
+
+var (
+	// ErrTagNotFound is returned when a tag doesn't exist
+	ErrTagNotFound = errors.New("tag not found")
+	// ErrTagExists is returned when a user already has a tag by a name, in any casing
+	ErrTagExists = errors.New("a tag with this name already exists")
+)
+
+type TagRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewTagRepository(db *pgxpool.Pool) *TagRepository {
+	return &TagRepository{db: db}
+}
+
+// tagColumns selects a tag with how many of its user's pieces and builds have it
+const tagColumns = `t.id, t.user_id, t.name, t.color, t.created_at, t.updated_at,
+			(SELECT COUNT(*) FROM pieces p WHERE p.user_id = t.user_id AND t.name = ANY(p.tags)) AS piece_count,
+			(SELECT COUNT(*) FROM builds b WHERE b.user_id = t.user_id AND t.name = ANY(b.tags)) AS build_count`
+
+func scanTag(row pgx.Row, tag *models.Tag) error {
+	return row.Scan(
+		&tag.ID,
+		&tag.UserID,
+		&tag.Name,
+		&tag.Color,
+		&tag.CreatedAt,
+		&tag.UpdatedAt,
+		&tag.PieceCount,
+		&tag.BuildCount,
+	)
+}
+
+// taggedTables are the tables whose tags come from the tag vocabulary
+var taggedTables = []string{"pieces", "builds", "wishlist_items"}
+
+// CreateTag adds a tag to a user's vocabulary
+func (r *TagRepository) CreateTag(tag *models.Tag) error {
+	ctx := context.Background()
+	query := `
+		INSERT INTO tags (id, user_id, name, color, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at, updated_at`
+
+	err := r.db.QueryRow(
+		ctx,
+		query,
+		tag.ID,
+		tag.UserID,
+		tag.Name,
+		tag.Color,
+		tag.CreatedAt,
+		tag.UpdatedAt,
+	).Scan(&tag.ID, &tag.CreatedAt, &tag.UpdatedAt)
+
+	if err != nil {
+		if isUniqueViolation(err) {
+			return ErrTagExists
+		}
+		return fmt.Errorf("failed to create tag: %w", err)
+	}
+
+	return nil
+}
+
+// GetTagByID retrieves a tag by its ID
+func (r *TagRepository) GetTagByID(id uuid.UUID) (*models.Tag, error) {
+	ctx := context.Background()
+	query := `SELECT ` + tagColumns + ` FROM tags t WHERE t.id = $1`
+
+	tag := &models.Tag{}
+	if err := scanTag(r.db.QueryRow(ctx, query, id), tag); err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrTagNotFound
+		}
+		return nil, fmt.Errorf("failed to get tag: %w", err)
+	}
+
+	return tag, nil
+}
+
+// queryTags runs a query selecting tagColumns
+func (r *TagRepository) queryTags(query string, args ...interface{}) ([]*models.Tag, error) {
+	ctx := context.Background()
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tags: %w", err)
+	}
+	defer rows.Close()
+
+	var tags []*models.Tag
+	for rows.Next() {
+		tag := &models.Tag{}
+		if err := scanTag(rows, tag); err != nil {
+			return nil, fmt.Errorf("failed to scan tag: %w", err)
+		}
+		tags = append(tags, tag)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get tags: %w", err)
+	}
+
+	return tags, nil
+}
+
+// ListTags retrieves a user's tags by name
+func (r *TagRepository) ListTags(userID uuid.UUID) ([]*models.Tag, error) {
+	return r.queryTags(`SELECT `+tagColumns+` FROM tags t WHERE t.user_id = $1 ORDER BY lower(t.name)`, userID)
+}
+
+// escapeLike escapes the wildcards of a LIKE pattern
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// AutocompleteTags suggests a user's tags for what they have typed so far.
+// Tags starting with it come first, most used first, followed by tags with
+// similar names to catch typos.
+func (r *TagRepository) AutocompleteTags(userID uuid.UUID, prefix string, limit int) ([]*models.Tag, error) {
+	query := `
+		SELECT id, user_id, name, color, created_at, updated_at, piece_count, build_count
+		FROM (
+			SELECT ` + tagColumns + `,
+				lower(t.name) LIKE $2 AS prefixed,
+				word_similarity($3, t.name) AS similarity
+			FROM tags t
+			WHERE t.user_id = $1 AND (lower(t.name) LIKE $2 OR $3 <% t.name)
+		) matches
+		ORDER BY prefixed DESC, piece_count + build_count DESC, similarity DESC, lower(name)
+		LIMIT $4`
+
+	tags, err := r.queryTags(query, userID, escapeLike(strings.ToLower(prefix))+"%", prefix, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to autocomplete tags: %w", err)
+	}
+
+	return tags, nil
+}
+
+// UpdateTag saves a tag. If it was renamed from previousName, every piece,
+// build and wishlist item with it is retagged in the same transaction. It
+// returns how many were retagged.
+func (r *TagRepository) UpdateTag(tag *models.Tag, previousName string) (int64, error) {
+	ctx := context.Background()
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// The tag is renamed before the items with it, so the vocabulary
+	// triggers find the new name instead of registering it as another tag
+	query := `
+		UPDATE tags
+		SET name = $3, color = $4, updated_at = $5
+		WHERE id = $1 AND user_id = $2
+		RETURNING updated_at`
+
+	err = tx.QueryRow(ctx, query, tag.ID, tag.UserID, tag.Name, tag.Color, tag.UpdatedAt).Scan(&tag.UpdatedAt)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return 0, ErrTagNotFound
+		}
+		if isUniqueViolation(err) {
+			return 0, ErrTagExists
+		}
+		return 0, fmt.Errorf("failed to update tag: %w", err)
+	}
+
+	var retagged int64
+	if tag.Name != previousName {
+		if retagged, err = replaceTag(ctx, tx, tag.UserID, previousName, &tag.Name); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return retagged, nil
+}
+
+// DeleteTag deletes a tag, removing it from every piece, build and wishlist item
+func (r *TagRepository) DeleteTag(tag *models.Tag) error {
+	ctx := context.Background()
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := replaceTag(ctx, tx, tag.UserID, tag.Name, nil); err != nil {
+		return err
+	}
+
+	result, err := tx.Exec(ctx, `DELETE FROM tags WHERE id = $1 AND user_id = $2`, tag.ID, tag.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to delete tag: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrTagNotFound
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// MergeTag retags every piece, build and wishlist item with source with
+// target instead, then deletes source, in one transaction. It returns how
+// many were retagged.
+func (r *TagRepository) MergeTag(source, target *models.Tag) (int64, error) {
+	ctx := context.Background()
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	retagged, err := replaceTag(ctx, tx, source.UserID, source.Name, &target.Name)
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := tx.Exec(ctx, `DELETE FROM tags WHERE id = $1 AND user_id = $2`, source.ID, source.UserID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete merged tag: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return 0, ErrTagNotFound
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return retagged, nil
+}
+
+// replaceTag replaces a tag on every piece, build and wishlist item of a
+// user, or removes it when replacement is nil. Items that end up with the replacement twice are
+// deduplicated by the vocabulary triggers. It returns how many were changed.
+func replaceTag(ctx context.Context, tx pgx.Tx, userID uuid.UUID, name string, replacement *string) (int64, error) {
+	var changed int64
+	for _, table := range taggedTables {
+		query := `UPDATE ` + table + ` SET tags = array_remove(tags, $2) WHERE user_id = $1 AND $2 = ANY(tags)`
+		args := []interface{}{userID, name}
+		if replacement != nil {
+			query = `UPDATE ` + table + ` SET tags = array_replace(tags, $2, $3) WHERE user_id = $1 AND $2 = ANY(tags)`
+			args = append(args, *replacement)
+		}
+
+		result, err := tx.Exec(ctx, query, args...)
+		if err != nil {
+			return 0, fmt.Errorf("failed to retag %s: %w", table, err)
+		}
+		changed += result.RowsAffected()
+	}
+	return changed, nil
+}
@@ -0,0 +1,101 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"kyarafit-backend/models"
+)
+
+// ErrEmailTaken is returned when registering an email that already has an account
+var ErrEmailTaken = errors.New("email is already registered")
+
+type UserRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewUserRepository(db *pgxpool.Pool) *UserRepository {
+	return &UserRepository{db: db}
+}
+
+// CreateUser creates a new user in the database
+func (r *UserRepository) CreateUser(user *models.User) error {
+	ctx := context.Background()
+	query := `
+		INSERT INTO users (id, email, username, display_name, avatar_url, password_hash, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, created_at, updated_at`
+
+	err := r.db.QueryRow(
+		ctx,
+		query,
+		user.ID,
+		user.Email,
+		user.Username,
+		user.DisplayName,
+		user.AvatarURL,
+		user.PasswordHash,
+		user.CreatedAt,
+		user.UpdatedAt,
+	).Scan(&user.ID, &user.CreatedAt, &user.UpdatedAt)
+
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return ErrEmailTaken
+		}
+		return fmt.Errorf("failed to create user: %w", err)
+	}
+
+	return nil
+}
+
+// GetUserByID retrieves a user by its ID
+func (r *UserRepository) GetUserByID(id uuid.UUID) (*models.User, error) {
+	query := `
+		SELECT id, email, username, display_name, avatar_url, password_hash, created_at, updated_at
+		FROM users
+		WHERE id = $1`
+
+	return r.getUser(query, id)
+}
+
+// GetUserByEmail retrieves a user by email. Emails are compared case-insensitively.
+func (r *UserRepository) GetUserByEmail(email string) (*models.User, error) {
+	query := `
+		SELECT id, email, username, display_name, avatar_url, password_hash, created_at, updated_at
+		FROM users
+		WHERE email = $1`
+
+	return r.getUser(query, email)
+}
+
+func (r *UserRepository) getUser(query string, arg interface{}) (*models.User, error) {
+	ctx := context.Background()
+
+	user := &models.User{}
+	err := r.db.QueryRow(ctx, query, arg).Scan(
+		&user.ID,
+		&user.Email,
+		&user.Username,
+		&user.DisplayName,
+		&user.AvatarURL,
+		&user.PasswordHash,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+	)
+
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, fmt.Errorf("user not found")
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	return user, nil
+}
@@ -0,0 +1,270 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"kyarafit-backend/models"
+)
+
+type WearLogRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewWearLogRepository(db *pgxpool.Pool) *WearLogRepository {
+	return &WearLogRepository{db: db}
+}
+
+// CreateWearLog creates a new wear log in the database
+func (r *WearLogRepository) CreateWearLog(wearLog *models.WearLog) error {
+	ctx := context.Background()
+	query := `
+		INSERT INTO wear_logs (id, user_id, piece_id, build_id, worn_on, location, event_name, duration_minutes, notes, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		RETURNING id, created_at, updated_at`
+
+	err := r.db.QueryRow(
+		ctx,
+		query,
+		wearLog.ID,
+		wearLog.UserID,
+		wearLog.PieceID,
+		wearLog.BuildID,
+		wearLog.WornOn,
+		wearLog.Location,
+		wearLog.EventName,
+		wearLog.DurationMinutes,
+		wearLog.Notes,
+		wearLog.CreatedAt,
+		wearLog.UpdatedAt,
+	).Scan(&wearLog.ID, &wearLog.CreatedAt, &wearLog.UpdatedAt)
+
+	if err != nil {
+		return fmt.Errorf("failed to create wear log: %w", err)
+	}
+
+	return nil
+}
+
+// GetWearLogByID retrieves a wear log by its ID
+func (r *WearLogRepository) GetWearLogByID(id uuid.UUID) (*models.WearLog, error) {
+	ctx := context.Background()
+	query := `
+		SELECT id, user_id, piece_id, build_id, worn_on, location, event_name, duration_minutes, notes, created_at, updated_at
+		FROM wear_logs
+		WHERE id = $1`
+
+	wearLog := &models.WearLog{}
+	err := r.db.QueryRow(ctx, query, id).Scan(
+		&wearLog.ID,
+		&wearLog.UserID,
+		&wearLog.PieceID,
+		&wearLog.BuildID,
+		&wearLog.WornOn,
+		&wearLog.Location,
+		&wearLog.EventName,
+		&wearLog.DurationMinutes,
+		&wearLog.Notes,
+		&wearLog.CreatedAt,
+		&wearLog.UpdatedAt,
+	)
+
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, fmt.Errorf("wear log not found")
+		}
+		return nil, fmt.Errorf("failed to get wear log: %w", err)
+	}
+
+	return wearLog, nil
+}
+
+// GetWearLogsByUserID retrieves all wear logs for a specific user, most recent first
+func (r *WearLogRepository) GetWearLogsByUserID(userID uuid.UUID, limit, offset int) ([]*models.WearLog, error) {
+	query := `
+		SELECT id, user_id, piece_id, build_id, worn_on, location, event_name, duration_minutes, notes, created_at, updated_at
+		FROM wear_logs
+		WHERE user_id = $1
+		ORDER BY worn_on DESC, created_at DESC
+		LIMIT $2 OFFSET $3`
+
+	return r.queryWearLogs(query, userID, limit, offset)
+}
+
+// GetWearLogsByPieceID retrieves the wear history of a piece. This includes logs
+// recorded against any build the piece is linked to.
+func (r *WearLogRepository) GetWearLogsByPieceID(userID, pieceID uuid.UUID, limit, offset int) ([]*models.WearLog, error) {
+	query := `
+		SELECT id, user_id, piece_id, build_id, worn_on, location, event_name, duration_minutes, notes, created_at, updated_at
+		FROM wear_logs
+		WHERE user_id = $1 AND (
+			piece_id = $2 OR
+			build_id IN (SELECT build_id FROM build_pieces WHERE piece_id = $2)
+		)
+		ORDER BY worn_on DESC, created_at DESC
+		LIMIT $3 OFFSET $4`
+
+	return r.queryWearLogs(query, userID, pieceID, limit, offset)
+}
+
+// GetWearLogsByBuildID retrieves the wear history of a build
+func (r *WearLogRepository) GetWearLogsByBuildID(userID, buildID uuid.UUID, limit, offset int) ([]*models.WearLog, error) {
+	query := `
+		SELECT id, user_id, piece_id, build_id, worn_on, location, event_name, duration_minutes, notes, created_at, updated_at
+		FROM wear_logs
+		WHERE user_id = $1 AND build_id = $2
+		ORDER BY worn_on DESC, created_at DESC
+		LIMIT $3 OFFSET $4`
+
+	return r.queryWearLogs(query, userID, buildID, limit, offset)
+}
+
+func (r *WearLogRepository) queryWearLogs(query string, args ...interface{}) ([]*models.WearLog, error) {
+	ctx := context.Background()
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get wear logs: %w", err)
+	}
+	defer rows.Close()
+
+	var logs []*models.WearLog
+	for rows.Next() {
+		wearLog := &models.WearLog{}
+		err := rows.Scan(
+			&wearLog.ID,
+			&wearLog.UserID,
+			&wearLog.PieceID,
+			&wearLog.BuildID,
+			&wearLog.WornOn,
+			&wearLog.Location,
+			&wearLog.EventName,
+			&wearLog.DurationMinutes,
+			&wearLog.Notes,
+			&wearLog.CreatedAt,
+			&wearLog.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan wear log: %w", err)
+		}
+		logs = append(logs, wearLog)
+	}
+
+	return logs, nil
+}
+
+// UpdateWearLog updates an existing wear log
+func (r *WearLogRepository) UpdateWearLog(wearLog *models.WearLog) error {
+	ctx := context.Background()
+	query := `
+		UPDATE wear_logs
+		SET piece_id = $2, build_id = $3, worn_on = $4, location = $5, event_name = $6, duration_minutes = $7, notes = $8, updated_at = $9
+		WHERE id = $1 AND user_id = $10
+		RETURNING updated_at`
+
+	err := r.db.QueryRow(
+		ctx,
+		query,
+		wearLog.ID,
+		wearLog.PieceID,
+		wearLog.BuildID,
+		wearLog.WornOn,
+		wearLog.Location,
+		wearLog.EventName,
+		wearLog.DurationMinutes,
+		wearLog.Notes,
+		wearLog.UpdatedAt,
+		wearLog.UserID,
+	).Scan(&wearLog.UpdatedAt)
+
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return fmt.Errorf("wear log not found or access denied")
+		}
+		return fmt.Errorf("failed to update wear log: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteWearLog deletes a wear log by ID
+func (r *WearLogRepository) DeleteWearLog(id uuid.UUID, userID uuid.UUID) error {
+	ctx := context.Background()
+	query := `DELETE FROM wear_logs WHERE id = $1 AND user_id = $2`
+
+	result, err := r.db.Exec(ctx, query, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete wear log: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("wear log not found or access denied")
+	}
+
+	return nil
+}
+
+// GetWearLogCount returns the total count of wear logs for a user
+func (r *WearLogRepository) GetWearLogCount(userID uuid.UUID) (int, error) {
+	ctx := context.Background()
+	query := `SELECT COUNT(*) FROM wear_logs WHERE user_id = $1`
+
+	var count int
+	err := r.db.QueryRow(ctx, query, userID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get wear log count: %w", err)
+	}
+
+	return count, nil
+}
+
+// GetPieceWearSummaries returns times worn and last worn for each piece. Wearing
+// a build counts as wearing every piece linked to it.
+func (r *WearLogRepository) GetPieceWearSummaries(pieceIDs []uuid.UUID) (map[uuid.UUID]models.WearSummary, error) {
+	query := `
+		SELECT p.piece_id, COUNT(DISTINCT wl.id), MAX(wl.worn_on)
+		FROM unnest($1::uuid[]) AS p(piece_id)
+		JOIN wear_logs wl ON wl.piece_id = p.piece_id
+			OR wl.build_id IN (SELECT bp.build_id FROM build_pieces bp WHERE bp.piece_id = p.piece_id)
+		GROUP BY p.piece_id`
+
+	return r.queryWearSummaries(query, pieceIDs)
+}
+
+// GetBuildWearSummaries returns times worn and last worn for each build
+func (r *WearLogRepository) GetBuildWearSummaries(buildIDs []uuid.UUID) (map[uuid.UUID]models.WearSummary, error) {
+	query := `
+		SELECT build_id, COUNT(*), MAX(worn_on)
+		FROM wear_logs
+		WHERE build_id = ANY($1::uuid[])
+		GROUP BY build_id`
+
+	return r.queryWearSummaries(query, buildIDs)
+}
+
+func (r *WearLogRepository) queryWearSummaries(query string, ids []uuid.UUID) (map[uuid.UUID]models.WearSummary, error) {
+	summaries := make(map[uuid.UUID]models.WearSummary, len(ids))
+	if len(ids) == 0 {
+		return summaries, nil
+	}
+
+	ctx := context.Background()
+	rows, err := r.db.Query(ctx, query, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get wear summaries: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id uuid.UUID
+		var summary models.WearSummary
+		if err := rows.Scan(&id, &summary.TimesWorn, &summary.LastWorn); err != nil {
+			return nil, fmt.Errorf("failed to scan wear summary: %w", err)
+		}
+		summaries[id] = summary
+	}
+
+	return summaries, nil
+}
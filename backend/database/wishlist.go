@@ -0,0 +1,374 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"kyarafit-backend/models"
+)
+
+// ErrWishlistItemAcquired is returned when acquiring an item that was already acquired
+var ErrWishlistItemAcquired = errors.New("wishlist item has already been acquired")
+
+type WishlistRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewWishlistRepository(db *pgxpool.Pool) *WishlistRepository {
+	return &WishlistRepository{db: db}
+}
+
+// wishlistBelowTarget is whether an item's current price, converted into the
+// target price's currency at today's rate, has reached the target. It's false
+// without a rate between the two.
+const wishlistBelowTarget = `COALESCE(convert_minor(current_price_minor, current_price_currency, target_price_currency, CURRENT_DATE) <= target_price_minor, false)`
+
+const wishlistItemColumns = `id, user_id, name, description, category, tags, source_link, image_url, target_price_minor, target_price_currency,
+			current_price_minor, current_price_currency, priority, build_id, status, acquired_piece_id, acquired_at, ` + wishlistBelowTarget + `,
+			created_at, updated_at`
+
+func scanWishlistItem(row pgx.Row, item *models.WishlistItem) error {
+	var target, current nullMoney
+	err := row.Scan(
+		&item.ID,
+		&item.UserID,
+		&item.Name,
+		&item.Description,
+		&item.Category,
+		&item.Tags,
+		&item.SourceLink,
+		&item.ImageURL,
+		&target.minorUnits,
+		&target.currency,
+		&current.minorUnits,
+		&current.currency,
+		&item.Priority,
+		&item.BuildID,
+		&item.Status,
+		&item.AcquiredPieceID,
+		&item.AcquiredAt,
+		&item.BelowTarget,
+		&item.CreatedAt,
+		&item.UpdatedAt,
+	)
+	item.TargetPrice = target.money()
+	item.CurrentPrice = current.money()
+	return err
+}
+
+// CreateWishlistItem creates a new wishlist item. Its current price, if any,
+// becomes the first entry of its price history.
+func (r *WishlistRepository) CreateWishlistItem(item *models.WishlistItem) error {
+	ctx := context.Background()
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		INSERT INTO wishlist_items (id, user_id, name, description, category, tags, source_link, image_url, target_price_minor, target_price_currency,
+			current_price_minor, current_price_currency, priority, build_id, status, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)
+		RETURNING id, tags, ` + wishlistBelowTarget + `, created_at, updated_at`
+
+	// tags are read back in the vocabulary spelling the triggers store, and
+	// below_target from today's exchange rates
+	err = tx.QueryRow(
+		ctx,
+		query,
+		item.ID,
+		item.UserID,
+		item.Name,
+		item.Description,
+		item.Category,
+		item.Tags,
+		item.SourceLink,
+		item.ImageURL,
+		minorUnits(item.TargetPrice),
+		currencyOf(item.TargetPrice),
+		minorUnits(item.CurrentPrice),
+		currencyOf(item.CurrentPrice),
+		item.Priority,
+		item.BuildID,
+		item.Status,
+		item.CreatedAt,
+		item.UpdatedAt,
+	).Scan(&item.ID, &item.Tags, &item.BelowTarget, &item.CreatedAt, &item.UpdatedAt)
+
+	if err != nil {
+		return fmt.Errorf("failed to create wishlist item: %w", err)
+	}
+
+	if item.CurrentPrice != nil {
+		if err := recordWishlistPrice(ctx, tx, item.ID, *item.CurrentPrice); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// GetWishlistItemByID retrieves a wishlist item by its ID
+func (r *WishlistRepository) GetWishlistItemByID(id uuid.UUID) (*models.WishlistItem, error) {
+	ctx := context.Background()
+	query := `
+		SELECT ` + wishlistItemColumns + `
+		FROM wishlist_items
+		WHERE id = $1`
+
+	item := &models.WishlistItem{}
+	err := scanWishlistItem(r.db.QueryRow(ctx, query, id), item)
+
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, fmt.Errorf("wishlist item not found")
+		}
+		return nil, fmt.Errorf("failed to get wishlist item: %w", err)
+	}
+
+	return item, nil
+}
+
+// GetWishlistItemsByUserID retrieves a user's wishlist items with the given
+// status, highest priority first
+func (r *WishlistRepository) GetWishlistItemsByUserID(userID uuid.UUID, status models.WishlistStatus, limit, offset int) ([]*models.WishlistItem, error) {
+	ctx := context.Background()
+	query := `
+		SELECT ` + wishlistItemColumns + `
+		FROM wishlist_items
+		WHERE user_id = $1 AND status = $2
+		ORDER BY priority DESC, created_at DESC
+		LIMIT $3 OFFSET $4`
+
+	rows, err := r.db.Query(ctx, query, userID, status, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get wishlist items: %w", err)
+	}
+	defer rows.Close()
+
+	var items []*models.WishlistItem
+	for rows.Next() {
+		item := &models.WishlistItem{}
+		if err := scanWishlistItem(rows, item); err != nil {
+			return nil, fmt.Errorf("failed to scan wishlist item: %w", err)
+		}
+		items = append(items, item)
+	}
+
+	return items, nil
+}
+
+// GetWishlistItemCount returns the number of a user's wishlist items with the given status
+func (r *WishlistRepository) GetWishlistItemCount(userID uuid.UUID, status models.WishlistStatus) (int, error) {
+	ctx := context.Background()
+	query := `SELECT COUNT(*) FROM wishlist_items WHERE user_id = $1 AND status = $2`
+
+	var count int
+	err := r.db.QueryRow(ctx, query, userID, status).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get wishlist item count: %w", err)
+	}
+
+	return count, nil
+}
+
+// GetPriceHistory retrieves every recorded price of a wishlist item, oldest first
+func (r *WishlistRepository) GetPriceHistory(itemID uuid.UUID) ([]models.WishlistPrice, error) {
+	ctx := context.Background()
+	query := `
+		SELECT id, wishlist_item_id, price_minor, price_currency, recorded_at
+		FROM wishlist_price_history
+		WHERE wishlist_item_id = $1
+		ORDER BY recorded_at ASC`
+
+	rows, err := r.db.Query(ctx, query, itemID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get price history: %w", err)
+	}
+	defer rows.Close()
+
+	history := []models.WishlistPrice{}
+	for rows.Next() {
+		var price models.WishlistPrice
+		if err := rows.Scan(&price.ID, &price.WishlistItemID, &price.Price.MinorUnits, &price.Price.Currency, &price.RecordedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan price history: %w", err)
+		}
+		history = append(history, price)
+	}
+
+	return history, nil
+}
+
+// UpdateWishlistItem updates an existing wishlist item. When priceChanged is
+// set, the new current price is appended to the price history.
+func (r *WishlistRepository) UpdateWishlistItem(item *models.WishlistItem, priceChanged bool) error {
+	ctx := context.Background()
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		UPDATE wishlist_items
+		SET name = $2, description = $3, category = $4, tags = $5, source_link = $6, image_url = $7,
+			target_price_minor = $8, target_price_currency = $9, current_price_minor = $10, current_price_currency = $11,
+			priority = $12, build_id = $13, updated_at = $14
+		WHERE id = $1 AND user_id = $15
+		RETURNING tags, ` + wishlistBelowTarget + `, updated_at`
+
+	err = tx.QueryRow(
+		ctx,
+		query,
+		item.ID,
+		item.Name,
+		item.Description,
+		item.Category,
+		item.Tags,
+		item.SourceLink,
+		item.ImageURL,
+		minorUnits(item.TargetPrice),
+		currencyOf(item.TargetPrice),
+		minorUnits(item.CurrentPrice),
+		currencyOf(item.CurrentPrice),
+		item.Priority,
+		item.BuildID,
+		item.UpdatedAt,
+		item.UserID,
+	).Scan(&item.Tags, &item.BelowTarget, &item.UpdatedAt)
+
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return fmt.Errorf("wishlist item not found or access denied")
+		}
+		return fmt.Errorf("failed to update wishlist item: %w", err)
+	}
+
+	if priceChanged && item.CurrentPrice != nil {
+		if err := recordWishlistPrice(ctx, tx, item.ID, *item.CurrentPrice); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// AcquireWishlistItem turns a wishlist item into a closet piece in one
+// transaction. The piece is linked to the item's build, if it has one, and
+// the item is then archived as acquired, or deleted when remove is set.
+func (r *WishlistRepository) AcquireWishlistItem(itemID, userID uuid.UUID, price *models.Money, purchaseDate *time.Time, remove bool) (*models.Piece, error) {
+	ctx := context.Background()
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		SELECT ` + wishlistItemColumns + `
+		FROM wishlist_items
+		WHERE id = $1 AND user_id = $2
+		FOR UPDATE`
+
+	item := &models.WishlistItem{}
+	if err := scanWishlistItem(tx.QueryRow(ctx, query, itemID, userID), item); err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, fmt.Errorf("wishlist item not found or access denied")
+		}
+		return nil, fmt.Errorf("failed to get wishlist item: %w", err)
+	}
+	if item.Status == models.WishlistStatusAcquired {
+		return nil, ErrWishlistItemAcquired
+	}
+
+	piece := item.ToPiece(price, purchaseDate)
+	if err := insertPiece(ctx, tx, piece); err != nil {
+		return nil, err
+	}
+
+	if item.BuildID != nil {
+		linkQuery := `
+			INSERT INTO build_pieces (id, build_id, piece_id, sort_order)
+			VALUES ($1, $2, $3, (SELECT COALESCE(MAX(sort_order) + 1, 0) FROM build_pieces WHERE build_id = $2))`
+		if _, err := tx.Exec(ctx, linkQuery, uuid.New(), *item.BuildID, piece.ID); err != nil {
+			return nil, fmt.Errorf("failed to add piece to build: %w", err)
+		}
+	}
+
+	if remove {
+		if _, err := tx.Exec(ctx, `DELETE FROM wishlist_items WHERE id = $1`, item.ID); err != nil {
+			return nil, fmt.Errorf("failed to delete wishlist item: %w", err)
+		}
+	} else {
+		// The price paid becomes the current price, in whatever currency it
+		// was paid in
+		paid := piece.Price
+
+		archiveQuery := `
+			UPDATE wishlist_items
+			SET status = $2, acquired_piece_id = $3, acquired_at = NOW(),
+				current_price_minor = COALESCE($4, current_price_minor), current_price_currency = COALESCE($5, current_price_currency),
+				updated_at = NOW()
+			WHERE id = $1`
+		if _, err := tx.Exec(ctx, archiveQuery, item.ID, models.WishlistStatusAcquired, piece.ID, minorUnits(paid), currencyOf(paid)); err != nil {
+			return nil, fmt.Errorf("failed to archive wishlist item: %w", err)
+		}
+		if paid != nil && (item.CurrentPrice == nil || *item.CurrentPrice != *paid) {
+			if err := recordWishlistPrice(ctx, tx, item.ID, *paid); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return piece, nil
+}
+
+// DeleteWishlistItem deletes a wishlist item, and its price history, by ID
+func (r *WishlistRepository) DeleteWishlistItem(id uuid.UUID, userID uuid.UUID) error {
+	ctx := context.Background()
+	query := `DELETE FROM wishlist_items WHERE id = $1 AND user_id = $2`
+
+	result, err := r.db.Exec(ctx, query, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete wishlist item: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("wishlist item not found or access denied")
+	}
+
+	return nil
+}
+
+func recordWishlistPrice(ctx context.Context, tx pgx.Tx, itemID uuid.UUID, price models.Money) error {
+	query := `
+		INSERT INTO wishlist_price_history (id, wishlist_item_id, price_minor, price_currency)
+		VALUES ($1, $2, $3, $4)`
+
+	if _, err := tx.Exec(ctx, query, uuid.New(), itemID, price.MinorUnits, price.Currency); err != nil {
+		return fmt.Errorf("failed to record wishlist price: %w", err)
+	}
+
+	return nil
+}
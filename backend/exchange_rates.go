@@ -0,0 +1,127 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"kyarafit-backend/database"
+	"kyarafit-backend/models"
+)
+
+// exchangeRatesHeader is the header row of an exchange rate file. Each row
+// after it gives how many units of quote one unit of base bought on a day:
+//
+//	date,base,quote,rate
+//	2024-05-01,USD,JPY,157.12
+var exchangeRatesHeader = []string{"date", "base", "quote", "rate"}
+
+// loadExchangeRates reads exchange rates from a CSV file and stores them,
+// replacing rates already loaded for the same pair and day
+func loadExchangeRates(currencyRepo *database.CurrencyRepository, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	rates, err := readExchangeRates(file)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	if len(rates) == 0 {
+		return fmt.Errorf("%s: no exchange rates", path)
+	}
+
+	refreshed, err := currencyRepo.UpsertExchangeRates(rates)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Loaded %d exchange rates; spending of %d builds updated", len(rates), refreshed)
+	return nil
+}
+
+// readExchangeRates parses and checks every row of an exchange rate file, so
+// a bad file loads nothing. A pair may have one rate a day.
+func readExchangeRates(r io.Reader) ([]models.ExchangeRate, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = len(exchangeRatesHeader)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	for i, name := range exchangeRatesHeader {
+		if strings.ToLower(strings.TrimSpace(header[i])) != name {
+			return nil, fmt.Errorf("header must be %s", strings.Join(exchangeRatesHeader, ","))
+		}
+	}
+
+	var rates []models.ExchangeRate
+	seen := make(map[string]int) // line of each date, base and quote
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := reader.FieldPos(0)
+
+		rate, err := parseExchangeRate(record)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		key := rate.EffectiveOn.Format("2006-01-02") + " " + rate.Base + "/" + rate.Quote
+		if first, ok := seen[key]; ok {
+			return nil, fmt.Errorf("line %d: duplicate rate for %s on %s, first given on line %d",
+				line, rate.Base+"/"+rate.Quote, rate.EffectiveOn.Format("2006-01-02"), first)
+		}
+		seen[key] = line
+		rates = append(rates, rate)
+	}
+
+	return rates, nil
+}
+
+func parseExchangeRate(record []string) (models.ExchangeRate, error) {
+	effectiveOn, err := time.Parse("2006-01-02", strings.TrimSpace(record[0]))
+	if err != nil {
+		return models.ExchangeRate{}, fmt.Errorf("invalid date %q. Use YYYY-MM-DD", record[0])
+	}
+
+	base, err := models.NormalizeCurrency(record[1])
+	if err != nil {
+		return models.ExchangeRate{}, fmt.Errorf("unsupported currency %q", record[1])
+	}
+	quote, err := models.NormalizeCurrency(record[2])
+	if err != nil {
+		return models.ExchangeRate{}, fmt.Errorf("unsupported currency %q", record[2])
+	}
+	if base == quote {
+		return models.ExchangeRate{}, fmt.Errorf("base and quote are both %s", base)
+	}
+
+	// The rate is stored as written, so it must be a plain decimal that fits
+	// NUMERIC(24,12)
+	rate := strings.TrimSpace(record[3])
+	whole, frac, _ := strings.Cut(rate, ".")
+	value, err := strconv.ParseFloat(rate, 64)
+	if err != nil || strings.Trim(whole+frac, "0123456789") != "" || whole == "" || len(whole) > 12 || len(frac) > 12 || value <= 0 {
+		return models.ExchangeRate{}, fmt.Errorf("invalid rate %q. Must be a positive decimal number", record[3])
+	}
+
+	return models.ExchangeRate{Base: base, Quote: quote, EffectiveOn: effectiveOn, Rate: rate}, nil
+}
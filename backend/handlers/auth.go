@@ -0,0 +1,226 @@
+package handlers
+
+import (
+	"errors"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"kyarafit-backend/auth"
+	"kyarafit-backend/database"
+	"kyarafit-backend/models"
+)
+
+type AuthHandler struct {
+	userRepo         *database.UserRepository
+	refreshTokenRepo *database.RefreshTokenRepository
+	issuer           *auth.TokenIssuer
+}
+
+func NewAuthHandler(userRepo *database.UserRepository, refreshTokenRepo *database.RefreshTokenRepository, issuer *auth.TokenIssuer) *AuthHandler {
+	return &AuthHandler{
+		userRepo:         userRepo,
+		refreshTokenRepo: refreshTokenRepo,
+		issuer:           issuer,
+	}
+}
+
+// newRefreshToken generates a refresh token for the request and the record it is stored as
+func (h *AuthHandler) newRefreshToken(c *fiber.Ctx) (string, *models.RefreshToken, error) {
+	token, hash, err := auth.NewRefreshToken()
+	if err != nil {
+		return "", nil, err
+	}
+
+	record := &models.RefreshToken{
+		ID:        uuid.New(),
+		TokenHash: hash,
+		ExpiresAt: time.Now().Add(h.issuer.RefreshTokenTTL()),
+		CreatedAt: time.Now(),
+	}
+	if userAgent := c.Get(fiber.HeaderUserAgent); userAgent != "" {
+		record.UserAgent = &userAgent
+	}
+
+	return token, record, nil
+}
+
+// startSession issues an access token and a refresh token in a new family for the user
+func (h *AuthHandler) startSession(c *fiber.Ctx, user *models.User) (*models.AuthResponse, error) {
+	accessToken, err := h.issuer.IssueAccessToken(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, record, err := h.newRefreshToken(c)
+	if err != nil {
+		return nil, err
+	}
+	record.UserID = user.ID
+	record.FamilyID = uuid.New()
+
+	if err := h.refreshTokenRepo.CreateRefreshToken(record); err != nil {
+		return nil, err
+	}
+
+	userResponse := user.ToResponse()
+	return &models.AuthResponse{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(h.issuer.AccessTokenTTL().Seconds()),
+		User:         &userResponse,
+	}, nil
+}
+
+// Register creates an account with an email and password and logs it in
+func (h *AuthHandler) Register(c *fiber.Ctx) error {
+	var req models.RegisterRequest
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+
+	email := strings.TrimSpace(req.Email)
+	if _, err := mail.ParseAddress(email); err != nil || len(email) > 255 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "A valid email is required",
+		})
+	}
+
+	passwordHash, err := auth.HashPassword(req.Password)
+	if err != nil {
+		if errors.Is(err, auth.ErrPasswordLength) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Password must be between 8 and 72 characters",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to create account",
+		})
+	}
+
+	user := &models.User{
+		ID:           uuid.New(),
+		Email:        email,
+		Username:     req.Username,
+		DisplayName:  req.DisplayName,
+		PasswordHash: &passwordHash,
+		CreatedAt:    time.Now(),
+		UpdatedAt:    time.Now(),
+	}
+
+	if err := h.userRepo.CreateUser(user); err != nil {
+		if errors.Is(err, database.ErrEmailTaken) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": "An account with this email already exists",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to create account",
+		})
+	}
+
+	session, err := h.startSession(c, user)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to start session",
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(session)
+}
+
+// Login exchanges an email and password for an access token and refresh token
+func (h *AuthHandler) Login(c *fiber.Ctx) error {
+	var req models.LoginRequest
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+
+	// Unknown emails still go through a password comparison so both failures look alike
+	var passwordHash *string
+	user, err := h.userRepo.GetUserByEmail(strings.TrimSpace(req.Email))
+	if err == nil {
+		passwordHash = user.PasswordHash
+	}
+	if !auth.CheckPassword(passwordHash, req.Password) {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Invalid email or password",
+		})
+	}
+
+	session, err := h.startSession(c, user)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to start session",
+		})
+	}
+
+	return c.JSON(session)
+}
+
+// Refresh rotates a refresh token, returning a new access token and refresh token
+func (h *AuthHandler) Refresh(c *fiber.Ctx) error {
+	var req models.RefreshRequest
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+
+	refreshToken, record, err := h.newRefreshToken(c)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to refresh session",
+		})
+	}
+
+	if err := h.refreshTokenRepo.RotateRefreshToken(auth.HashRefreshToken(req.RefreshToken), record); err != nil {
+		if errors.Is(err, database.ErrInvalidRefreshToken) || errors.Is(err, database.ErrRefreshTokenReused) {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Invalid or expired refresh token",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to refresh session",
+		})
+	}
+
+	accessToken, err := h.issuer.IssueAccessToken(record.UserID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to refresh session",
+		})
+	}
+
+	return c.JSON(models.AuthResponse{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(h.issuer.AccessTokenTTL().Seconds()),
+	})
+}
+
+// Logout revokes a refresh token and every rotation of it. Access tokens
+// already issued stay valid until they expire.
+func (h *AuthHandler) Logout(c *fiber.Ctx) error {
+	var req models.RefreshRequest
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+
+	if err := h.refreshTokenRepo.RevokeRefreshTokenFamily(auth.HashRefreshToken(req.RefreshToken)); err != nil {
+		if errors.Is(err, database.ErrInvalidRefreshToken) {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Invalid or expired refresh token",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to log out",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Logged out successfully",
+	})
+}
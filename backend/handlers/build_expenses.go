@@ -0,0 +1,429 @@
+package handlers
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"kyarafit-backend/database"
+	"kyarafit-backend/middleware"
+	"kyarafit-backend/models"
+)
+
+type BuildExpensesHandler struct {
+	buildRepo    *database.BuildRepository
+	pieceRepo    *database.PieceRepository
+	expenseRepo  *database.BuildExpenseRepository
+	currencyRepo *database.CurrencyRepository
+}
+
+func NewBuildExpensesHandler(buildRepo *database.BuildRepository, pieceRepo *database.PieceRepository, expenseRepo *database.BuildExpenseRepository, currencyRepo *database.CurrencyRepository) *BuildExpensesHandler {
+	return &BuildExpensesHandler{
+		buildRepo:    buildRepo,
+		pieceRepo:    pieceRepo,
+		expenseRepo:  expenseRepo,
+		currencyRepo: currencyRepo,
+	}
+}
+
+// ownedBuild loads the build named in the :id param and checks it belongs to the user
+func (h *BuildExpensesHandler) ownedBuild(c *fiber.Ctx, userUUID uuid.UUID) (*models.Build, *fiber.Error) {
+	buildID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Invalid build ID")
+	}
+
+	build, err := h.buildRepo.GetBuildByID(buildID)
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusNotFound, "Build not found")
+	}
+
+	if build.UserID != userUUID {
+		return nil, fiber.NewError(fiber.StatusForbidden, "Access denied")
+	}
+
+	return build, nil
+}
+
+// findExpense loads the expense named in the :expenseId param from the build
+func (h *BuildExpensesHandler) findExpense(c *fiber.Ctx, build *models.Build) (*models.BuildExpense, *fiber.Error) {
+	expenseID, err := uuid.Parse(c.Params("expenseId"))
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Invalid expense ID")
+	}
+
+	expense, err := h.expenseRepo.GetExpense(build.ID, expenseID)
+	if err != nil {
+		if errors.Is(err, database.ErrBuildExpenseNotFound) {
+			return nil, fiber.NewError(fiber.StatusNotFound, "Expense not found")
+		}
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "Failed to retrieve expense")
+	}
+
+	return expense, nil
+}
+
+// ownedPiece parses a piece ID for an expense and checks the piece belongs to the user
+func (h *BuildExpensesHandler) ownedPiece(raw string, userUUID uuid.UUID) (*uuid.UUID, *fiber.Error) {
+	pieceID, err := uuid.Parse(raw)
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Invalid piece ID")
+	}
+
+	piece, err := h.pieceRepo.GetPieceByID(pieceID)
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusNotFound, "Piece not found")
+	}
+	if piece.UserID != userUUID {
+		return nil, fiber.NewError(fiber.StatusForbidden, "Access denied")
+	}
+
+	return &piece.ID, nil
+}
+
+// optionalText returns nil for an empty string so it clears the field
+func optionalText(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
+
+// spendSummary reports a build's budget, what it has spent and its budget
+// warning, all in the build's currency
+func spendSummary(build *models.Build) fiber.Map {
+	response := build.ToResponse()
+	return fiber.Map{
+		"currency":         response.Currency,
+		"budget":           response.Budget,
+		"spent":            response.Spent,
+		"budget_remaining": response.BudgetRemaining,
+		"budget_warning":   response.BudgetWarning,
+	}
+}
+
+// GetBuildExpenses retrieves a build's expense ledger with its breakdown and budget status
+func (h *BuildExpensesHandler) GetBuildExpenses(c *fiber.Ctx) error {
+	principal, err := middleware.CurrentPrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	build, ferr := h.ownedBuild(c, principal.UserID)
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{
+			"error": ferr.Message,
+		})
+	}
+
+	expenses, err := h.expenseRepo.ListExpenses(build.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to retrieve expenses",
+		})
+	}
+	if expenses == nil {
+		expenses = []*models.BuildExpense{}
+	}
+
+	breakdown, err := h.expenseRepo.GetExpenseBreakdown(principal.UserID, database.ExpenseFilter{BuildID: &build.ID}, build.Currency)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to retrieve expenses",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"expenses":  expenses,
+		"breakdown": breakdown,
+		"spend":     spendSummary(build),
+	})
+}
+
+// CreateBuildExpense records an expense on a build
+func (h *BuildExpensesHandler) CreateBuildExpense(c *fiber.Ctx) error {
+	principal, err := middleware.CurrentPrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	build, ferr := h.ownedBuild(c, principal.UserID)
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{
+			"error": ferr.Message,
+		})
+	}
+
+	var req models.CreateBuildExpenseRequest
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+
+	// A bare amount is in the build's currency
+	amount, ferr := parseMoney(*req.Amount, build.Currency, "amount")
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{
+			"error": ferr.Message,
+		})
+	}
+
+	now := time.Now()
+	spentOn := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if req.SpentOn != nil && *req.SpentOn != "" {
+		spentOn, err = time.Parse("2006-01-02", *req.SpentOn)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid spent on date format. Use YYYY-MM-DD",
+			})
+		}
+	}
+
+	var pieceID *uuid.UUID
+	if req.PieceID != nil && *req.PieceID != "" {
+		pieceID, ferr = h.ownedPiece(*req.PieceID, principal.UserID)
+		if ferr != nil {
+			return c.Status(ferr.Code).JSON(fiber.Map{
+				"error": ferr.Message,
+			})
+		}
+	}
+
+	expense := &models.BuildExpense{
+		ID:        uuid.New(),
+		BuildID:   build.ID,
+		Source:    models.ExpenseSourceManual,
+		Amount:    amount,
+		SpentOn:   spentOn,
+		PieceID:   pieceID,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if req.Description != nil {
+		expense.Description = optionalText(*req.Description)
+	}
+	if req.Vendor != nil {
+		expense.Vendor = optionalText(*req.Vendor)
+	}
+	if req.Category != nil {
+		expense.Category = optionalText(*req.Category)
+	}
+	if req.Notes != nil {
+		expense.Notes = optionalText(*req.Notes)
+	}
+
+	if err := h.expenseRepo.CreateExpense(expense); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to create expense",
+		})
+	}
+
+	// The ledger trigger has updated the build's spent
+	build, err = h.buildRepo.GetBuildByID(build.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to retrieve build",
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message": "Expense created successfully",
+		"expense": expense,
+		"spend":   spendSummary(build),
+	})
+}
+
+// UpdateBuildExpense updates an expense. Entries for linked pieces follow the
+// piece, so only their vendor and notes can be changed here.
+func (h *BuildExpensesHandler) UpdateBuildExpense(c *fiber.Ctx) error {
+	principal, err := middleware.CurrentPrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	build, ferr := h.ownedBuild(c, principal.UserID)
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{
+			"error": ferr.Message,
+		})
+	}
+
+	expense, ferr := h.findExpense(c, build)
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{
+			"error": ferr.Message,
+		})
+	}
+
+	var req models.UpdateBuildExpenseRequest
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+
+	if expense.Source == models.ExpenseSourcePiece &&
+		(req.Amount != nil || req.Description != nil || req.Category != nil || req.SpentOn != nil || req.PieceID != nil) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "This expense follows a linked piece. Edit the piece's price, name, category or purchase date instead",
+		})
+	}
+
+	// Update fields if provided
+	if req.Amount != nil {
+		// A bare amount keeps the expense's currency
+		if expense.Amount, ferr = parseMoney(*req.Amount, expense.Amount.Currency, "amount"); ferr != nil {
+			return c.Status(ferr.Code).JSON(fiber.Map{
+				"error": ferr.Message,
+			})
+		}
+	}
+	if req.Description != nil {
+		expense.Description = optionalText(*req.Description)
+	}
+	if req.Vendor != nil {
+		expense.Vendor = optionalText(*req.Vendor)
+	}
+	if req.Category != nil {
+		expense.Category = optionalText(*req.Category)
+	}
+	if req.SpentOn != nil {
+		parsedDate, err := time.Parse("2006-01-02", *req.SpentOn)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid spent on date format. Use YYYY-MM-DD",
+			})
+		}
+		expense.SpentOn = parsedDate
+	}
+	if req.PieceID != nil {
+		if *req.PieceID == "" {
+			expense.PieceID = nil
+		} else {
+			expense.PieceID, ferr = h.ownedPiece(*req.PieceID, principal.UserID)
+			if ferr != nil {
+				return c.Status(ferr.Code).JSON(fiber.Map{
+					"error": ferr.Message,
+				})
+			}
+		}
+	}
+	if req.Notes != nil {
+		expense.Notes = optionalText(*req.Notes)
+	}
+
+	expense.UpdatedAt = time.Now()
+
+	if err := h.expenseRepo.UpdateExpense(expense); err != nil {
+		if errors.Is(err, database.ErrBuildExpenseNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Expense not found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to update expense",
+		})
+	}
+
+	// The ledger trigger has updated the build's spent
+	build, err = h.buildRepo.GetBuildByID(build.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to retrieve build",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Expense updated successfully",
+		"expense": expense,
+		"spend":   spendSummary(build),
+	})
+}
+
+// DeleteBuildExpense removes a manual expense from a build's ledger
+func (h *BuildExpensesHandler) DeleteBuildExpense(c *fiber.Ctx) error {
+	principal, err := middleware.CurrentPrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	build, ferr := h.ownedBuild(c, principal.UserID)
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{
+			"error": ferr.Message,
+		})
+	}
+
+	expense, ferr := h.findExpense(c, build)
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{
+			"error": ferr.Message,
+		})
+	}
+
+	if expense.Source == models.ExpenseSourcePiece {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "This expense follows a linked piece. Remove the piece from the build instead",
+		})
+	}
+
+	if err := h.expenseRepo.DeleteExpense(build.ID, expense.ID); err != nil {
+		if errors.Is(err, database.ErrBuildExpenseNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Expense not found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to delete expense",
+		})
+	}
+
+	return c.Status(fiber.StatusNoContent).JSON(fiber.Map{
+		"message": "Expense deleted successfully",
+	})
+}
+
+// GetExpenseStats breaks down spending across all of the user's builds for a
+// period, by build, category, currency and month, in the user's home currency
+func (h *BuildExpensesHandler) GetExpenseStats(c *fiber.Ctx) error {
+	principal, err := middleware.CurrentPrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	var filter database.ExpenseFilter
+	var ferr *fiber.Error
+	if filter.From, ferr = queryDate(c, "from"); ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{
+			"error": ferr.Message,
+		})
+	}
+	if filter.To, ferr = queryDate(c, "to"); ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{
+			"error": ferr.Message,
+		})
+	}
+	if filter.From != nil && filter.To != nil && filter.To.Before(*filter.From) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "to must not be before from",
+		})
+	}
+
+	currency, err := h.currencyRepo.GetHomeCurrency(principal.UserID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to retrieve expense statistics",
+		})
+	}
+
+	breakdown, err := h.expenseRepo.GetExpenseBreakdown(principal.UserID, filter, currency)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to retrieve expense statistics",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"from":  filter.From,
+		"to":    filter.To,
+		"stats": breakdown,
+	})
+}
@@ -0,0 +1,306 @@
+package handlers
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"kyarafit-backend/database"
+	"kyarafit-backend/middleware"
+	"kyarafit-backend/models"
+	"kyarafit-backend/storage"
+)
+
+type BuildPiecesHandler struct {
+	buildRepo      *database.BuildRepository
+	pieceRepo      *database.PieceRepository
+	buildPieceRepo *database.BuildPieceRepository
+	blobs          storage.BlobStore
+}
+
+func NewBuildPiecesHandler(buildRepo *database.BuildRepository, pieceRepo *database.PieceRepository, buildPieceRepo *database.BuildPieceRepository, blobs storage.BlobStore) *BuildPiecesHandler {
+	return &BuildPiecesHandler{
+		buildRepo:      buildRepo,
+		pieceRepo:      pieceRepo,
+		buildPieceRepo: buildPieceRepo,
+		blobs:          blobs,
+	}
+}
+
+// ownedBuild loads the build named in the :id param and checks it belongs to the user
+func (h *BuildPiecesHandler) ownedBuild(c *fiber.Ctx, userUUID uuid.UUID) (*models.Build, *fiber.Error) {
+	buildID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Invalid build ID")
+	}
+
+	build, err := h.buildRepo.GetBuildByID(buildID)
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusNotFound, "Build not found")
+	}
+
+	if build.UserID != userUUID {
+		return nil, fiber.NewError(fiber.StatusForbidden, "Access denied")
+	}
+
+	return build, nil
+}
+
+// GetBuildPieces retrieves every piece linked to a build
+func (h *BuildPiecesHandler) GetBuildPieces(c *fiber.Ctx) error {
+	principal, err := middleware.CurrentPrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	build, ferr := h.ownedBuild(c, principal.UserID)
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{
+			"error": ferr.Message,
+		})
+	}
+
+	buildPieces, err := h.buildPieceRepo.GetBuildPieces(build.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to retrieve build pieces",
+		})
+	}
+
+	response := make([]models.BuildPieceResponse, 0, len(buildPieces))
+	for _, bp := range buildPieces {
+		signPieceImages(h.blobs, bp.Piece)
+		response = append(response, bp.ToResponse())
+	}
+
+	return c.JSON(fiber.Map{
+		"pieces": response,
+	})
+}
+
+// AddBuildPiece attaches a closet piece to a build
+func (h *BuildPiecesHandler) AddBuildPiece(c *fiber.Ctx) error {
+	principal, err := middleware.CurrentPrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	build, ferr := h.ownedBuild(c, principal.UserID)
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{
+			"error": ferr.Message,
+		})
+	}
+
+	var req models.AddBuildPieceRequest
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+
+	pieceID, err := uuid.Parse(req.PieceID)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid piece ID",
+		})
+	}
+
+	// Both sides of the link must belong to the caller
+	piece, err := h.pieceRepo.GetPieceByID(pieceID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Piece not found",
+		})
+	}
+	if piece.UserID != principal.UserID {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Access denied",
+		})
+	}
+
+	quantity := 1
+	if req.Quantity != nil {
+		quantity = *req.Quantity
+	}
+
+	bp := &models.BuildPiece{
+		ID:        uuid.New(),
+		BuildID:   build.ID,
+		PieceID:   piece.ID,
+		Role:      req.Role,
+		Quantity:  quantity,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		Piece:     piece,
+	}
+
+	if err := h.buildPieceRepo.AddPieceToBuild(bp, req.SortOrder); err != nil {
+		if errors.Is(err, database.ErrPieceAlreadyInBuild) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": "Piece is already linked to this build",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to add piece to build",
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message":     "Piece added to build successfully",
+		"build_piece": bp.ToResponse(),
+	})
+}
+
+// UpdateBuildPiece updates the role, quantity or sort order of a piece within a build
+func (h *BuildPiecesHandler) UpdateBuildPiece(c *fiber.Ctx) error {
+	principal, err := middleware.CurrentPrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	build, ferr := h.ownedBuild(c, principal.UserID)
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{
+			"error": ferr.Message,
+		})
+	}
+
+	pieceID, err := uuid.Parse(c.Params("pieceId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid piece ID",
+		})
+	}
+
+	existing, err := h.buildPieceRepo.GetBuildPiece(build.ID, pieceID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Piece is not linked to this build",
+		})
+	}
+
+	var req models.UpdateBuildPieceRequest
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+
+	// Update fields if provided
+	if req.Role != nil {
+		if *req.Role == "" {
+			existing.Role = nil
+		} else {
+			existing.Role = req.Role
+		}
+	}
+	if req.Quantity != nil {
+		existing.Quantity = *req.Quantity
+	}
+	if req.SortOrder != nil {
+		existing.SortOrder = *req.SortOrder
+	}
+
+	existing.UpdatedAt = time.Now()
+
+	if err := h.buildPieceRepo.UpdateBuildPiece(existing); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to update build piece",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message":     "Build piece updated successfully",
+		"build_piece": existing.ToResponse(),
+	})
+}
+
+// ReorderBuildPieces sets the order of every piece in a build
+func (h *BuildPiecesHandler) ReorderBuildPieces(c *fiber.Ctx) error {
+	principal, err := middleware.CurrentPrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	build, ferr := h.ownedBuild(c, principal.UserID)
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{
+			"error": ferr.Message,
+		})
+	}
+
+	var req models.ReorderBuildPiecesRequest
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+
+	pieceIDs := make([]uuid.UUID, 0, len(req.PieceIDs))
+	for _, idStr := range req.PieceIDs {
+		pieceID, err := uuid.Parse(idStr)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid piece ID",
+			})
+		}
+		pieceIDs = append(pieceIDs, pieceID)
+	}
+
+	if err := h.buildPieceRepo.ReorderBuildPieces(build.ID, pieceIDs); err != nil {
+		if errors.Is(err, database.ErrInvalidPieceOrder) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "piece_ids must list every piece in the build exactly once",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to reorder build pieces",
+		})
+	}
+
+	buildPieces, err := h.buildPieceRepo.GetBuildPieces(build.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to retrieve build pieces",
+		})
+	}
+
+	response := make([]models.BuildPieceResponse, 0, len(buildPieces))
+	for _, bp := range buildPieces {
+		signPieceImages(h.blobs, bp.Piece)
+		response = append(response, bp.ToResponse())
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Build pieces reordered successfully",
+		"pieces":  response,
+	})
+}
+
+// RemoveBuildPiece unlinks a piece from a build. The piece itself stays in the closet.
+func (h *BuildPiecesHandler) RemoveBuildPiece(c *fiber.Ctx) error {
+	principal, err := middleware.CurrentPrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	build, ferr := h.ownedBuild(c, principal.UserID)
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{
+			"error": ferr.Message,
+		})
+	}
+
+	pieceID, err := uuid.Parse(c.Params("pieceId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid piece ID",
+		})
+	}
+
+	if err := h.buildPieceRepo.RemovePieceFromBuild(build.ID, pieceID); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Piece is not linked to this build",
+		})
+	}
+
+	return c.Status(fiber.StatusNoContent).JSON(fiber.Map{
+		"message": "Piece removed from build successfully",
+	})
+}
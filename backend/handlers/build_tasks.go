@@ -0,0 +1,438 @@
+package handlers
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"kyarafit-backend/database"
+	"kyarafit-backend/middleware"
+	"kyarafit-backend/models"
+)
+
+type BuildTasksHandler struct {
+	buildRepo *database.BuildRepository
+	pieceRepo *database.PieceRepository
+	taskRepo  *database.BuildTaskRepository
+}
+
+func NewBuildTasksHandler(buildRepo *database.BuildRepository, pieceRepo *database.PieceRepository, taskRepo *database.BuildTaskRepository) *BuildTasksHandler {
+	return &BuildTasksHandler{
+		buildRepo: buildRepo,
+		pieceRepo: pieceRepo,
+		taskRepo:  taskRepo,
+	}
+}
+
+// ownedBuild loads the build named in the :id param and checks it belongs to the user
+func (h *BuildTasksHandler) ownedBuild(c *fiber.Ctx, userUUID uuid.UUID) (*models.Build, *fiber.Error) {
+	buildID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Invalid build ID")
+	}
+
+	build, err := h.buildRepo.GetBuildByID(buildID)
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusNotFound, "Build not found")
+	}
+
+	if build.UserID != userUUID {
+		return nil, fiber.NewError(fiber.StatusForbidden, "Access denied")
+	}
+
+	return build, nil
+}
+
+// findTask loads the task named in the :taskId param from the build
+func (h *BuildTasksHandler) findTask(c *fiber.Ctx, build *models.Build) (*models.BuildTask, *fiber.Error) {
+	taskID, err := uuid.Parse(c.Params("taskId"))
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Invalid task ID")
+	}
+
+	task, err := h.taskRepo.GetTask(build.ID, taskID)
+	if err != nil {
+		if errors.Is(err, database.ErrBuildTaskNotFound) {
+			return nil, fiber.NewError(fiber.StatusNotFound, "Task not found")
+		}
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "Failed to retrieve task")
+	}
+
+	return task, nil
+}
+
+// ownedPiece parses a piece ID for a task and checks the piece belongs to the user
+func (h *BuildTasksHandler) ownedPiece(raw string, userUUID uuid.UUID) (*uuid.UUID, *fiber.Error) {
+	pieceID, err := uuid.Parse(raw)
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Invalid piece ID")
+	}
+
+	piece, err := h.pieceRepo.GetPieceByID(pieceID)
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusNotFound, "Piece not found")
+	}
+	if piece.UserID != userUUID {
+		return nil, fiber.NewError(fiber.StatusForbidden, "Access denied")
+	}
+
+	return &piece.ID, nil
+}
+
+// GetBuildTasks retrieves a build's tasks and milestones in order, with its progress
+func (h *BuildTasksHandler) GetBuildTasks(c *fiber.Ctx) error {
+	principal, err := middleware.CurrentPrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	build, ferr := h.ownedBuild(c, principal.UserID)
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{
+			"error": ferr.Message,
+		})
+	}
+
+	tasks, err := h.taskRepo.GetBuildTasks(build.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to retrieve tasks",
+		})
+	}
+
+	progress := models.TaskProgress{Total: len(tasks)}
+	for _, task := range tasks {
+		if task.Done {
+			progress.Done++
+		}
+	}
+	buildResponse := build.ToResponse()
+	buildResponse.ApplyTaskProgress(progress)
+
+	if tasks == nil {
+		tasks = []*models.BuildTask{}
+	}
+
+	return c.JSON(fiber.Map{
+		"tasks":            tasks,
+		"total_count":      progress.Total,
+		"done_count":       progress.Done,
+		"progress_percent": buildResponse.ProgressPercent,
+	})
+}
+
+// CreateBuildTask adds a task or milestone to a build
+func (h *BuildTasksHandler) CreateBuildTask(c *fiber.Ctx) error {
+	principal, err := middleware.CurrentPrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	build, ferr := h.ownedBuild(c, principal.UserID)
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{
+			"error": ferr.Message,
+		})
+	}
+
+	var req models.CreateBuildTaskRequest
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+
+	kind := models.BuildTaskKindTask
+	if req.Kind != nil {
+		kind = models.BuildTaskKind(*req.Kind)
+	}
+
+	var dueDate *time.Time
+	if req.DueDate != nil && *req.DueDate != "" {
+		parsedDate, err := time.Parse("2006-01-02", *req.DueDate)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid due date format. Use YYYY-MM-DD",
+			})
+		}
+		dueDate = &parsedDate
+	}
+
+	var pieceID *uuid.UUID
+	if req.PieceID != nil && *req.PieceID != "" {
+		pieceID, ferr = h.ownedPiece(*req.PieceID, principal.UserID)
+		if ferr != nil {
+			return c.Status(ferr.Code).JSON(fiber.Map{
+				"error": ferr.Message,
+			})
+		}
+	}
+
+	now := time.Now()
+	task := &models.BuildTask{
+		ID:        uuid.New(),
+		BuildID:   build.ID,
+		BuildName: build.Name,
+		Kind:      kind,
+		Title:     req.Title,
+		DueDate:   dueDate,
+		PieceID:   pieceID,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if req.Done != nil {
+		task.SetDone(*req.Done, now)
+	}
+
+	if err := h.taskRepo.CreateTask(task, req.SortOrder); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to create task",
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message": "Task created successfully",
+		"task":    task,
+	})
+}
+
+// UpdateBuildTask updates a task, including ticking it off or reopening it
+func (h *BuildTasksHandler) UpdateBuildTask(c *fiber.Ctx) error {
+	principal, err := middleware.CurrentPrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	build, ferr := h.ownedBuild(c, principal.UserID)
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{
+			"error": ferr.Message,
+		})
+	}
+
+	task, ferr := h.findTask(c, build)
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{
+			"error": ferr.Message,
+		})
+	}
+
+	var req models.UpdateBuildTaskRequest
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+
+	// Update fields if provided
+	if req.Kind != nil {
+		task.Kind = models.BuildTaskKind(*req.Kind)
+	}
+	if req.Title != nil {
+		task.Title = *req.Title
+	}
+	if req.DueDate != nil {
+		if *req.DueDate == "" {
+			task.DueDate = nil
+		} else {
+			parsedDate, err := time.Parse("2006-01-02", *req.DueDate)
+			if err != nil {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+					"error": "Invalid due date format. Use YYYY-MM-DD",
+				})
+			}
+			task.DueDate = &parsedDate
+		}
+	}
+	if req.SortOrder != nil {
+		task.SortOrder = *req.SortOrder
+	}
+	if req.PieceID != nil {
+		if *req.PieceID == "" {
+			task.PieceID = nil
+		} else {
+			task.PieceID, ferr = h.ownedPiece(*req.PieceID, principal.UserID)
+			if ferr != nil {
+				return c.Status(ferr.Code).JSON(fiber.Map{
+					"error": ferr.Message,
+				})
+			}
+		}
+	}
+
+	now := time.Now()
+	if req.Done != nil {
+		task.SetDone(*req.Done, now)
+	}
+	task.UpdatedAt = now
+
+	if err := h.taskRepo.UpdateTask(task); err != nil {
+		if errors.Is(err, database.ErrBuildTaskNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Task not found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to update task",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Task updated successfully",
+		"task":    task,
+	})
+}
+
+// ReorderBuildTasks sets the order of every task on a build
+func (h *BuildTasksHandler) ReorderBuildTasks(c *fiber.Ctx) error {
+	principal, err := middleware.CurrentPrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	build, ferr := h.ownedBuild(c, principal.UserID)
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{
+			"error": ferr.Message,
+		})
+	}
+
+	var req models.ReorderBuildTasksRequest
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+
+	taskIDs := make([]uuid.UUID, 0, len(req.TaskIDs))
+	for _, idStr := range req.TaskIDs {
+		taskID, err := uuid.Parse(idStr)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid task ID",
+			})
+		}
+		taskIDs = append(taskIDs, taskID)
+	}
+
+	if err := h.taskRepo.ReorderTasks(build.ID, taskIDs); err != nil {
+		if errors.Is(err, database.ErrInvalidTaskOrder) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "task_ids must list every task in the build exactly once",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to reorder tasks",
+		})
+	}
+
+	tasks, err := h.taskRepo.GetBuildTasks(build.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to retrieve tasks",
+		})
+	}
+	if tasks == nil {
+		tasks = []*models.BuildTask{}
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Tasks reordered successfully",
+		"tasks":   tasks,
+	})
+}
+
+// DeleteBuildTask removes a task from a build
+func (h *BuildTasksHandler) DeleteBuildTask(c *fiber.Ctx) error {
+	principal, err := middleware.CurrentPrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	build, ferr := h.ownedBuild(c, principal.UserID)
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{
+			"error": ferr.Message,
+		})
+	}
+
+	taskID, err := uuid.Parse(c.Params("taskId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid task ID",
+		})
+	}
+
+	if err := h.taskRepo.DeleteTask(build.ID, taskID); err != nil {
+		if errors.Is(err, database.ErrBuildTaskNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Task not found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to delete task",
+		})
+	}
+
+	return c.Status(fiber.StatusNoContent).JSON(fiber.Map{
+		"message": "Task deleted successfully",
+	})
+}
+
+// GetTasks lists tasks across all of the user's builds, soonest due first.
+// Only open tasks are listed unless done is given.
+func (h *BuildTasksHandler) GetTasks(c *fiber.Ctx) error {
+	principal, err := middleware.CurrentPrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	var filter database.TaskFilter
+	var ferr *fiber.Error
+	if filter.DueBefore, ferr = queryDate(c, "due_before"); ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{
+			"error": ferr.Message,
+		})
+	}
+	if filter.Done, ferr = queryBool(c, "done"); ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{
+			"error": ferr.Message,
+		})
+	}
+	if filter.Done == nil {
+		open := false
+		filter.Done = &open
+	}
+	if kind := c.Query("kind"); kind != "" {
+		if !models.IsValidTaskKind(kind) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid kind. Must be one of: task, milestone",
+			})
+		}
+		taskKind := models.BuildTaskKind(kind)
+		filter.Kind = &taskKind
+	}
+	if buildIDStr := c.Query("build_id"); buildIDStr != "" {
+		buildID, err := uuid.Parse(buildIDStr)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid build ID",
+			})
+		}
+		filter.BuildID = &buildID
+	}
+
+	limit, offset := parseLimitOffset(c)
+
+	tasks, total, err := h.taskRepo.ListTasks(principal.UserID, filter, limit, offset)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to retrieve tasks",
+		})
+	}
+	if tasks == nil {
+		tasks = []*models.BuildTask{}
+	}
+
+	return c.JSON(fiber.Map{
+		"tasks":       tasks,
+		"total_count": total,
+		"limit":       limit,
+		"offset":      offset,
+	})
+}
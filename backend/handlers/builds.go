@@ -1,61 +1,109 @@
 package handlers
 
 import (
+	"errors"
 	"strconv"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 	"kyarafit-backend/database"
+	"kyarafit-backend/middleware"
 	"kyarafit-backend/models"
+	"kyarafit-backend/storage"
 )
 
 type BuildsHandler struct {
-	buildRepo *database.BuildRepository
+	buildRepo      *database.BuildRepository
+	buildPieceRepo *database.BuildPieceRepository
+	taskRepo       *database.BuildTaskRepository
+	wearLogRepo    *database.WearLogRepository
+	currencyRepo   *database.CurrencyRepository
+	blobs          storage.BlobStore
 }
 
-func NewBuildsHandler(buildRepo *database.BuildRepository) *BuildsHandler {
-	return &BuildsHandler{buildRepo: buildRepo}
+func NewBuildsHandler(buildRepo *database.BuildRepository, buildPieceRepo *database.BuildPieceRepository, taskRepo *database.BuildTaskRepository, wearLogRepo *database.WearLogRepository, currencyRepo *database.CurrencyRepository, blobs storage.BlobStore) *BuildsHandler {
+	return &BuildsHandler{buildRepo: buildRepo, buildPieceRepo: buildPieceRepo, taskRepo: taskRepo, wearLogRepo: wearLogRepo, currencyRepo: currencyRepo, blobs: blobs}
 }
 
-// CreateBuild creates a new build
-func (h *BuildsHandler) CreateBuild(c *fiber.Ctx) error {
-	userID, ok := c.Locals("userID").(string)
-	if !ok {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "User not authenticated",
-		})
+// applyBuildMoney sets a build's currency and budget from a request. A bare
+// budget is in the requested currency, or else the build's, and a budget in
+// another currency moves the build to it. Budgets aren't converted, so a
+// build with a budget changes currency only along with a new budget.
+func applyBuildMoney(build *models.Build, currency *string, budget *models.MoneyInput) *fiber.Error {
+	target := build.Currency
+	if currency != nil {
+		code, ferr := parseCurrency(*currency)
+		if ferr != nil {
+			return ferr
+		}
+		target = code
 	}
 
-	userUUID, err := uuid.Parse(userID)
+	if budget != nil {
+		money, ferr := parseMoney(*budget, target, "budget")
+		if ferr != nil {
+			return ferr
+		}
+		if currency != nil && money.Currency != target {
+			return fiber.NewError(fiber.StatusBadRequest, "Budget must be in the build's currency")
+		}
+		build.Budget = &money
+		build.Currency = money.Currency
+		return nil
+	}
+
+	if target != build.Currency && build.Budget != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Set the budget again in the new currency to change the currency of a build with a budget")
+	}
+	build.Currency = target
+	return nil
+}
+
+// toResponses converts builds to their response format with times worn, last
+// worn and task progress filled in
+func (h *BuildsHandler) toResponses(builds []*models.Build) ([]models.BuildResponse, error) {
+	ids := make([]uuid.UUID, 0, len(builds))
+	for _, build := range builds {
+		ids = append(ids, build.ID)
+	}
+
+	summaries, err := h.wearLogRepo.GetBuildWearSummaries(ids)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid user ID",
-		})
+		return nil, err
 	}
 
-	var req models.CreateBuildRequest
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid request body",
-		})
+	progress, err := h.taskRepo.GetTaskProgress(ids)
+	if err != nil {
+		return nil, err
 	}
 
-	// Validate required fields
-	if req.Name == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Name is required",
-		})
+	response := make([]models.BuildResponse, 0, len(builds))
+	for _, build := range builds {
+		buildResponse := build.ToResponse()
+		buildResponse.ApplyWearSummary(summaries[build.ID])
+		buildResponse.ApplyTaskProgress(progress[build.ID])
+		response = append(response, buildResponse)
+	}
+
+	return response, nil
+}
+
+// CreateBuild creates a new build
+func (h *BuildsHandler) CreateBuild(c *fiber.Ctx) error {
+	principal, err := middleware.CurrentPrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	var req models.CreateBuildRequest
+	if err := parseBody(c, &req); err != nil {
+		return err
 	}
 
 	// Set default status if not provided
 	status := models.BuildStatusIdea
 	if req.Status != nil {
-		if !models.IsValidStatus(*req.Status) {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error": "Invalid status. Must be one of: idea, sourcing, wip, complete, on_hold, cancelled",
-			})
-		}
 		status = models.BuildStatus(*req.Status)
 	}
 
@@ -83,15 +131,13 @@ func (h *BuildsHandler) CreateBuild(c *fiber.Ctx) error {
 
 	build := &models.Build{
 		ID:          uuid.New(),
-		UserID:      userUUID,
+		UserID:      principal.UserID,
 		Name:        req.Name,
 		Description: req.Description,
 		Character:   req.Character,
 		Series:      req.Series,
 		Status:      status,
 		Priority:    req.Priority,
-		Budget:      req.Budget,
-		Spent:       req.Spent,
 		StartDate:   startDate,
 		TargetDate:  targetDate,
 		Tags:        req.Tags,
@@ -100,125 +146,188 @@ func (h *BuildsHandler) CreateBuild(c *fiber.Ctx) error {
 		UpdatedAt:   time.Now(),
 	}
 
+	// Builds are in the home currency unless told otherwise
+	if build.Currency, err = h.currencyRepo.GetHomeCurrency(principal.UserID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to create build",
+		})
+	}
+	if ferr := applyBuildMoney(build, req.Currency, req.Budget); ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{
+			"error": ferr.Message,
+		})
+	}
+
+	// A build created complete was completed today
+	if build.Status == models.BuildStatusComplete {
+		today := time.Date(build.CreatedAt.Year(), build.CreatedAt.Month(), build.CreatedAt.Day(), 0, 0, 0, 0, time.UTC)
+		build.CompletedDate = &today
+	}
+
 	if err := h.buildRepo.CreateBuild(build); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to create build",
 		})
 	}
 
+	// A new build has no tasks yet
+	response := build.ToResponse()
+	response.ApplyTaskProgress(models.TaskProgress{})
+
 	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
 		"message": "Build created successfully",
-		"build":   build.ToResponse(),
+		"build":   response,
 	})
 }
 
-// GetBuilds retrieves all builds for the authenticated user
-func (h *BuildsHandler) GetBuilds(c *fiber.Ctx) error {
-	userID, ok := c.Locals("userID").(string)
-	if !ok {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "User not authenticated",
-		})
+// parseBuildFilter reads the filters and sort of a build listing from the query string
+func parseBuildFilter(c *fiber.Ctx) (database.BuildFilter, *fiber.Error) {
+	filter := database.BuildFilter{
+		Tags:      queryList(c, "tags"),
+		Series:    c.Query("series"),
+		Character: c.Query("character"),
+		Search:    c.Query("search"),
+		Sort:      c.Query("sort"),
 	}
 
-	userUUID, err := uuid.Parse(userID)
-	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid user ID",
-		})
+	for _, status := range queryList(c, "status") {
+		if !models.IsValidStatus(status) {
+			return filter, fiber.NewError(fiber.StatusBadRequest, "Invalid status. Must be one of: idea, sourcing, wip, complete, on_hold, cancelled")
+		}
+		filter.Statuses = append(filter.Statuses, models.BuildStatus(status))
 	}
 
-	// Parse query parameters
-	limit := 20
-	offset := 0
-	status := c.Query("status")
-	priority := c.Query("priority")
-	search := c.Query("search")
-	upcoming := c.Query("upcoming")
+	switch match := c.Query("tag_match", "any"); match {
+	case "any", "all":
+		filter.MatchAllTags = match == "all"
+	default:
+		return filter, fiber.NewError(fiber.StatusBadRequest, "Invalid tag_match. Must be one of: any, all")
+	}
 
-	if limitStr := c.Query("limit"); limitStr != "" {
-		if parsedLimit, err := strconv.Atoi(limitStr); err == nil && parsedLimit > 0 && parsedLimit <= 100 {
-			limit = parsedLimit
+	// priority is shorthand for an exact priority_min and priority_max
+	priorities := []struct {
+		param  string
+		target **int
+	}{
+		{"priority", &filter.MinPriority},
+		{"priority_min", &filter.MinPriority},
+		{"priority_max", &filter.MaxPriority},
+	}
+	for _, p := range priorities {
+		value, ferr := queryInt(c, p.param)
+		if ferr != nil {
+			return filter, ferr
+		}
+		if value == nil {
+			continue
+		}
+		if *value < 1 || *value > 5 {
+			return filter, fiber.NewError(fiber.StatusBadRequest, "Invalid "+p.param+". Must be between 1 and 5")
+		}
+		*p.target = value
+		if p.param == "priority" {
+			filter.MaxPriority = value
 		}
 	}
 
-	if offsetStr := c.Query("offset"); offsetStr != "" {
-		if parsedOffset, err := strconv.Atoi(offsetStr); err == nil && parsedOffset >= 0 {
-			offset = parsedOffset
+	dates := []struct {
+		param  string
+		target **time.Time
+	}{
+		{"start_from", &filter.StartFrom},
+		{"start_to", &filter.StartTo},
+		{"target_from", &filter.TargetFrom},
+		{"target_to", &filter.TargetTo},
+	}
+	for _, d := range dates {
+		value, ferr := queryDate(c, d.param)
+		if ferr != nil {
+			return filter, ferr
 		}
+		*d.target = value
 	}
 
-	var builds []*models.Build
-	var buildsErr error
-
-	if search != "" {
-		builds, buildsErr = h.buildRepo.SearchBuilds(userUUID, search, limit, offset)
-	} else if status != "" {
-		if !models.IsValidStatus(status) {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error": "Invalid status. Must be one of: idea, sourcing, wip, complete, on_hold, cancelled",
-			})
-		}
-		builds, buildsErr = h.buildRepo.GetBuildsByStatus(userUUID, models.BuildStatus(status), limit, offset)
-	} else if priority != "" {
-		if parsedPriority, err := strconv.Atoi(priority); err == nil && parsedPriority >= 1 && parsedPriority <= 5 {
-			builds, buildsErr = h.buildRepo.GetBuildsByPriority(userUUID, parsedPriority, limit, offset)
-		} else {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error": "Invalid priority. Must be between 1 and 5",
-			})
-		}
-	} else if upcoming != "" {
+	if upcoming := c.Query("upcoming"); upcoming != "" {
 		days := 30 // Default to 30 days
 		if parsedDays, err := strconv.Atoi(upcoming); err == nil && parsedDays > 0 {
 			days = parsedDays
 		}
-		builds, buildsErr = h.buildRepo.GetUpcomingBuilds(userUUID, days, limit, offset)
-	} else {
-		builds, buildsErr = h.buildRepo.GetBuildsByUserID(userUUID, limit, offset)
+		filter.DueWithinDays = &days
 	}
 
-	if buildsErr != nil {
+	switch budget := c.Query("budget"); budget {
+	case "":
+	case "over", "under":
+		over := budget == "over"
+		filter.OverBudget = &over
+	default:
+		return filter, fiber.NewError(fiber.StatusBadRequest, "Invalid budget. Must be one of: over, under")
+	}
+
+	return filter, nil
+}
+
+// GetBuilds retrieves the authenticated user's builds matching any mix of filters
+func (h *BuildsHandler) GetBuilds(c *fiber.Ctx) error {
+	principal, err := middleware.CurrentPrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	filter, ferr := parseBuildFilter(c)
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{
+			"error": ferr.Message,
+		})
+	}
+
+	limit, after, ferr := parseCursorPage(c)
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{
+			"error": ferr.Message,
+		})
+	}
+
+	builds, next, err := h.buildRepo.ListBuilds(principal.UserID, filter, after, limit)
+	if err != nil {
+		if ferr := listError(err, database.BuildSortKeys()); ferr != nil {
+			return c.Status(ferr.Code).JSON(fiber.Map{
+				"error": ferr.Message,
+			})
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to retrieve builds",
 		})
 	}
 
-	// Convert to response format
-	var response []models.BuildResponse
-	for _, build := range builds {
-		response = append(response, build.ToResponse())
+	total, err := h.buildRepo.CountBuilds(principal.UserID, filter)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to retrieve builds",
+		})
 	}
 
-	// Get total count for pagination
-	totalCount, err := h.buildRepo.GetBuildCount(userUUID)
+	// Convert to response format
+	response, err := h.toResponses(builds)
 	if err != nil {
-		// Log error but don't fail the request
-		totalCount = len(builds)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to retrieve builds",
+		})
 	}
 
 	return c.JSON(fiber.Map{
 		"builds":      response,
-		"total_count": totalCount,
+		"total":       total,
 		"limit":       limit,
-		"offset":      offset,
+		"next_cursor": nextCursor(next),
 	})
 }
 
 // GetBuild retrieves a specific build by ID
 func (h *BuildsHandler) GetBuild(c *fiber.Ctx) error {
-	userID, ok := c.Locals("userID").(string)
-	if !ok {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "User not authenticated",
-		})
-	}
-
-	userUUID, err := uuid.Parse(userID)
+	principal, err := middleware.CurrentPrincipal(c)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid user ID",
-		})
+		return err
 	}
 
 	buildIDStr := c.Params("id")
@@ -237,31 +346,45 @@ func (h *BuildsHandler) GetBuild(c *fiber.Ctx) error {
 	}
 
 	// Check if the build belongs to the authenticated user
-	if build.UserID != userUUID {
+	if build.UserID != principal.UserID {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
 			"error": "Access denied",
 		})
 	}
 
+	responses, err := h.toResponses([]*models.Build{build})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to retrieve build",
+		})
+	}
+	response := responses[0]
+
+	// Optionally expand the linked pieces (?include=pieces)
+	if includes(c.Query("include"), "pieces") {
+		buildPieces, err := h.buildPieceRepo.GetBuildPieces(build.ID)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to retrieve build pieces",
+			})
+		}
+		response.Pieces = make([]models.BuildPieceResponse, 0, len(buildPieces))
+		for _, bp := range buildPieces {
+			signPieceImages(h.blobs, bp.Piece)
+			response.Pieces = append(response.Pieces, bp.ToResponse())
+		}
+	}
+
 	return c.JSON(fiber.Map{
-		"build": build.ToResponse(),
+		"build": response,
 	})
 }
 
 // UpdateBuild updates an existing build
 func (h *BuildsHandler) UpdateBuild(c *fiber.Ctx) error {
-	userID, ok := c.Locals("userID").(string)
-	if !ok {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "User not authenticated",
-		})
-	}
-
-	userUUID, err := uuid.Parse(userID)
+	principal, err := middleware.CurrentPrincipal(c)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid user ID",
-		})
+		return err
 	}
 
 	buildIDStr := c.Params("id")
@@ -280,17 +403,15 @@ func (h *BuildsHandler) UpdateBuild(c *fiber.Ctx) error {
 		})
 	}
 
-	if existingBuild.UserID != userUUID {
+	if existingBuild.UserID != principal.UserID {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
 			"error": "Access denied",
 		})
 	}
 
 	var req models.UpdateBuildRequest
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid request body",
-		})
+	if err := parseBody(c, &req); err != nil {
+		return err
 	}
 
 	// Update fields if provided
@@ -306,22 +427,13 @@ func (h *BuildsHandler) UpdateBuild(c *fiber.Ctx) error {
 	if req.Series != nil {
 		existingBuild.Series = req.Series
 	}
-	if req.Status != nil {
-		if !models.IsValidStatus(*req.Status) {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error": "Invalid status. Must be one of: idea, sourcing, wip, complete, on_hold, cancelled",
-			})
-		}
-		existingBuild.Status = models.BuildStatus(*req.Status)
-	}
 	if req.Priority != nil {
 		existingBuild.Priority = req.Priority
 	}
-	if req.Budget != nil {
-		existingBuild.Budget = req.Budget
-	}
-	if req.Spent != nil {
-		existingBuild.Spent = req.Spent
+	if ferr := applyBuildMoney(existingBuild, req.Currency, req.Budget); ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{
+			"error": ferr.Message,
+		})
 	}
 	if req.StartDate != nil {
 		if *req.StartDate != "" {
@@ -349,18 +461,8 @@ func (h *BuildsHandler) UpdateBuild(c *fiber.Ctx) error {
 			existingBuild.TargetDate = nil
 		}
 	}
-	if req.CompletedDate != nil {
-		if *req.CompletedDate != "" {
-			parsedDate, err := time.Parse("2006-01-02", *req.CompletedDate)
-			if err != nil {
-				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-					"error": "Invalid completed date format. Use YYYY-MM-DD",
-				})
-			}
-			existingBuild.CompletedDate = &parsedDate
-		} else {
-			existingBuild.CompletedDate = nil
-		}
+	if fe := models.CheckDateOrder(existingBuild.StartDate, existingBuild.TargetDate, "start_date", "target_date"); fe != nil {
+		return &models.ValidationError{Fields: []models.FieldError{*fe}}
 	}
 	if req.Tags != nil {
 		existingBuild.Tags = req.Tags
@@ -369,36 +471,112 @@ func (h *BuildsHandler) UpdateBuild(c *fiber.Ctx) error {
 		existingBuild.Notes = req.Notes
 	}
 
+	// Changing status follows the allowed transitions and sets or clears the
+	// completed date, which may then be corrected on a complete build
+	var statusChange *models.BuildStatusChange
+	if req.Status != nil {
+		statusChange, err = existingBuild.TransitionTo(models.BuildStatus(*req.Status), req.StatusNote, time.Now())
+		if err != nil {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error":        "Can't change status from " + string(existingBuild.Status) + " to " + *req.Status,
+				"allowed_next": existingBuild.Status.Transitions(),
+			})
+		}
+	}
+	if req.CompletedDate != nil {
+		if existingBuild.Status != models.BuildStatusComplete {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Only complete builds have a completed date",
+			})
+		}
+		parsedDate, err := time.Parse("2006-01-02", *req.CompletedDate)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid completed date format. Use YYYY-MM-DD",
+			})
+		}
+		existingBuild.CompletedDate = &parsedDate
+	}
+
 	existingBuild.UpdatedAt = time.Now()
 
-	if err := h.buildRepo.UpdateBuild(existingBuild); err != nil {
+	if err := h.buildRepo.UpdateBuild(existingBuild, statusChange); err != nil {
+		if errors.Is(err, database.ErrBuildStatusChanged) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": "Build status changed while updating. Fetch the build and try again",
+			})
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to update build",
 		})
 	}
 
+	response, err := h.toResponses([]*models.Build{existingBuild})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to retrieve build",
+		})
+	}
+
 	return c.JSON(fiber.Map{
 		"message": "Build updated successfully",
-		"build":   existingBuild.ToResponse(),
+		"build":   response[0],
 	})
 }
 
-// DeleteBuild deletes a build
-func (h *BuildsHandler) DeleteBuild(c *fiber.Ctx) error {
-	userID, ok := c.Locals("userID").(string)
-	if !ok {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "User not authenticated",
-		})
+// GetBuildHistory retrieves a build's status changes, oldest first, with how
+// long the build spent in each status
+func (h *BuildsHandler) GetBuildHistory(c *fiber.Ctx) error {
+	principal, err := middleware.CurrentPrincipal(c)
+	if err != nil {
+		return err
 	}
 
-	userUUID, err := uuid.Parse(userID)
+	buildID, err := uuid.Parse(c.Params("id"))
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid user ID",
+			"error": "Invalid build ID",
+		})
+	}
+
+	build, err := h.buildRepo.GetBuildByID(buildID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Build not found",
+		})
+	}
+
+	if build.UserID != principal.UserID {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Access denied",
 		})
 	}
 
+	history, err := h.buildRepo.GetBuildStatusHistory(build.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to retrieve build history",
+		})
+	}
+
+	periods, totals := models.BuildStatusPeriods(history, time.Now())
+
+	return c.JSON(fiber.Map{
+		"build_id":       build.ID,
+		"status":         build.Status,
+		"allowed_next":   build.Status.Transitions(),
+		"history":        periods,
+		"time_in_status": totals,
+	})
+}
+
+// DeleteBuild deletes a build
+func (h *BuildsHandler) DeleteBuild(c *fiber.Ctx) error {
+	principal, err := middleware.CurrentPrincipal(c)
+	if err != nil {
+		return err
+	}
+
 	buildIDStr := c.Params("id")
 	buildID, err := uuid.Parse(buildIDStr)
 	if err != nil {
@@ -407,7 +585,7 @@ func (h *BuildsHandler) DeleteBuild(c *fiber.Ctx) error {
 		})
 	}
 
-	if err := h.buildRepo.DeleteBuild(buildID, userUUID); err != nil {
+	if err := h.buildRepo.DeleteBuild(buildID, principal.UserID); err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"error": "Build not found or access denied",
 		})
@@ -420,49 +598,17 @@ func (h *BuildsHandler) DeleteBuild(c *fiber.Ctx) error {
 
 // GetBuildStats retrieves build statistics for the authenticated user
 func (h *BuildsHandler) GetBuildStats(c *fiber.Ctx) error {
-	userID, ok := c.Locals("userID").(string)
-	if !ok {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "User not authenticated",
-		})
-	}
-
-	userUUID, err := uuid.Parse(userID)
+	principal, err := middleware.CurrentPrincipal(c)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid user ID",
-		})
+		return err
 	}
 
-	// Get total count
-	totalCount, err := h.buildRepo.GetBuildCount(userUUID)
+	stats, err := h.buildRepo.GetBuildStats(principal.UserID)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to get build statistics",
 		})
 	}
 
-	// Get counts by status
-	ideaCount, _ := h.buildRepo.GetBuildsByStatus(userUUID, models.BuildStatusIdea, 1, 0)
-	sourcingCount, _ := h.buildRepo.GetBuildsByStatus(userUUID, models.BuildStatusSourcing, 1, 0)
-	wipCount, _ := h.buildRepo.GetBuildsByStatus(userUUID, models.BuildStatusWIP, 1, 0)
-	completeCount, _ := h.buildRepo.GetBuildsByStatus(userUUID, models.BuildStatusComplete, 1, 0)
-	onHoldCount, _ := h.buildRepo.GetBuildsByStatus(userUUID, models.BuildStatusOnHold, 1, 0)
-	cancelledCount, _ := h.buildRepo.GetBuildsByStatus(userUUID, models.BuildStatusCancelled, 1, 0)
-
-	// Get upcoming builds (next 30 days)
-	upcomingBuilds, _ := h.buildRepo.GetUpcomingBuilds(userUUID, 30, 10, 0)
-
-	return c.JSON(fiber.Map{
-		"total_builds": totalCount,
-		"by_status": fiber.Map{
-			"idea":      len(ideaCount),
-			"sourcing":  len(sourcingCount),
-			"wip":       len(wipCount),
-			"complete":  len(completeCount),
-			"on_hold":   len(onHoldCount),
-			"cancelled": len(cancelledCount),
-		},
-		"upcoming_builds": len(upcomingBuilds),
-	})
+	return c.JSON(stats)
 }
@@ -0,0 +1,292 @@
+package handlers
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"kyarafit-backend/database"
+	"kyarafit-backend/middleware"
+	"kyarafit-backend/models"
+)
+
+type CategoriesHandler struct {
+	categoryRepo *database.CategoryRepository
+}
+
+func NewCategoriesHandler(categoryRepo *database.CategoryRepository) *CategoriesHandler {
+	return &CategoriesHandler{categoryRepo: categoryRepo}
+}
+
+// findCategory parses a category ID and loads the category if it belongs to the user
+func (h *CategoriesHandler) findCategory(idStr string, userUUID uuid.UUID) (*models.Category, *fiber.Error) {
+	categoryID, err := uuid.Parse(idStr)
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Invalid category ID")
+	}
+	category, err := h.categoryRepo.GetCategoryByID(categoryID)
+	if err != nil {
+		if errors.Is(err, database.ErrCategoryNotFound) {
+			return nil, fiber.NewError(fiber.StatusNotFound, "Category not found")
+		}
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "Failed to retrieve category")
+	}
+	if category.UserID != userUUID {
+		return nil, fiber.NewError(fiber.StatusForbidden, "Access denied")
+	}
+	return category, nil
+}
+
+// resolveParent parses a parent category ID and checks the category belongs to
+// the user. An empty string resolves to no parent.
+func (h *CategoriesHandler) resolveParent(idStr string, userUUID uuid.UUID) (*uuid.UUID, *fiber.Error) {
+	if idStr == "" {
+		return nil, nil
+	}
+	parent, ferr := h.findCategory(idStr, userUUID)
+	if ferr != nil {
+		switch ferr.Code {
+		case fiber.StatusBadRequest:
+			return nil, fiber.NewError(fiber.StatusBadRequest, "Invalid parent category ID")
+		case fiber.StatusNotFound:
+			return nil, fiber.NewError(fiber.StatusNotFound, "Parent category not found")
+		}
+		return nil, ferr
+	}
+	return &parent.ID, nil
+}
+
+// categoryError converts an error saving a category to a response error
+func categoryError(err error, fallback string) *fiber.Error {
+	switch {
+	case errors.Is(err, database.ErrCategoryNotFound):
+		return fiber.NewError(fiber.StatusNotFound, "Category not found")
+	case errors.Is(err, database.ErrCategoryExists):
+		return fiber.NewError(fiber.StatusConflict, "A category with this name already exists. Merge into it instead")
+	case errors.Is(err, database.ErrCategoryCycle):
+		return fiber.NewError(fiber.StatusBadRequest, "A category can't be nested under itself or its subcategories")
+	default:
+		return fiber.NewError(fiber.StatusInternalServerError, fallback)
+	}
+}
+
+// GetCategories retrieves the authenticated user's categories
+func (h *CategoriesHandler) GetCategories(c *fiber.Ctx) error {
+	principal, err := middleware.CurrentPrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	categories, err := h.categoryRepo.ListCategories(principal.UserID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to retrieve categories",
+		})
+	}
+	if categories == nil {
+		categories = []*models.Category{}
+	}
+
+	return c.JSON(fiber.Map{
+		"categories": categories,
+		"count":      len(categories),
+	})
+}
+
+// CreateCategory adds a category to the authenticated user's vocabulary
+func (h *CategoriesHandler) CreateCategory(c *fiber.Ctx) error {
+	principal, err := middleware.CurrentPrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	var req models.CreateCategoryRequest
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+
+	category := &models.Category{
+		ID:        uuid.New(),
+		UserID:    principal.UserID,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+
+	var ferr *fiber.Error
+	if category.Name, ferr = parseVocabularyName(req.Name); ferr == nil && req.Color != nil {
+		category.Color, ferr = parseColor(*req.Color)
+	}
+	if ferr == nil && req.ParentID != nil {
+		category.ParentID, ferr = h.resolveParent(*req.ParentID, principal.UserID)
+	}
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{
+			"error": ferr.Message,
+		})
+	}
+
+	if err := h.categoryRepo.CreateCategory(category); err != nil {
+		ferr := categoryError(err, "Failed to create category")
+		return c.Status(ferr.Code).JSON(fiber.Map{
+			"error": ferr.Message,
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message":  "Category created successfully",
+		"category": category,
+	})
+}
+
+// GetCategory retrieves a specific category by ID
+func (h *CategoriesHandler) GetCategory(c *fiber.Ctx) error {
+	principal, err := middleware.CurrentPrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	category, ferr := h.findCategory(c.Params("id"), principal.UserID)
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{
+			"error": ferr.Message,
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"category": category,
+	})
+}
+
+// UpdateCategory updates a category. Renaming it renames the category of all
+// its pieces in the same transaction.
+func (h *CategoriesHandler) UpdateCategory(c *fiber.Ctx) error {
+	principal, err := middleware.CurrentPrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	category, ferr := h.findCategory(c.Params("id"), principal.UserID)
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{
+			"error": ferr.Message,
+		})
+	}
+
+	var req models.UpdateCategoryRequest
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+
+	previousName := category.Name
+	if req.Name != nil {
+		category.Name, ferr = parseVocabularyName(*req.Name)
+	}
+	if ferr == nil && req.Color != nil {
+		category.Color, ferr = parseColor(*req.Color)
+	}
+	if ferr == nil && req.ParentID != nil {
+		category.ParentID, ferr = h.resolveParent(*req.ParentID, principal.UserID)
+	}
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{
+			"error": ferr.Message,
+		})
+	}
+
+	category.UpdatedAt = time.Now()
+
+	moved, err := h.categoryRepo.UpdateCategory(category, previousName)
+	if err != nil {
+		ferr := categoryError(err, "Failed to update category")
+		return c.Status(ferr.Code).JSON(fiber.Map{
+			"error": ferr.Message,
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message":        "Category updated successfully",
+		"category":       category,
+		"pieces_updated": moved,
+	})
+}
+
+// DeleteCategory deletes a category, clearing it from its pieces
+func (h *CategoriesHandler) DeleteCategory(c *fiber.Ctx) error {
+	principal, err := middleware.CurrentPrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	category, ferr := h.findCategory(c.Params("id"), principal.UserID)
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{
+			"error": ferr.Message,
+		})
+	}
+
+	if err := h.categoryRepo.DeleteCategory(category); err != nil {
+		ferr := categoryError(err, "Failed to delete category")
+		return c.Status(ferr.Code).JSON(fiber.Map{
+			"error": ferr.Message,
+		})
+	}
+
+	return c.Status(fiber.StatusNoContent).JSON(fiber.Map{
+		"message": "Category deleted successfully",
+	})
+}
+
+// MergeCategory moves every piece and subcategory of a category into another
+// one and deletes it, in one transaction
+func (h *CategoriesHandler) MergeCategory(c *fiber.Ctx) error {
+	principal, err := middleware.CurrentPrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	source, ferr := h.findCategory(c.Params("id"), principal.UserID)
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{
+			"error": ferr.Message,
+		})
+	}
+
+	var req models.MergeRequest
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+
+	target, ferr := h.findCategory(req.Into, principal.UserID)
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{
+			"error": ferr.Message,
+		})
+	}
+	if target.ID == source.ID {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "A category can't be merged into itself",
+		})
+	}
+
+	moved, err := h.categoryRepo.MergeCategory(source, target)
+	if err != nil {
+		ferr := categoryError(err, "Failed to merge categories")
+		return c.Status(ferr.Code).JSON(fiber.Map{
+			"error": ferr.Message,
+		})
+	}
+
+	merged, err := h.categoryRepo.GetCategoryByID(target.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to retrieve category",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message":        "Categories merged successfully",
+		"category":       merged,
+		"pieces_updated": moved,
+	})
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"kyarafit-backend/middleware"
+	"kyarafit-backend/models"
+)
+
+// GetPackingList builds a convention's packing list from the pieces of its
+// scheduled builds, grouped by category
+func (h *ConventionsHandler) GetPackingList(c *fiber.Ctx) error {
+	principal, err := middleware.CurrentPrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	convention, ferr := h.ownedConvention(c, principal.UserID)
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{
+			"error": ferr.Message,
+		})
+	}
+
+	items, err := h.conventionRepo.GetPackingList(convention.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to retrieve packing list",
+		})
+	}
+
+	packedCount := 0
+	for _, item := range items {
+		signPieceImages(h.blobs, item.Piece)
+		if item.Packed {
+			packedCount++
+		}
+	}
+
+	return c.JSON(fiber.Map{
+		"convention_id": convention.ID,
+		"categories":    models.GroupPackingItems(items),
+		"total_count":   len(items),
+		"packed_count":  packedCount,
+	})
+}
+
+// UpdatePackingItem ticks a piece on a convention's packing list on or off
+func (h *ConventionsHandler) UpdatePackingItem(c *fiber.Ctx) error {
+	principal, err := middleware.CurrentPrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	convention, ferr := h.ownedConvention(c, principal.UserID)
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{
+			"error": ferr.Message,
+		})
+	}
+
+	pieceID, err := uuid.Parse(c.Params("pieceId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid piece ID",
+		})
+	}
+
+	var req models.SetPackedRequest
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+
+	if err := h.conventionRepo.SetPacked(convention.ID, pieceID, req.Packed); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Piece is not on this convention's packing list",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message":  "Packing list updated successfully",
+		"piece_id": pieceID,
+		"packed":   req.Packed,
+	})
+}
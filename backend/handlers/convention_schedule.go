@@ -0,0 +1,352 @@
+package handlers
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"kyarafit-backend/database"
+	"kyarafit-backend/middleware"
+	"kyarafit-backend/models"
+)
+
+// parseClock parses an "HH:MM" time of day and returns it normalized
+func parseClock(value string) (string, bool) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return "", false
+	}
+	return t.Format("15:04"), true
+}
+
+// resolveBuild parses a build ID and checks the build belongs to the user.
+// An empty string resolves to no build.
+func (h *ConventionsHandler) resolveBuild(idStr string, userUUID uuid.UUID) (*uuid.UUID, *fiber.Error) {
+	if idStr == "" {
+		return nil, nil
+	}
+	buildID, err := uuid.Parse(idStr)
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Invalid build ID")
+	}
+	build, err := h.buildRepo.GetBuildByID(buildID)
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusNotFound, "Build not found")
+	}
+	if build.UserID != userUUID {
+		return nil, fiber.NewError(fiber.StatusForbidden, "Access denied")
+	}
+	return &build.ID, nil
+}
+
+// resolveCoord parses a coord ID and checks the coord belongs to the user.
+// An empty string resolves to no coord.
+func (h *ConventionsHandler) resolveCoord(idStr string, userUUID uuid.UUID) (*uuid.UUID, *fiber.Error) {
+	if idStr == "" {
+		return nil, nil
+	}
+	coordID, err := uuid.Parse(idStr)
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Invalid coord ID")
+	}
+	coord, err := h.coordRepo.GetCoordByID(coordID)
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusNotFound, "Coord not found")
+	}
+	if coord.UserID != userUUID {
+		return nil, fiber.NewError(fiber.StatusForbidden, "Access denied")
+	}
+	return &coord.ID, nil
+}
+
+// validateScheduleEntry checks an entry against its convention
+func validateScheduleEntry(convention *models.Convention, entry *models.ScheduleEntry) *fiber.Error {
+	if !entry.EntryType.Valid() {
+		return fiber.NewError(fiber.StatusBadRequest, "Entry type must be cosplay, photoshoot, meetup or other")
+	}
+	if !convention.Covers(entry.Day) {
+		return fiber.NewError(fiber.StatusBadRequest, "Day must fall within the convention dates")
+	}
+	if entry.EndTime <= entry.StartTime {
+		return fiber.NewError(fiber.StatusBadRequest, "End time must be after start time")
+	}
+	if entry.EntryType == models.ScheduleEntryCosplay && !entry.Outfit() {
+		return fiber.NewError(fiber.StatusBadRequest, "A cosplay entry needs a build_id or coord_id")
+	}
+	return nil
+}
+
+// GetSchedule retrieves a convention's schedule grouped by day
+func (h *ConventionsHandler) GetSchedule(c *fiber.Ctx) error {
+	principal, err := middleware.CurrentPrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	convention, ferr := h.ownedConvention(c, principal.UserID)
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{
+			"error": ferr.Message,
+		})
+	}
+
+	entries, err := h.conventionRepo.GetScheduleEntries(convention.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to retrieve convention schedule",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"convention_id": convention.ID,
+		"timezone":      convention.Timezone,
+		"schedule":      convention.Schedule(entries),
+	})
+}
+
+// CreateScheduleEntry adds an entry to a convention's schedule
+func (h *ConventionsHandler) CreateScheduleEntry(c *fiber.Ctx) error {
+	principal, err := middleware.CurrentPrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	convention, ferr := h.ownedConvention(c, principal.UserID)
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{
+			"error": ferr.Message,
+		})
+	}
+
+	var req models.CreateScheduleEntryRequest
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+
+	day, err := time.Parse("2006-01-02", req.Day)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid day format. Use YYYY-MM-DD",
+		})
+	}
+	startTime, ok := parseClock(req.StartTime)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid start time format. Use HH:MM",
+		})
+	}
+	endTime, ok := parseClock(req.EndTime)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid end time format. Use HH:MM",
+		})
+	}
+
+	entry := &models.ScheduleEntry{
+		ID:           uuid.New(),
+		ConventionID: convention.ID,
+		EntryType:    models.ScheduleEntryCosplay,
+		Day:          day,
+		StartTime:    startTime,
+		EndTime:      endTime,
+		Title:        req.Title,
+		Location:     req.Location,
+		Notes:        req.Notes,
+		CreatedAt:    time.Now(),
+		UpdatedAt:    time.Now(),
+	}
+	if req.EntryType != nil {
+		entry.EntryType = models.ScheduleEntryType(*req.EntryType)
+	}
+
+	if req.BuildID != nil {
+		buildID, ferr := h.resolveBuild(*req.BuildID, principal.UserID)
+		if ferr != nil {
+			return c.Status(ferr.Code).JSON(fiber.Map{
+				"error": ferr.Message,
+			})
+		}
+		entry.BuildID = buildID
+	}
+	if req.CoordID != nil {
+		coordID, ferr := h.resolveCoord(*req.CoordID, principal.UserID)
+		if ferr != nil {
+			return c.Status(ferr.Code).JSON(fiber.Map{
+				"error": ferr.Message,
+			})
+		}
+		entry.CoordID = coordID
+	}
+
+	if ferr := validateScheduleEntry(convention, entry); ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{
+			"error": ferr.Message,
+		})
+	}
+
+	if err := h.conventionRepo.CreateScheduleEntry(entry); err != nil {
+		if errors.Is(err, database.ErrScheduleConflict) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": "Another build or coord is already scheduled in this time slot",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to create schedule entry",
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message": "Schedule entry created successfully",
+		"entry":   entry.ToResponse(),
+	})
+}
+
+// UpdateScheduleEntry updates an entry on a convention's schedule
+func (h *ConventionsHandler) UpdateScheduleEntry(c *fiber.Ctx) error {
+	principal, err := middleware.CurrentPrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	convention, ferr := h.ownedConvention(c, principal.UserID)
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{
+			"error": ferr.Message,
+		})
+	}
+
+	entryID, err := uuid.Parse(c.Params("entryId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid schedule entry ID",
+		})
+	}
+
+	existingEntry, err := h.conventionRepo.GetScheduleEntryByID(convention.ID, entryID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Schedule entry not found",
+		})
+	}
+
+	var req models.UpdateScheduleEntryRequest
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+
+	// Update fields if provided
+	if req.EntryType != nil {
+		existingEntry.EntryType = models.ScheduleEntryType(*req.EntryType)
+	}
+	if req.Day != nil {
+		day, err := time.Parse("2006-01-02", *req.Day)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid day format. Use YYYY-MM-DD",
+			})
+		}
+		existingEntry.Day = day
+	}
+	if req.StartTime != nil {
+		startTime, ok := parseClock(*req.StartTime)
+		if !ok {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid start time format. Use HH:MM",
+			})
+		}
+		existingEntry.StartTime = startTime
+	}
+	if req.EndTime != nil {
+		endTime, ok := parseClock(*req.EndTime)
+		if !ok {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid end time format. Use HH:MM",
+			})
+		}
+		existingEntry.EndTime = endTime
+	}
+	if req.BuildID != nil {
+		buildID, ferr := h.resolveBuild(*req.BuildID, principal.UserID)
+		if ferr != nil {
+			return c.Status(ferr.Code).JSON(fiber.Map{
+				"error": ferr.Message,
+			})
+		}
+		existingEntry.BuildID = buildID
+	}
+	if req.CoordID != nil {
+		coordID, ferr := h.resolveCoord(*req.CoordID, principal.UserID)
+		if ferr != nil {
+			return c.Status(ferr.Code).JSON(fiber.Map{
+				"error": ferr.Message,
+			})
+		}
+		existingEntry.CoordID = coordID
+	}
+	if req.Title != nil {
+		existingEntry.Title = req.Title
+	}
+	if req.Location != nil {
+		existingEntry.Location = req.Location
+	}
+	if req.Notes != nil {
+		existingEntry.Notes = req.Notes
+	}
+
+	if ferr := validateScheduleEntry(convention, existingEntry); ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{
+			"error": ferr.Message,
+		})
+	}
+
+	existingEntry.UpdatedAt = time.Now()
+
+	if err := h.conventionRepo.UpdateScheduleEntry(existingEntry); err != nil {
+		if errors.Is(err, database.ErrScheduleConflict) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": "Another build or coord is already scheduled in this time slot",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to update schedule entry",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Schedule entry updated successfully",
+		"entry":   existingEntry.ToResponse(),
+	})
+}
+
+// DeleteScheduleEntry removes an entry from a convention's schedule
+func (h *ConventionsHandler) DeleteScheduleEntry(c *fiber.Ctx) error {
+	principal, err := middleware.CurrentPrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	convention, ferr := h.ownedConvention(c, principal.UserID)
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{
+			"error": ferr.Message,
+		})
+	}
+
+	entryID, err := uuid.Parse(c.Params("entryId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid schedule entry ID",
+		})
+	}
+
+	if err := h.conventionRepo.DeleteScheduleEntry(convention.ID, entryID); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Schedule entry not found",
+		})
+	}
+
+	return c.Status(fiber.StatusNoContent).JSON(fiber.Map{
+		"message": "Schedule entry deleted successfully",
+	})
+}
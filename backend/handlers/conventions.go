@@ -0,0 +1,335 @@
+package handlers
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"kyarafit-backend/database"
+	"kyarafit-backend/middleware"
+	"kyarafit-backend/models"
+	"kyarafit-backend/storage"
+)
+
+type ConventionsHandler struct {
+	conventionRepo *database.ConventionRepository
+	buildRepo      *database.BuildRepository
+	taskRepo       *database.BuildTaskRepository
+	coordRepo      *database.CoordRepository
+	blobs          storage.BlobStore
+}
+
+func NewConventionsHandler(conventionRepo *database.ConventionRepository, buildRepo *database.BuildRepository, taskRepo *database.BuildTaskRepository, coordRepo *database.CoordRepository, blobs storage.BlobStore) *ConventionsHandler {
+	return &ConventionsHandler{
+		conventionRepo: conventionRepo,
+		buildRepo:      buildRepo,
+		taskRepo:       taskRepo,
+		coordRepo:      coordRepo,
+		blobs:          blobs,
+	}
+}
+
+// ownedConvention loads the convention named in the :id param and checks it belongs to the user
+func (h *ConventionsHandler) ownedConvention(c *fiber.Ctx, userUUID uuid.UUID) (*models.Convention, *fiber.Error) {
+	conventionID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Invalid convention ID")
+	}
+
+	convention, err := h.conventionRepo.GetConventionByID(conventionID)
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusNotFound, "Convention not found")
+	}
+
+	if convention.UserID != userUUID {
+		return nil, fiber.NewError(fiber.StatusForbidden, "Access denied")
+	}
+
+	return convention, nil
+}
+
+// CreateConvention creates a new convention
+func (h *ConventionsHandler) CreateConvention(c *fiber.Ctx) error {
+	principal, err := middleware.CurrentPrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	var req models.CreateConventionRequest
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+
+	startDate, err := time.Parse("2006-01-02", req.StartDate)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid start date format. Use YYYY-MM-DD",
+		})
+	}
+	endDate, err := time.Parse("2006-01-02", req.EndDate)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid end date format. Use YYYY-MM-DD",
+		})
+	}
+
+	timezone := "UTC"
+	if req.Timezone != nil {
+		timezone = *req.Timezone
+	}
+
+	convention := &models.Convention{
+		ID:                uuid.New(),
+		UserID:            principal.UserID,
+		Name:              req.Name,
+		Venue:             req.Venue,
+		City:              req.City,
+		StartDate:         startDate,
+		EndDate:           endDate,
+		Timezone:          timezone,
+		HotelName:         req.HotelName,
+		HotelAddress:      req.HotelAddress,
+		HotelConfirmation: req.HotelConfirmation,
+		Website:           req.Website,
+		Notes:             req.Notes,
+		CreatedAt:         time.Now(),
+		UpdatedAt:         time.Now(),
+	}
+
+	if err := h.conventionRepo.CreateConvention(convention); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to create convention",
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message":    "Convention created successfully",
+		"convention": convention.ToResponse(),
+	})
+}
+
+// GetConventions retrieves all conventions for the authenticated user
+func (h *ConventionsHandler) GetConventions(c *fiber.Ctx) error {
+	principal, err := middleware.CurrentPrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	limit, offset := parseLimitOffset(c)
+
+	conventions, err := h.conventionRepo.GetConventionsByUserID(principal.UserID, limit, offset)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to retrieve conventions",
+		})
+	}
+
+	totalCount, err := h.conventionRepo.GetConventionCount(principal.UserID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to retrieve conventions",
+		})
+	}
+
+	response := make([]models.ConventionResponse, 0, len(conventions))
+	for _, convention := range conventions {
+		response = append(response, convention.ToResponse())
+	}
+
+	return c.JSON(fiber.Map{
+		"conventions": response,
+		"total_count": totalCount,
+		"limit":       limit,
+		"offset":      offset,
+	})
+}
+
+// GetConvention retrieves a convention with its schedule grouped by day
+func (h *ConventionsHandler) GetConvention(c *fiber.Ctx) error {
+	principal, err := middleware.CurrentPrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	convention, ferr := h.ownedConvention(c, principal.UserID)
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{
+			"error": ferr.Message,
+		})
+	}
+
+	entries, err := h.conventionRepo.GetScheduleEntries(convention.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to retrieve convention schedule",
+		})
+	}
+
+	response := convention.ToResponse()
+	response.Schedule = convention.Schedule(entries)
+
+	return c.JSON(fiber.Map{
+		"convention": response,
+	})
+}
+
+// UpdateConvention updates an existing convention
+func (h *ConventionsHandler) UpdateConvention(c *fiber.Ctx) error {
+	principal, err := middleware.CurrentPrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	existingConvention, ferr := h.ownedConvention(c, principal.UserID)
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{
+			"error": ferr.Message,
+		})
+	}
+
+	var req models.UpdateConventionRequest
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+
+	// Update fields if provided
+	if req.Name != nil {
+		existingConvention.Name = *req.Name
+	}
+	if req.Venue != nil {
+		existingConvention.Venue = req.Venue
+	}
+	if req.City != nil {
+		existingConvention.City = req.City
+	}
+	if req.StartDate != nil {
+		startDate, err := time.Parse("2006-01-02", *req.StartDate)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid start date format. Use YYYY-MM-DD",
+			})
+		}
+		existingConvention.StartDate = startDate
+	}
+	if req.EndDate != nil {
+		endDate, err := time.Parse("2006-01-02", *req.EndDate)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid end date format. Use YYYY-MM-DD",
+			})
+		}
+		existingConvention.EndDate = endDate
+	}
+	if fe := models.CheckDateOrder(&existingConvention.StartDate, &existingConvention.EndDate, "start_date", "end_date"); fe != nil {
+		return &models.ValidationError{Fields: []models.FieldError{*fe}}
+	}
+	if req.Timezone != nil {
+		existingConvention.Timezone = *req.Timezone
+	}
+	if req.HotelName != nil {
+		existingConvention.HotelName = req.HotelName
+	}
+	if req.HotelAddress != nil {
+		existingConvention.HotelAddress = req.HotelAddress
+	}
+	if req.HotelConfirmation != nil {
+		existingConvention.HotelConfirmation = req.HotelConfirmation
+	}
+	if req.Website != nil {
+		existingConvention.Website = req.Website
+	}
+	if req.Notes != nil {
+		existingConvention.Notes = req.Notes
+	}
+
+	existingConvention.UpdatedAt = time.Now()
+
+	if err := h.conventionRepo.UpdateConvention(existingConvention); err != nil {
+		if errors.Is(err, database.ErrScheduleOutsideDates) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": "The schedule has entries outside the new dates. Move or remove them first",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to update convention",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message":    "Convention updated successfully",
+		"convention": existingConvention.ToResponse(),
+	})
+}
+
+// DeleteConvention deletes a convention and its schedule
+func (h *ConventionsHandler) DeleteConvention(c *fiber.Ctx) error {
+	principal, err := middleware.CurrentPrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	conventionID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid convention ID",
+		})
+	}
+
+	if err := h.conventionRepo.DeleteConvention(conventionID, principal.UserID); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Convention not found or access denied",
+		})
+	}
+
+	return c.Status(fiber.StatusNoContent).JSON(fiber.Map{
+		"message": "Convention deleted successfully",
+	})
+}
+
+// GetAtRiskBuilds lists the builds scheduled for a convention that won't be
+// complete by its start date
+func (h *ConventionsHandler) GetAtRiskBuilds(c *fiber.Ctx) error {
+	principal, err := middleware.CurrentPrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	convention, ferr := h.ownedConvention(c, principal.UserID)
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{
+			"error": ferr.Message,
+		})
+	}
+
+	atRisk, err := h.conventionRepo.GetAtRiskBuilds(convention.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to retrieve at-risk builds",
+		})
+	}
+
+	ids := make([]uuid.UUID, 0, len(atRisk))
+	for _, build := range atRisk {
+		ids = append(ids, build.Build.ID)
+	}
+	progress, err := h.taskRepo.GetTaskProgress(ids)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to retrieve at-risk builds",
+		})
+	}
+
+	response := make([]models.AtRiskBuildResponse, 0, len(atRisk))
+	for _, build := range atRisk {
+		buildResponse := build.ToResponse()
+		buildResponse.Build.ApplyTaskProgress(progress[build.Build.ID])
+		response = append(response, buildResponse)
+	}
+
+	return c.JSON(fiber.Map{
+		"builds":           response,
+		"convention_start": convention.StartDate,
+		"total_count":      len(response),
+	})
+}
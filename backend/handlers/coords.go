@@ -0,0 +1,358 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"kyarafit-backend/database"
+	"kyarafit-backend/middleware"
+	"kyarafit-backend/models"
+	"kyarafit-backend/storage"
+)
+
+type CoordsHandler struct {
+	coordRepo *database.CoordRepository
+	pieceRepo *database.PieceRepository
+	buildRepo *database.BuildRepository
+	blobs     storage.BlobStore
+}
+
+func NewCoordsHandler(coordRepo *database.CoordRepository, pieceRepo *database.PieceRepository, buildRepo *database.BuildRepository, blobs storage.BlobStore) *CoordsHandler {
+	return &CoordsHandler{
+		coordRepo: coordRepo,
+		pieceRepo: pieceRepo,
+		buildRepo: buildRepo,
+		blobs:     blobs,
+	}
+}
+
+// toResponse converts a coord to its response format with signed image URLs
+// on its layers' pieces
+func (h *CoordsHandler) toResponse(coord *models.Coord) models.CoordResponse {
+	for _, layer := range coord.Layers {
+		signPieceImages(h.blobs, layer.Piece)
+	}
+	return coord.ToResponse()
+}
+
+// buildLayers converts requested layers into models and checks that every
+// referenced piece belongs to the user. Layers without a z_index are stacked
+// in the order they were given.
+func (h *CoordsHandler) buildLayers(reqLayers []models.CoordLayerRequest, userUUID uuid.UUID) ([]models.CoordLayer, *fiber.Error) {
+	layers := make([]models.CoordLayer, 0, len(reqLayers))
+	for i, reqLayer := range reqLayers {
+		pieceID, err := uuid.Parse(reqLayer.PieceID)
+		if err != nil {
+			return nil, fiber.NewError(fiber.StatusBadRequest, "Invalid piece ID in layers")
+		}
+
+		zIndex := i
+		if reqLayer.ZIndex != nil {
+			zIndex = *reqLayer.ZIndex
+		}
+
+		scale := 1.0
+		if reqLayer.Scale != nil {
+			if *reqLayer.Scale <= 0 {
+				return nil, fiber.NewError(fiber.StatusBadRequest, "Layer scale must be greater than 0")
+			}
+			scale = *reqLayer.Scale
+		}
+
+		if reqLayer.Rotation < -360 || reqLayer.Rotation > 360 {
+			return nil, fiber.NewError(fiber.StatusBadRequest, "Layer rotation must be between -360 and 360 degrees")
+		}
+
+		layers = append(layers, models.CoordLayer{
+			ID:        uuid.New(),
+			PieceID:   pieceID,
+			ZIndex:    zIndex,
+			PositionX: reqLayer.PositionX,
+			PositionY: reqLayer.PositionY,
+			Scale:     scale,
+			Rotation:  reqLayer.Rotation,
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+		})
+	}
+
+	coord := models.Coord{Layers: layers}
+	pieceIDs := coord.PieceIDs()
+	if len(pieceIDs) > 0 {
+		owned, err := h.pieceRepo.CountOwnedPieces(userUUID, pieceIDs)
+		if err != nil {
+			return nil, fiber.NewError(fiber.StatusInternalServerError, "Failed to verify layer pieces")
+		}
+		if owned != len(pieceIDs) {
+			return nil, fiber.NewError(fiber.StatusForbidden, "Every layer must use a piece from your closet")
+		}
+	}
+
+	return layers, nil
+}
+
+// resolveBuild parses a build ID and checks the build belongs to the user.
+// An empty string resolves to no build.
+func (h *CoordsHandler) resolveBuild(idStr string, userUUID uuid.UUID) (*uuid.UUID, *fiber.Error) {
+	if idStr == "" {
+		return nil, nil
+	}
+	buildID, err := uuid.Parse(idStr)
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Invalid build ID")
+	}
+	build, err := h.buildRepo.GetBuildByID(buildID)
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusNotFound, "Build not found")
+	}
+	if build.UserID != userUUID {
+		return nil, fiber.NewError(fiber.StatusForbidden, "Access denied")
+	}
+	return &build.ID, nil
+}
+
+// CreateCoord creates a new coord with its canvas layers
+func (h *CoordsHandler) CreateCoord(c *fiber.Ctx) error {
+	principal, err := middleware.CurrentPrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	var req models.CreateCoordRequest
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+
+	coord := &models.Coord{
+		ID:              uuid.New(),
+		UserID:          principal.UserID,
+		Name:            req.Name,
+		Description:     req.Description,
+		CanvasWidth:     req.CanvasWidth,
+		CanvasHeight:    req.CanvasHeight,
+		BackgroundColor: req.BackgroundColor,
+		CreatedAt:       time.Now(),
+		UpdatedAt:       time.Now(),
+	}
+
+	if req.BuildID != nil {
+		buildID, ferr := h.resolveBuild(*req.BuildID, principal.UserID)
+		if ferr != nil {
+			return c.Status(ferr.Code).JSON(fiber.Map{
+				"error": ferr.Message,
+			})
+		}
+		coord.BuildID = buildID
+	}
+
+	layers, ferr := h.buildLayers(req.Layers, principal.UserID)
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{
+			"error": ferr.Message,
+		})
+	}
+	coord.Layers = layers
+
+	if err := h.coordRepo.CreateCoord(coord); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to create coord",
+		})
+	}
+
+	// Reload so the layers come back with their pieces
+	created, err := h.coordRepo.GetCoordByID(coord.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to retrieve coord",
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message": "Coord created successfully",
+		"coord":   h.toResponse(created),
+	})
+}
+
+// GetCoords retrieves all coords for the authenticated user
+func (h *CoordsHandler) GetCoords(c *fiber.Ctx) error {
+	principal, err := middleware.CurrentPrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	limit, offset := parseLimitOffset(c)
+
+	coords, err := h.coordRepo.GetCoordsByUserID(principal.UserID, limit, offset)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to retrieve coords",
+		})
+	}
+
+	totalCount, err := h.coordRepo.GetCoordCount(principal.UserID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to retrieve coords",
+		})
+	}
+
+	response := make([]models.CoordResponse, 0, len(coords))
+	for _, coord := range coords {
+		response = append(response, h.toResponse(coord))
+	}
+
+	return c.JSON(fiber.Map{
+		"coords":      response,
+		"total_count": totalCount,
+		"limit":       limit,
+		"offset":      offset,
+	})
+}
+
+// GetCoord retrieves a specific coord by ID
+func (h *CoordsHandler) GetCoord(c *fiber.Ctx) error {
+	principal, err := middleware.CurrentPrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	coordID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid coord ID",
+		})
+	}
+
+	coord, err := h.coordRepo.GetCoordByID(coordID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Coord not found",
+		})
+	}
+
+	if coord.UserID != principal.UserID {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Access denied",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"coord": h.toResponse(coord),
+	})
+}
+
+// UpdateCoord updates an existing coord. Sending layers replaces the whole canvas.
+func (h *CoordsHandler) UpdateCoord(c *fiber.Ctx) error {
+	principal, err := middleware.CurrentPrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	coordID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid coord ID",
+		})
+	}
+
+	existingCoord, err := h.coordRepo.GetCoordByID(coordID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Coord not found",
+		})
+	}
+
+	if existingCoord.UserID != principal.UserID {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Access denied",
+		})
+	}
+
+	var req models.UpdateCoordRequest
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+
+	// Update fields if provided
+	if req.Name != nil {
+		existingCoord.Name = *req.Name
+	}
+	if req.Description != nil {
+		existingCoord.Description = req.Description
+	}
+	if req.BuildID != nil {
+		buildID, ferr := h.resolveBuild(*req.BuildID, principal.UserID)
+		if ferr != nil {
+			return c.Status(ferr.Code).JSON(fiber.Map{
+				"error": ferr.Message,
+			})
+		}
+		existingCoord.BuildID = buildID
+	}
+	if req.CanvasWidth != nil {
+		existingCoord.CanvasWidth = req.CanvasWidth
+	}
+	if req.CanvasHeight != nil {
+		existingCoord.CanvasHeight = req.CanvasHeight
+	}
+	if req.BackgroundColor != nil {
+		existingCoord.BackgroundColor = req.BackgroundColor
+	}
+
+	replaceLayers := req.Layers != nil
+	if replaceLayers {
+		layers, ferr := h.buildLayers(req.Layers, principal.UserID)
+		if ferr != nil {
+			return c.Status(ferr.Code).JSON(fiber.Map{
+				"error": ferr.Message,
+			})
+		}
+		existingCoord.Layers = layers
+	}
+
+	existingCoord.UpdatedAt = time.Now()
+
+	if err := h.coordRepo.UpdateCoord(existingCoord, replaceLayers); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to update coord",
+		})
+	}
+
+	updated, err := h.coordRepo.GetCoordByID(existingCoord.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to retrieve coord",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Coord updated successfully",
+		"coord":   h.toResponse(updated),
+	})
+}
+
+// DeleteCoord deletes a coord. The pieces on its canvas stay in the closet.
+func (h *CoordsHandler) DeleteCoord(c *fiber.Ctx) error {
+	principal, err := middleware.CurrentPrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	coordID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid coord ID",
+		})
+	}
+
+	if err := h.coordRepo.DeleteCoord(coordID, principal.UserID); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Coord not found or access denied",
+		})
+	}
+
+	return c.Status(fiber.StatusNoContent).JSON(fiber.Map{
+		"message": "Coord deleted successfully",
+	})
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"kyarafit-backend/database"
+	"kyarafit-backend/middleware"
+	"kyarafit-backend/models"
+)
+
+type CurrencyHandler struct {
+	currencyRepo *database.CurrencyRepository
+}
+
+func NewCurrencyHandler(currencyRepo *database.CurrencyRepository) *CurrencyHandler {
+	return &CurrencyHandler{currencyRepo: currencyRepo}
+}
+
+// GetHomeCurrency retrieves the user's home currency and the currencies
+// amounts may be recorded in
+func (h *CurrencyHandler) GetHomeCurrency(c *fiber.Ctx) error {
+	principal, err := middleware.CurrentPrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	currency, err := h.currencyRepo.GetHomeCurrency(principal.UserID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to retrieve home currency",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"home_currency":        currency,
+		"supported_currencies": models.SupportedCurrencies(),
+	})
+}
+
+// UpdateHomeCurrency changes the currency new amounts default to and stats
+// are reported in. Amounts already recorded keep their own currency.
+func (h *CurrencyHandler) UpdateHomeCurrency(c *fiber.Ctx) error {
+	principal, err := middleware.CurrentPrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	var req models.UpdateHomeCurrencyRequest
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+
+	currency, ferr := parseCurrency(req.HomeCurrency)
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{
+			"error": ferr.Message,
+		})
+	}
+
+	if err := h.currencyRepo.SetHomeCurrency(principal.UserID, currency); err != nil {
+		if errors.Is(err, database.ErrUserNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "User not found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to update home currency",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message":       "Home currency updated successfully",
+		"home_currency": currency,
+	})
+}
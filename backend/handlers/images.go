@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"kyarafit-backend/models"
+	"kyarafit-backend/storage"
+)
+
+// signedURLTTL is how long the image URLs in a response stay valid
+const signedURLTTL = time.Hour
+
+// signPieceImages swaps the URL fields of a piece's uploaded images for signed,
+// expiring download URLs. URLs that clients set directly are left alone.
+func signPieceImages(blobs storage.BlobStore, piece *models.Piece) {
+	if piece == nil {
+		return
+	}
+
+	images := []struct {
+		key *string
+		url **string
+	}{
+		{piece.ImageKey, &piece.ImageURL},
+		{piece.CutoutKey, &piece.CutoutURL},
+		{piece.ThumbnailKey, &piece.ThumbnailURL},
+	}
+	for _, image := range images {
+		if image.key == nil {
+			continue
+		}
+		signed, err := blobs.SignedURL(*image.key, signedURLTTL)
+		if err != nil {
+			log.Printf("Failed to sign URL for blob %s: %v", *image.key, err)
+			continue
+		}
+		*image.url = &signed
+	}
+}
+
+type UploadsHandler struct {
+	store *storage.LocalStore
+}
+
+func NewUploadsHandler(store *storage.LocalStore) *UploadsHandler {
+	return &UploadsHandler{store: store}
+}
+
+// ServeBlob serves a blob from local storage to anyone holding a valid signed URL
+func (h *UploadsHandler) ServeBlob(c *fiber.Ctx) error {
+	key := c.Params("*")
+
+	if err := h.store.Verify(key, c.Query("expires"), c.Query("signature")); err != nil {
+		if errors.Is(err, storage.ErrURLExpired) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "Download link has expired",
+			})
+		}
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Invalid download link",
+		})
+	}
+
+	exists, err := h.store.Exists(c.Context(), key)
+	if err != nil || !exists {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "File not found",
+		})
+	}
+	filePath, err := h.store.Path(key)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "File not found",
+		})
+	}
+
+	// Keys are content-addressed, so a blob never changes once written
+	c.Set(fiber.HeaderCacheControl, "private, max-age=3600, immutable")
+	return c.SendFile(filePath)
+}
@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"kyarafit-backend/database"
+	"kyarafit-backend/middleware"
+	"kyarafit-backend/models"
+)
+
+const (
+	// jobEventsInterval is how often the events stream checks a job for changes
+	jobEventsInterval = time.Second
+	// jobEventsTimeout closes an events stream whose job hasn't finished, so
+	// clients reconnect or fall back to polling instead of holding it open forever
+	jobEventsTimeout = 5 * time.Minute
+)
+
+type JobsHandler struct {
+	jobRepo *database.JobRepository
+}
+
+func NewJobsHandler(jobRepo *database.JobRepository) *JobsHandler {
+	return &JobsHandler{jobRepo: jobRepo}
+}
+
+// findJob parses a job ID and loads the job if it belongs to the user
+func (h *JobsHandler) findJob(idStr string, userUUID uuid.UUID) (*models.Job, *fiber.Error) {
+	jobID, err := uuid.Parse(idStr)
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Invalid job ID")
+	}
+	job, err := h.jobRepo.GetJobByID(jobID)
+	if err != nil || job.UserID != userUUID {
+		return nil, fiber.NewError(fiber.StatusNotFound, "Job not found")
+	}
+	return job, nil
+}
+
+// GetJob retrieves the status and progress of a background job
+func (h *JobsHandler) GetJob(c *fiber.Ctx) error {
+	principal, err := middleware.CurrentPrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	job, ferr := h.findJob(c.Params("id"), principal.UserID)
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{
+			"error": ferr.Message,
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"job": job.ToResponse(),
+	})
+}
+
+// JobEvents streams a job's progress as server-sent events. A "progress" event
+// is sent whenever the job changes and a final "complete" event once it has
+// succeeded or died, after which the stream closes.
+func (h *JobsHandler) JobEvents(c *fiber.Ctx) error {
+	principal, err := middleware.CurrentPrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	job, ferr := h.findJob(c.Params("id"), principal.UserID)
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{
+			"error": ferr.Message,
+		})
+	}
+
+	c.Set("Content-Type", "text/event-stream")
+	c.Set("Cache-Control", "no-cache")
+	c.Set("Connection", "keep-alive")
+	c.Set("X-Accel-Buffering", "no")
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		deadline := time.Now().Add(jobEventsTimeout)
+		var lastUpdate time.Time
+
+		for {
+			if !job.UpdatedAt.Equal(lastUpdate) {
+				lastUpdate = job.UpdatedAt
+				event := "progress"
+				if job.Status.Done() {
+					event = "complete"
+				}
+				if err := writeEvent(w, event, job.ToResponse()); err != nil {
+					// The client went away
+					return
+				}
+				if job.Status.Done() {
+					return
+				}
+			}
+
+			if time.Now().After(deadline) {
+				return
+			}
+			time.Sleep(jobEventsInterval)
+
+			next, err := h.jobRepo.GetJobByID(job.ID)
+			if err != nil {
+				log.Printf("Failed to refresh job %s: %v", job.ID, err)
+				return
+			}
+			job = next
+		}
+	})
+
+	return nil
+}
+
+// writeEvent writes one server-sent event with a JSON payload and flushes it
+func writeEvent(w *bufio.Writer, event string, data interface{}) error {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, encoded); err != nil {
+		return err
+	}
+	return w.Flush()
+}
@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"kyarafit-backend/models"
+)
+
+// unsupportedCurrencyMessage is the error for a currency code that isn't supported
+const unsupportedCurrencyMessage = "Unsupported currency. Use an ISO 4217 code such as USD or JPY"
+
+// parseCurrency reads an ISO 4217 currency code, accepting lower case
+func parseCurrency(raw string) (string, *fiber.Error) {
+	code, err := models.NormalizeCurrency(raw)
+	if err != nil {
+		return "", fiber.NewError(fiber.StatusBadRequest, unsupportedCurrencyMessage)
+	}
+	return code, nil
+}
+
+// parseMoney reads an amount from a request, in fallback when it names no
+// currency. field names the amount in errors, e.g. "price".
+func parseMoney(input models.MoneyInput, fallback, field string) (models.Money, *fiber.Error) {
+	money, err := input.Money(fallback)
+	if err != nil {
+		if errors.Is(err, models.ErrUnsupportedCurrency) {
+			return models.Money{}, fiber.NewError(fiber.StatusBadRequest, unsupportedCurrencyMessage)
+		}
+		return models.Money{}, fiber.NewError(fiber.StatusBadRequest, "Invalid "+field+": "+err.Error())
+	}
+	return money, nil
+}
@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"kyarafit-backend/imaging"
+	"kyarafit-backend/middleware"
+	"kyarafit-backend/models"
+	"kyarafit-backend/storage"
+)
+
+// UploadPieceImage stores an uploaded image for a piece with a placeholder
+// thumbnail made from it, and queues a job to remove its background. The job
+// replaces the placeholder with a thumbnail of the cutout when it finishes.
+func (h *PiecesHandler) UploadPieceImage(c *fiber.Ctx) error {
+	principal, err := middleware.CurrentPrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	pieceID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid piece ID",
+		})
+	}
+
+	piece, err := h.pieceRepo.GetPieceByID(pieceID)
+	if err != nil || piece.UserID != principal.UserID {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Piece not found",
+		})
+	}
+
+	fileHeader, err := c.FormFile("image")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "An image file is required in the \"image\" form field",
+		})
+	}
+	if fileHeader.Size > imaging.MaxUploadSize {
+		return c.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{
+			"error": fmt.Sprintf("Image must be %d MB or smaller", imaging.MaxUploadSize>>20),
+		})
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Failed to read image",
+		})
+	}
+	defer file.Close()
+
+	original, err := io.ReadAll(io.LimitReader(file, imaging.MaxUploadSize+1))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Failed to read image",
+		})
+	}
+	if len(original) > imaging.MaxUploadSize {
+		return c.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{
+			"error": fmt.Sprintf("Image must be %d MB or smaller", imaging.MaxUploadSize>>20),
+		})
+	}
+
+	contentType, err := imaging.DetectType(original)
+	if err != nil {
+		if errors.Is(err, imaging.ErrTooLarge) {
+			return c.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{
+				"error": "Image dimensions are too large",
+			})
+		}
+		return c.Status(fiber.StatusUnsupportedMediaType).JSON(fiber.Map{
+			"error": "Image must be a JPEG, PNG or WebP file",
+		})
+	}
+
+	// Keys are content-addressed within the user's storage, so uploading the
+	// same photo again reuses the stored blobs
+	keyPrefix := "users/" + principal.UserID.String()
+	ctx := c.UserContext()
+
+	imageKey, err := storage.PutContent(ctx, h.blobs, keyPrefix, original, contentType, imaging.Extensions[contentType])
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to store image",
+		})
+	}
+
+	// Until the background is removed the piece shows a thumbnail of the
+	// original image
+	thumbnail, err := imaging.Thumbnail(original)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to create thumbnail",
+		})
+	}
+	thumbnailKey, err := storage.PutContent(ctx, h.blobs, keyPrefix, thumbnail, "image/png", "png")
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to store image",
+		})
+	}
+
+	piece.ImageURL = nil
+	piece.ImageKey = &imageKey
+	piece.CutoutURL = nil
+	piece.CutoutKey = nil
+	piece.ThumbnailURL = nil
+	piece.ThumbnailKey = &thumbnailKey
+
+	if err := h.pieceRepo.UpdatePieceImages(piece); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to update piece",
+		})
+	}
+
+	payload, err := json.Marshal(models.PieceImageJob{
+		PieceID:     piece.ID,
+		ImageKey:    imageKey,
+		ContentType: contentType,
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to queue image processing",
+		})
+	}
+
+	job := &models.Job{
+		ID:          uuid.New(),
+		UserID:      principal.UserID,
+		Kind:        models.JobKindPieceImage,
+		Payload:     payload,
+		MaxAttempts: models.DefaultJobMaxAttempts,
+	}
+	if err := h.jobRepo.CreateJob(job); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to queue image processing",
+		})
+	}
+
+	response, err := h.toResponses([]*models.Piece{piece})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to retrieve piece",
+		})
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+		"message": "Image uploaded, background removal queued",
+		"piece":   response[0],
+		"job":     job.ToResponse(),
+	})
+}
@@ -1,51 +1,83 @@
 package handlers
 
 import (
-	"strconv"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 	"kyarafit-backend/database"
+	"kyarafit-backend/middleware"
 	"kyarafit-backend/models"
+	"kyarafit-backend/storage"
 )
 
 type PiecesHandler struct {
-	pieceRepo *database.PieceRepository
+	pieceRepo    *database.PieceRepository
+	wearLogRepo  *database.WearLogRepository
+	jobRepo      *database.JobRepository
+	currencyRepo *database.CurrencyRepository
+	blobs        storage.BlobStore
 }
 
-func NewPiecesHandler(pieceRepo *database.PieceRepository) *PiecesHandler {
-	return &PiecesHandler{pieceRepo: pieceRepo}
+func NewPiecesHandler(pieceRepo *database.PieceRepository, wearLogRepo *database.WearLogRepository, jobRepo *database.JobRepository, currencyRepo *database.CurrencyRepository, blobs storage.BlobStore) *PiecesHandler {
+	return &PiecesHandler{
+		pieceRepo:    pieceRepo,
+		wearLogRepo:  wearLogRepo,
+		jobRepo:      jobRepo,
+		currencyRepo: currencyRepo,
+		blobs:        blobs,
+	}
 }
 
-// CreatePiece creates a new piece
-func (h *PiecesHandler) CreatePiece(c *fiber.Ctx) error {
-	userID, ok := c.Locals("userID").(string)
-	if !ok {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "User not authenticated",
-		})
+// parsePrice reads a piece's price, in the user's home currency when it
+// names no currency
+func (h *PiecesHandler) parsePrice(input models.MoneyInput, userID uuid.UUID) (*models.Money, *fiber.Error) {
+	home, err := h.currencyRepo.GetHomeCurrency(userID)
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "Failed to retrieve home currency")
+	}
+
+	price, ferr := parseMoney(input, home, "price")
+	if ferr != nil {
+		return nil, ferr
 	}
+	return &price, nil
+}
 
-	userUUID, err := uuid.Parse(userID)
+// toResponses converts pieces to their response format with times worn, last
+// worn and signed image URLs filled in
+func (h *PiecesHandler) toResponses(pieces []*models.Piece) ([]models.PieceResponse, error) {
+	ids := make([]uuid.UUID, 0, len(pieces))
+	for _, piece := range pieces {
+		ids = append(ids, piece.ID)
+	}
+
+	summaries, err := h.wearLogRepo.GetPieceWearSummaries(ids)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid user ID",
-		})
+		return nil, err
 	}
 
-	var req models.CreatePieceRequest
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid request body",
-		})
+	response := make([]models.PieceResponse, 0, len(pieces))
+	for _, piece := range pieces {
+		signPieceImages(h.blobs, piece)
+		pieceResponse := piece.ToResponse()
+		pieceResponse.ApplyWearSummary(summaries[piece.ID])
+		response = append(response, pieceResponse)
 	}
 
-	// Validate required fields
-	if req.Name == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Name is required",
-		})
+	return response, nil
+}
+
+// CreatePiece creates a new piece
+func (h *PiecesHandler) CreatePiece(c *fiber.Ctx) error {
+	principal, err := middleware.CurrentPrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	var req models.CreatePieceRequest
+	if err := parseBody(c, &req); err != nil {
+		return err
 	}
 
 	// Parse purchase date if provided
@@ -60,9 +92,19 @@ func (h *PiecesHandler) CreatePiece(c *fiber.Ctx) error {
 		purchaseDate = &parsedDate
 	}
 
+	var price *models.Money
+	if req.Price != nil {
+		var ferr *fiber.Error
+		if price, ferr = h.parsePrice(*req.Price, principal.UserID); ferr != nil {
+			return c.Status(ferr.Code).JSON(fiber.Map{
+				"error": ferr.Message,
+			})
+		}
+	}
+
 	piece := &models.Piece{
 		ID:           uuid.New(),
-		UserID:       userUUID,
+		UserID:       principal.UserID,
 		Name:         req.Name,
 		Description:  req.Description,
 		ImageURL:     req.ImageURL,
@@ -71,7 +113,7 @@ func (h *PiecesHandler) CreatePiece(c *fiber.Ctx) error {
 		Tags:         req.Tags,
 		SourceLink:   req.SourceLink,
 		PurchaseDate: purchaseDate,
-		Price:        req.Price,
+		Price:        price,
 		CreatedAt:    time.Now(),
 		UpdatedAt:    time.Now(),
 	}
@@ -88,92 +130,118 @@ func (h *PiecesHandler) CreatePiece(c *fiber.Ctx) error {
 	})
 }
 
-// GetPieces retrieves all pieces for the authenticated user
-func (h *PiecesHandler) GetPieces(c *fiber.Ctx) error {
-	userID, ok := c.Locals("userID").(string)
-	if !ok {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "User not authenticated",
-		})
+// parsePieceFilter reads the filters and sort of a piece listing from the
+// query string. Price bounds are in the given currency.
+func parsePieceFilter(c *fiber.Ctx, currency string) (database.PieceFilter, *fiber.Error) {
+	filter := database.PieceFilter{
+		Categories: queryList(c, "category"),
+		Tags:       queryList(c, "tags"),
+		Search:     c.Query("search"),
+		Sort:       c.Query("sort"),
 	}
 
-	userUUID, err := uuid.Parse(userID)
-	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid user ID",
-		})
+	switch match := c.Query("tag_match", "any"); match {
+	case "any", "all":
+		filter.MatchAllTags = match == "all"
+	default:
+		return filter, fiber.NewError(fiber.StatusBadRequest, "Invalid tag_match. Must be one of: any, all")
 	}
 
-	// Parse query parameters
-	limit := 20
-	offset := 0
-	category := c.Query("category")
-	search := c.Query("search")
+	var ferr *fiber.Error
+	if filter.MinPrice, ferr = queryMoney(c, "price_min", currency); ferr != nil {
+		return filter, ferr
+	}
+	if filter.MaxPrice, ferr = queryMoney(c, "price_max", currency); ferr != nil {
+		return filter, ferr
+	}
+	if filter.PurchasedFrom, ferr = queryDate(c, "purchased_from"); ferr != nil {
+		return filter, ferr
+	}
+	if filter.PurchasedTo, ferr = queryDate(c, "purchased_to"); ferr != nil {
+		return filter, ferr
+	}
+	if filter.HasImage, ferr = queryBool(c, "has_image"); ferr != nil {
+		return filter, ferr
+	}
 
-	if limitStr := c.Query("limit"); limitStr != "" {
-		if parsedLimit, err := strconv.Atoi(limitStr); err == nil && parsedLimit > 0 && parsedLimit <= 100 {
-			limit = parsedLimit
-		}
+	unlinked, ferr := queryBool(c, "unlinked")
+	if ferr != nil {
+		return filter, ferr
 	}
+	filter.Unlinked = unlinked != nil && *unlinked
 
-	if offsetStr := c.Query("offset"); offsetStr != "" {
-		if parsedOffset, err := strconv.Atoi(offsetStr); err == nil && parsedOffset >= 0 {
-			offset = parsedOffset
-		}
+	return filter, nil
+}
+
+// GetPieces retrieves the authenticated user's pieces matching any mix of filters
+func (h *PiecesHandler) GetPieces(c *fiber.Ctx) error {
+	principal, err := middleware.CurrentPrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	// Prices are compared in the home currency
+	currency, err := h.currencyRepo.GetHomeCurrency(principal.UserID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to retrieve pieces",
+		})
 	}
 
-	var pieces []*models.Piece
-	var piecesErr error
+	filter, ferr := parsePieceFilter(c, currency)
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{
+			"error": ferr.Message,
+		})
+	}
 
-	if search != "" {
-		pieces, piecesErr = h.pieceRepo.SearchPieces(userUUID, search, limit, offset)
-	} else if category != "" {
-		pieces, piecesErr = h.pieceRepo.GetPiecesByCategory(userUUID, category, limit, offset)
-	} else {
-		pieces, piecesErr = h.pieceRepo.GetPiecesByUserID(userUUID, limit, offset)
+	limit, after, ferr := parseCursorPage(c)
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{
+			"error": ferr.Message,
+		})
 	}
 
-	if piecesErr != nil {
+	pieces, next, err := h.pieceRepo.ListPieces(principal.UserID, filter, after, limit)
+	if err != nil {
+		if ferr := listError(err, database.PieceSortKeys()); ferr != nil {
+			return c.Status(ferr.Code).JSON(fiber.Map{
+				"error": ferr.Message,
+			})
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to retrieve pieces",
 		})
 	}
 
-	// Convert to response format
-	var response []models.PieceResponse
-	for _, piece := range pieces {
-		response = append(response, piece.ToResponse())
+	total, err := h.pieceRepo.CountPieces(principal.UserID, filter)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to retrieve pieces",
+		})
 	}
 
-	// Get total count for pagination
-	totalCount, err := h.pieceRepo.GetPieceCount(userUUID)
+	// Convert to response format
+	response, err := h.toResponses(pieces)
 	if err != nil {
-		// Log error but don't fail the request
-		totalCount = len(pieces)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to retrieve pieces",
+		})
 	}
 
 	return c.JSON(fiber.Map{
 		"pieces":      response,
-		"total_count": totalCount,
+		"total":       total,
 		"limit":       limit,
-		"offset":      offset,
+		"next_cursor": nextCursor(next),
 	})
 }
 
 // GetPiece retrieves a specific piece by ID
 func (h *PiecesHandler) GetPiece(c *fiber.Ctx) error {
-	userID, ok := c.Locals("userID").(string)
-	if !ok {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "User not authenticated",
-		})
-	}
-
-	userUUID, err := uuid.Parse(userID)
+	principal, err := middleware.CurrentPrincipal(c)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid user ID",
-		})
+		return err
 	}
 
 	pieceIDStr := c.Params("id")
@@ -192,31 +260,29 @@ func (h *PiecesHandler) GetPiece(c *fiber.Ctx) error {
 	}
 
 	// Check if the piece belongs to the authenticated user
-	if piece.UserID != userUUID {
+	if piece.UserID != principal.UserID {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
 			"error": "Access denied",
 		})
 	}
 
+	response, err := h.toResponses([]*models.Piece{piece})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to retrieve piece",
+		})
+	}
+
 	return c.JSON(fiber.Map{
-		"piece": piece.ToResponse(),
+		"piece": response[0],
 	})
 }
 
 // UpdatePiece updates an existing piece
 func (h *PiecesHandler) UpdatePiece(c *fiber.Ctx) error {
-	userID, ok := c.Locals("userID").(string)
-	if !ok {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "User not authenticated",
-		})
-	}
-
-	userUUID, err := uuid.Parse(userID)
+	principal, err := middleware.CurrentPrincipal(c)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid user ID",
-		})
+		return err
 	}
 
 	pieceIDStr := c.Params("id")
@@ -235,17 +301,15 @@ func (h *PiecesHandler) UpdatePiece(c *fiber.Ctx) error {
 		})
 	}
 
-	if existingPiece.UserID != userUUID {
+	if existingPiece.UserID != principal.UserID {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
 			"error": "Access denied",
 		})
 	}
 
 	var req models.UpdatePieceRequest
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid request body",
-		})
+	if err := parseBody(c, &req); err != nil {
+		return err
 	}
 
 	// Update fields if provided
@@ -255,11 +319,16 @@ func (h *PiecesHandler) UpdatePiece(c *fiber.Ctx) error {
 	if req.Description != nil {
 		existingPiece.Description = req.Description
 	}
+	// A URL set directly replaces any uploaded image, along with its cutout
 	if req.ImageURL != nil {
 		existingPiece.ImageURL = req.ImageURL
+		existingPiece.ImageKey = nil
+		existingPiece.CutoutURL = nil
+		existingPiece.CutoutKey = nil
 	}
 	if req.ThumbnailURL != nil {
 		existingPiece.ThumbnailURL = req.ThumbnailURL
+		existingPiece.ThumbnailKey = nil
 	}
 	if req.Category != nil {
 		existingPiece.Category = req.Category
@@ -284,7 +353,12 @@ func (h *PiecesHandler) UpdatePiece(c *fiber.Ctx) error {
 		}
 	}
 	if req.Price != nil {
-		existingPiece.Price = req.Price
+		var ferr *fiber.Error
+		if existingPiece.Price, ferr = h.parsePrice(*req.Price, principal.UserID); ferr != nil {
+			return c.Status(ferr.Code).JSON(fiber.Map{
+				"error": ferr.Message,
+			})
+		}
 	}
 
 	existingPiece.UpdatedAt = time.Now()
@@ -295,26 +369,24 @@ func (h *PiecesHandler) UpdatePiece(c *fiber.Ctx) error {
 		})
 	}
 
+	response, err := h.toResponses([]*models.Piece{existingPiece})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to retrieve piece",
+		})
+	}
+
 	return c.JSON(fiber.Map{
 		"message": "Piece updated successfully",
-		"piece":   existingPiece.ToResponse(),
+		"piece":   response[0],
 	})
 }
 
 // DeletePiece deletes a piece
 func (h *PiecesHandler) DeletePiece(c *fiber.Ctx) error {
-	userID, ok := c.Locals("userID").(string)
-	if !ok {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "User not authenticated",
-		})
-	}
-
-	userUUID, err := uuid.Parse(userID)
+	principal, err := middleware.CurrentPrincipal(c)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid user ID",
-		})
+		return err
 	}
 
 	pieceIDStr := c.Params("id")
@@ -325,7 +397,7 @@ func (h *PiecesHandler) DeletePiece(c *fiber.Ctx) error {
 		})
 	}
 
-	if err := h.pieceRepo.DeletePiece(pieceID, userUUID); err != nil {
+	if err := h.pieceRepo.DeletePiece(pieceID, principal.UserID); err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"error": "Piece not found or access denied",
 		})
@@ -336,25 +408,39 @@ func (h *PiecesHandler) DeletePiece(c *fiber.Ctx) error {
 	})
 }
 
-// GetCategories retrieves all unique categories for the authenticated user
+// GetCategories retrieves the authenticated user's categories with how many
+// pieces are in each
 func (h *PiecesHandler) GetCategories(c *fiber.Ctx) error {
-	userID, ok := c.Locals("userID").(string)
-	if !ok {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "User not authenticated",
-		})
+	principal, err := middleware.CurrentPrincipal(c)
+	if err != nil {
+		return err
 	}
 
-	_, err := uuid.Parse(userID)
+	categories, err := h.pieceRepo.GetCategories(principal.UserID)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid user ID",
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to retrieve categories",
 		})
 	}
 
-	// This would require a new method in the repository
-	// For now, return a simple response
 	return c.JSON(fiber.Map{
-		"categories": []string{"wig", "dress", "prop", "shoes", "accessory", "makeup", "other"},
+		"categories": categories,
 	})
 }
+
+// GetPieceStats retrieves closet statistics for the authenticated user
+func (h *PiecesHandler) GetPieceStats(c *fiber.Ctx) error {
+	principal, err := middleware.CurrentPrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	stats, err := h.pieceRepo.GetPieceStats(principal.UserID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get piece statistics",
+		})
+	}
+
+	return c.JSON(stats)
+}
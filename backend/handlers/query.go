@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"kyarafit-backend/database"
+	"kyarafit-backend/models"
+)
+
+// includes reports whether a comma-separated ?include= value names the given expansion
+func includes(include, name string) bool {
+	for _, part := range strings.Split(include, ",") {
+		if strings.TrimSpace(part) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// parseLimitOffset reads the limit and offset query parameters with the usual defaults
+func parseLimitOffset(c *fiber.Ctx) (int, int) {
+	limit := parseLimit(c)
+	offset := 0
+
+	if offsetStr := c.Query("offset"); offsetStr != "" {
+		if parsedOffset, err := strconv.Atoi(offsetStr); err == nil && parsedOffset >= 0 {
+			offset = parsedOffset
+		}
+	}
+
+	return limit, offset
+}
+
+// parseLimit reads the limit query parameter, defaulting to 20 and capped at 100
+func parseLimit(c *fiber.Ctx) int {
+	limit := 20
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if parsedLimit, err := strconv.Atoi(limitStr); err == nil && parsedLimit > 0 && parsedLimit <= 100 {
+			limit = parsedLimit
+		}
+	}
+	return limit
+}
+
+// parseCursorPage reads the limit and cursor query parameters of a
+// keyset-paginated list
+func parseCursorPage(c *fiber.Ctx) (int, *database.Cursor, *fiber.Error) {
+	limit := parseLimit(c)
+
+	after, err := database.DecodeCursor(c.Query("cursor"))
+	if err != nil {
+		return 0, nil, fiber.NewError(fiber.StatusBadRequest, "Invalid cursor")
+	}
+
+	return limit, after, nil
+}
+
+// nextCursor encodes the cursor of the page after this one, or returns nil
+// on the last page so it is sent as null
+func nextCursor(next *database.Cursor) *string {
+	if next == nil {
+		return nil
+	}
+	encoded := next.Encode()
+	return &encoded
+}
+
+// queryList reads a multi-valued query parameter, given either repeated
+// (?status=idea&status=wip) or comma-separated (?status=idea,wip)
+func queryList(c *fiber.Ctx, name string) []string {
+	var values []string
+	for _, raw := range c.Context().QueryArgs().PeekMulti(name) {
+		for _, value := range strings.Split(string(raw), ",") {
+			if value = strings.TrimSpace(value); value != "" {
+				values = append(values, value)
+			}
+		}
+	}
+	return values
+}
+
+// queryInt reads an optional integer query parameter
+func queryInt(c *fiber.Ctx, name string) (*int, *fiber.Error) {
+	raw := c.Query(name)
+	if raw == "" {
+		return nil, nil
+	}
+	value, err := strconv.Atoi(raw)
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("Invalid %s. Must be a whole number", name))
+	}
+	return &value, nil
+}
+
+// queryMoney reads an optional decimal amount query parameter in a currency
+func queryMoney(c *fiber.Ctx, name, currency string) (*models.Money, *fiber.Error) {
+	raw := c.Query(name)
+	if raw == "" {
+		return nil, nil
+	}
+	value, err := models.ParseMoney(raw, currency)
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("Invalid %s: %s", name, err.Error()))
+	}
+	return &value, nil
+}
+
+// queryBool reads an optional true/false query parameter
+func queryBool(c *fiber.Ctx, name string) (*bool, *fiber.Error) {
+	raw := c.Query(name)
+	if raw == "" {
+		return nil, nil
+	}
+	value, err := strconv.ParseBool(raw)
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("Invalid %s. Must be true or false", name))
+	}
+	return &value, nil
+}
+
+// queryDate reads an optional YYYY-MM-DD query parameter
+func queryDate(c *fiber.Ctx, name string) (*time.Time, *fiber.Error) {
+	raw := c.Query(name)
+	if raw == "" {
+		return nil, nil
+	}
+	value, err := time.Parse("2006-01-02", raw)
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("Invalid %s format. Use YYYY-MM-DD", name))
+	}
+	return &value, nil
+}
+
+// listError maps a list query error to a response error, or returns nil for
+// errors that aren't the client's fault
+func listError(err error, sortKeys []string) *fiber.Error {
+	switch {
+	case errors.Is(err, database.ErrInvalidCursor):
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid cursor")
+	case errors.Is(err, database.ErrInvalidSort):
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid sort. Must be one of: "+strings.Join(sortKeys, ", ")+", optionally prefixed with - for descending order")
+	}
+	return nil
+}
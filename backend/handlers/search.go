@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"kyarafit-backend/database"
+	"kyarafit-backend/middleware"
+)
+
+type SearchHandler struct {
+	searchRepo *database.SearchRepository
+}
+
+func NewSearchHandler(searchRepo *database.SearchRepository) *SearchHandler {
+	return &SearchHandler{searchRepo: searchRepo}
+}
+
+// Search returns the user's pieces, builds and other entities matching a
+// query, ranked and grouped by type
+func (h *SearchHandler) Search(c *fiber.Ctx) error {
+	principal, err := middleware.CurrentPrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	query := strings.TrimSpace(c.Query("q"))
+	if query == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Search query is required",
+		})
+	}
+	if len(query) > 200 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Search query must be 200 characters or fewer",
+		})
+	}
+
+	allTypes := database.SearchTypes()
+	types := queryList(c, "types")
+	if len(types) == 0 {
+		types = allTypes
+	}
+	for _, kind := range types {
+		valid := false
+		for _, known := range allTypes {
+			if kind == known {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid types. Must be any of: " + strings.Join(allTypes, ", "),
+			})
+		}
+	}
+
+	// limit applies to each type
+	limit := 5
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if parsedLimit, err := strconv.Atoi(limitStr); err == nil && parsedLimit > 0 && parsedLimit <= 20 {
+			limit = parsedLimit
+		}
+	}
+
+	results, err := h.searchRepo.Search(principal.UserID, query, types, limit)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to search",
+		})
+	}
+
+	total := 0
+	for _, hits := range results {
+		total += len(hits)
+	}
+
+	return c.JSON(fiber.Map{
+		"query":       query,
+		"results":     results,
+		"total_count": total,
+	})
+}
@@ -0,0 +1,308 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"kyarafit-backend/database"
+	"kyarafit-backend/middleware"
+	"kyarafit-backend/models"
+)
+
+type TagsHandler struct {
+	tagRepo *database.TagRepository
+}
+
+func NewTagsHandler(tagRepo *database.TagRepository) *TagsHandler {
+	return &TagsHandler{tagRepo: tagRepo}
+}
+
+// findTag parses a tag ID and loads the tag if it belongs to the user
+func (h *TagsHandler) findTag(idStr string, userUUID uuid.UUID) (*models.Tag, *fiber.Error) {
+	tagID, err := uuid.Parse(idStr)
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Invalid tag ID")
+	}
+	tag, err := h.tagRepo.GetTagByID(tagID)
+	if err != nil {
+		if errors.Is(err, database.ErrTagNotFound) {
+			return nil, fiber.NewError(fiber.StatusNotFound, "Tag not found")
+		}
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "Failed to retrieve tag")
+	}
+	if tag.UserID != userUUID {
+		return nil, fiber.NewError(fiber.StatusForbidden, "Access denied")
+	}
+	return tag, nil
+}
+
+// tagError converts an error saving a tag to a response error
+func tagError(err error, fallback string) *fiber.Error {
+	switch {
+	case errors.Is(err, database.ErrTagNotFound):
+		return fiber.NewError(fiber.StatusNotFound, "Tag not found")
+	case errors.Is(err, database.ErrTagExists):
+		return fiber.NewError(fiber.StatusConflict, "A tag with this name already exists. Merge into it instead")
+	default:
+		return fiber.NewError(fiber.StatusInternalServerError, fallback)
+	}
+}
+
+// GetTags retrieves the authenticated user's tags
+func (h *TagsHandler) GetTags(c *fiber.Ctx) error {
+	principal, err := middleware.CurrentPrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	tags, err := h.tagRepo.ListTags(principal.UserID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to retrieve tags",
+		})
+	}
+	if tags == nil {
+		tags = []*models.Tag{}
+	}
+
+	return c.JSON(fiber.Map{
+		"tags":  tags,
+		"count": len(tags),
+	})
+}
+
+// AutocompleteTags suggests the authenticated user's tags for a partly typed name
+func (h *TagsHandler) AutocompleteTags(c *fiber.Ctx) error {
+	principal, err := middleware.CurrentPrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	prefix := strings.TrimSpace(c.Query("q"))
+	if prefix == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "q is required",
+		})
+	}
+	if len(prefix) > maxVocabularyName {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "q must be 100 characters or fewer",
+		})
+	}
+
+	limit := 10
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if parsedLimit, err := strconv.Atoi(limitStr); err == nil && parsedLimit > 0 && parsedLimit <= 50 {
+			limit = parsedLimit
+		}
+	}
+
+	tags, err := h.tagRepo.AutocompleteTags(principal.UserID, prefix, limit)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to autocomplete tags",
+		})
+	}
+	if tags == nil {
+		tags = []*models.Tag{}
+	}
+
+	return c.JSON(fiber.Map{
+		"tags": tags,
+	})
+}
+
+// CreateTag adds a tag to the authenticated user's vocabulary
+func (h *TagsHandler) CreateTag(c *fiber.Ctx) error {
+	principal, err := middleware.CurrentPrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	var req models.CreateTagRequest
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+
+	tag := &models.Tag{
+		ID:        uuid.New(),
+		UserID:    principal.UserID,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+
+	var ferr *fiber.Error
+	if tag.Name, ferr = parseVocabularyName(req.Name); ferr == nil && req.Color != nil {
+		tag.Color, ferr = parseColor(*req.Color)
+	}
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{
+			"error": ferr.Message,
+		})
+	}
+
+	if err := h.tagRepo.CreateTag(tag); err != nil {
+		ferr := tagError(err, "Failed to create tag")
+		return c.Status(ferr.Code).JSON(fiber.Map{
+			"error": ferr.Message,
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message": "Tag created successfully",
+		"tag":     tag,
+	})
+}
+
+// GetTag retrieves a specific tag by ID
+func (h *TagsHandler) GetTag(c *fiber.Ctx) error {
+	principal, err := middleware.CurrentPrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	tag, ferr := h.findTag(c.Params("id"), principal.UserID)
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{
+			"error": ferr.Message,
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"tag": tag,
+	})
+}
+
+// UpdateTag updates a tag. Renaming it retags every piece, build and wishlist
+// item with it in the same transaction.
+func (h *TagsHandler) UpdateTag(c *fiber.Ctx) error {
+	principal, err := middleware.CurrentPrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	tag, ferr := h.findTag(c.Params("id"), principal.UserID)
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{
+			"error": ferr.Message,
+		})
+	}
+
+	var req models.UpdateTagRequest
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+
+	previousName := tag.Name
+	if req.Name != nil {
+		tag.Name, ferr = parseVocabularyName(*req.Name)
+	}
+	if ferr == nil && req.Color != nil {
+		tag.Color, ferr = parseColor(*req.Color)
+	}
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{
+			"error": ferr.Message,
+		})
+	}
+
+	tag.UpdatedAt = time.Now()
+
+	retagged, err := h.tagRepo.UpdateTag(tag, previousName)
+	if err != nil {
+		ferr := tagError(err, "Failed to update tag")
+		return c.Status(ferr.Code).JSON(fiber.Map{
+			"error": ferr.Message,
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message":       "Tag updated successfully",
+		"tag":           tag,
+		"items_updated": retagged,
+	})
+}
+
+// DeleteTag deletes a tag, removing it from every piece, build and wishlist item
+func (h *TagsHandler) DeleteTag(c *fiber.Ctx) error {
+	principal, err := middleware.CurrentPrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	tag, ferr := h.findTag(c.Params("id"), principal.UserID)
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{
+			"error": ferr.Message,
+		})
+	}
+
+	if err := h.tagRepo.DeleteTag(tag); err != nil {
+		ferr := tagError(err, "Failed to delete tag")
+		return c.Status(ferr.Code).JSON(fiber.Map{
+			"error": ferr.Message,
+		})
+	}
+
+	return c.Status(fiber.StatusNoContent).JSON(fiber.Map{
+		"message": "Tag deleted successfully",
+	})
+}
+
+// MergeTag retags every piece, build and wishlist item with a tag with
+// another one and deletes it, in one transaction
+func (h *TagsHandler) MergeTag(c *fiber.Ctx) error {
+	principal, err := middleware.CurrentPrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	source, ferr := h.findTag(c.Params("id"), principal.UserID)
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{
+			"error": ferr.Message,
+		})
+	}
+
+	var req models.MergeRequest
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+
+	target, ferr := h.findTag(req.Into, principal.UserID)
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{
+			"error": ferr.Message,
+		})
+	}
+	if target.ID == source.ID {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "A tag can't be merged into itself",
+		})
+	}
+
+	retagged, err := h.tagRepo.MergeTag(source, target)
+	if err != nil {
+		ferr := tagError(err, "Failed to merge tags")
+		return c.Status(ferr.Code).JSON(fiber.Map{
+			"error": ferr.Message,
+		})
+	}
+
+	merged, err := h.tagRepo.GetTagByID(target.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to retrieve tag",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message":       "Tags merged successfully",
+		"tag":           merged,
+		"items_updated": retagged,
+	})
+}
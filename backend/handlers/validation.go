@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"kyarafit-backend/models"
+)
+
+// validate checks request bodies against their validate struct tags
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New()
+
+	// Report fields by their JSON names
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+
+	// An empty date, link, ID or color clears an optional field, but
+	// omitempty only skips nil pointers, so these formats pass an empty
+	// string. Fields that can't be empty are caught by required or min.
+	formats := validator.New()
+	for _, tag := range []string{"datetime", "url", "uuid", "hexcolor"} {
+		tag := tag
+		if err := v.RegisterValidation(tag, func(fl validator.FieldLevel) bool {
+			value := fl.Field().String()
+			if value == "" {
+				return true
+			}
+			rule := tag
+			if fl.Param() != "" {
+				rule += "=" + fl.Param()
+			}
+			return formats.Var(value, rule) == nil
+		}); err != nil {
+			panic(err)
+		}
+	}
+
+	// iso4217 accepts the supported currencies, in any case, rather than
+	// every code in the standard
+	if err := v.RegisterValidation("iso4217", func(fl validator.FieldLevel) bool {
+		_, err := models.NormalizeCurrency(fl.Field().String())
+		return err == nil
+	}); err != nil {
+		panic(err)
+	}
+
+	// timezone takes an IANA name. The built-in rule also passes an empty
+	// string, which time.LoadLocation reads as UTC.
+	if err := v.RegisterValidation("timezone", func(fl validator.FieldLevel) bool {
+		tz := fl.Field().String()
+		if tz == "" || tz == "Local" {
+			return false
+		}
+		_, err := time.LoadLocation(tz)
+		return err == nil
+	}); err != nil {
+		panic(err)
+	}
+
+	return v
+}
+
+// parseBody reads a JSON request body into req and checks it against its
+// validate tags, then any rules spanning several fields. A body that can't
+// be read is a 400; one that breaks its rules is a *models.ValidationError,
+// which the error handler sends as a 422 listing every failed field.
+func parseBody(c *fiber.Ctx, req interface{}) error {
+	if err := c.BodyParser(req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+	return validateRequest(req)
+}
+
+// validateRequest checks a request against its validate tags, then any rules
+// spanning several fields
+func validateRequest(req interface{}) error {
+	if err := validate.Struct(req); err != nil {
+		var fieldErrs validator.ValidationErrors
+		if !errors.As(err, &fieldErrs) {
+			return fiber.NewError(fiber.StatusInternalServerError, "Failed to validate request")
+		}
+		verr := &models.ValidationError{}
+		for _, fe := range fieldErrs {
+			field := fieldPath(fe.Namespace())
+			verr.Fields = append(verr.Fields, models.FieldError{
+				Field:   field,
+				Code:    fe.Tag(),
+				Message: field + " " + ruleMessage(fe),
+			})
+		}
+		return verr
+	}
+
+	if crossField, ok := req.(models.CrossFieldValidator); ok {
+		if fields := crossField.ValidateFields(); len(fields) > 0 {
+			return &models.ValidationError{Fields: fields}
+		}
+	}
+
+	return nil
+}
+
+// fieldPath drops the struct name from a field namespace, leaving its JSON
+// path such as "budget.currency" or "layers[0].piece_id"
+func fieldPath(namespace string) string {
+	if _, path, ok := strings.Cut(namespace, "."); ok {
+		return path
+	}
+	return namespace
+}
+
+// ruleMessage describes the rule a field broke
+func ruleMessage(fe validator.FieldError) string {
+	param := fe.Param()
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "min", "max":
+		bound := "at least"
+		if fe.Tag() == "max" {
+			bound = "at most"
+		}
+		switch fe.Kind() {
+		case reflect.String:
+			if fe.Tag() == "min" && param == "1" {
+				return "must not be empty"
+			}
+			return fmt.Sprintf("must be %s %s characters", bound, param)
+		case reflect.Slice, reflect.Array, reflect.Map:
+			return fmt.Sprintf("must have %s %s items", bound, param)
+		default:
+			return fmt.Sprintf("must be %s %s", bound, param)
+		}
+	case "gt":
+		return "must be greater than " + param
+	case "oneof":
+		return "must be one of: " + strings.Join(strings.Fields(param), ", ")
+	case "datetime":
+		switch param {
+		case "2006-01-02":
+			return "must be a date in YYYY-MM-DD format"
+		case "15:04":
+			return "must be a time of day in HH:MM format"
+		}
+		return "must match the format " + param
+	case "email":
+		return "must be a valid email address"
+	case "url":
+		return "must be a valid URL"
+	case "uuid":
+		return "must be a valid UUID"
+	case "hexcolor":
+		return "must be a hex color such as #ff88aa"
+	case "iso4217":
+		return "must be a supported ISO 4217 currency code such as USD or JPY"
+	case "timezone":
+		return "must be an IANA time zone such as America/Los_Angeles"
+	default:
+		return "is invalid"
+	}
+}
@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// maxVocabularyName is the longest category or tag name
+const maxVocabularyName = 100
+
+var colorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// parseVocabularyName trims a category or tag name and checks its length
+func parseVocabularyName(name string) (string, *fiber.Error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", fiber.NewError(fiber.StatusBadRequest, "Name is required")
+	}
+	if len(name) > maxVocabularyName {
+		return "", fiber.NewError(fiber.StatusBadRequest, "Name must be 100 characters or fewer")
+	}
+	return name, nil
+}
+
+// parseColor checks a #RRGGBB color and lowercases it. An empty color parses
+// to nil, which clears it.
+func parseColor(color string) (*string, *fiber.Error) {
+	if color == "" {
+		return nil, nil
+	}
+	if !colorPattern.MatchString(color) {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Invalid color. Use #RRGGBB")
+	}
+	color = strings.ToLower(color)
+	return &color, nil
+}
@@ -0,0 +1,404 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"kyarafit-backend/database"
+	"kyarafit-backend/middleware"
+	"kyarafit-backend/models"
+)
+
+type WearLogsHandler struct {
+	wearLogRepo *database.WearLogRepository
+	pieceRepo   *database.PieceRepository
+	buildRepo   *database.BuildRepository
+}
+
+func NewWearLogsHandler(wearLogRepo *database.WearLogRepository, pieceRepo *database.PieceRepository, buildRepo *database.BuildRepository) *WearLogsHandler {
+	return &WearLogsHandler{
+		wearLogRepo: wearLogRepo,
+		pieceRepo:   pieceRepo,
+		buildRepo:   buildRepo,
+	}
+}
+
+// resolvePiece parses a piece ID and checks the piece belongs to the user.
+// An empty string resolves to no piece.
+func (h *WearLogsHandler) resolvePiece(idStr string, userUUID uuid.UUID) (*uuid.UUID, *fiber.Error) {
+	if idStr == "" {
+		return nil, nil
+	}
+	pieceID, err := uuid.Parse(idStr)
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Invalid piece ID")
+	}
+	piece, err := h.pieceRepo.GetPieceByID(pieceID)
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusNotFound, "Piece not found")
+	}
+	if piece.UserID != userUUID {
+		return nil, fiber.NewError(fiber.StatusForbidden, "Access denied")
+	}
+	return &piece.ID, nil
+}
+
+// resolveBuild parses a build ID and checks the build belongs to the user.
+// An empty string resolves to no build.
+func (h *WearLogsHandler) resolveBuild(idStr string, userUUID uuid.UUID) (*uuid.UUID, *fiber.Error) {
+	if idStr == "" {
+		return nil, nil
+	}
+	buildID, err := uuid.Parse(idStr)
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Invalid build ID")
+	}
+	build, err := h.buildRepo.GetBuildByID(buildID)
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusNotFound, "Build not found")
+	}
+	if build.UserID != userUUID {
+		return nil, fiber.NewError(fiber.StatusForbidden, "Access denied")
+	}
+	return &build.ID, nil
+}
+
+// CreateWearLog records that a piece and/or build was worn
+func (h *WearLogsHandler) CreateWearLog(c *fiber.Ctx) error {
+	principal, err := middleware.CurrentPrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	var req models.CreateWearLogRequest
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+
+	wearLog := &models.WearLog{
+		ID:              uuid.New(),
+		UserID:          principal.UserID,
+		Location:        req.Location,
+		EventName:       req.EventName,
+		DurationMinutes: req.DurationMinutes,
+		Notes:           req.Notes,
+		CreatedAt:       time.Now(),
+		UpdatedAt:       time.Now(),
+	}
+
+	if req.PieceID != nil {
+		pieceID, ferr := h.resolvePiece(*req.PieceID, principal.UserID)
+		if ferr != nil {
+			return c.Status(ferr.Code).JSON(fiber.Map{
+				"error": ferr.Message,
+			})
+		}
+		wearLog.PieceID = pieceID
+	}
+	if req.BuildID != nil {
+		buildID, ferr := h.resolveBuild(*req.BuildID, principal.UserID)
+		if ferr != nil {
+			return c.Status(ferr.Code).JSON(fiber.Map{
+				"error": ferr.Message,
+			})
+		}
+		wearLog.BuildID = buildID
+	}
+
+	wornOn, err := time.Parse("2006-01-02", req.WornOn)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid worn on date format. Use YYYY-MM-DD",
+		})
+	}
+	wearLog.WornOn = wornOn
+
+	if req.DurationMinutes != nil && *req.DurationMinutes < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Duration must not be negative",
+		})
+	}
+
+	if err := h.wearLogRepo.CreateWearLog(wearLog); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to create wear log",
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message":  "Wear log created successfully",
+		"wear_log": wearLog.ToResponse(),
+	})
+}
+
+// GetWearLogs retrieves all wear logs for the authenticated user
+func (h *WearLogsHandler) GetWearLogs(c *fiber.Ctx) error {
+	principal, err := middleware.CurrentPrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	limit, offset := parseLimitOffset(c)
+
+	wearLogs, err := h.wearLogRepo.GetWearLogsByUserID(principal.UserID, limit, offset)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to retrieve wear logs",
+		})
+	}
+
+	totalCount, err := h.wearLogRepo.GetWearLogCount(principal.UserID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to retrieve wear logs",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"wear_logs":   wearLogResponses(wearLogs),
+		"total_count": totalCount,
+		"limit":       limit,
+		"offset":      offset,
+	})
+}
+
+// GetWearLog retrieves a specific wear log by ID
+func (h *WearLogsHandler) GetWearLog(c *fiber.Ctx) error {
+	principal, err := middleware.CurrentPrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	wearLogID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid wear log ID",
+		})
+	}
+
+	wearLog, err := h.wearLogRepo.GetWearLogByID(wearLogID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Wear log not found",
+		})
+	}
+
+	if wearLog.UserID != principal.UserID {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Access denied",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"wear_log": wearLog.ToResponse(),
+	})
+}
+
+// UpdateWearLog updates an existing wear log
+func (h *WearLogsHandler) UpdateWearLog(c *fiber.Ctx) error {
+	principal, err := middleware.CurrentPrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	wearLogID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid wear log ID",
+		})
+	}
+
+	existingLog, err := h.wearLogRepo.GetWearLogByID(wearLogID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Wear log not found",
+		})
+	}
+
+	if existingLog.UserID != principal.UserID {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Access denied",
+		})
+	}
+
+	var req models.UpdateWearLogRequest
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+
+	// Update fields if provided
+	if req.PieceID != nil {
+		pieceID, ferr := h.resolvePiece(*req.PieceID, principal.UserID)
+		if ferr != nil {
+			return c.Status(ferr.Code).JSON(fiber.Map{
+				"error": ferr.Message,
+			})
+		}
+		existingLog.PieceID = pieceID
+	}
+	if req.BuildID != nil {
+		buildID, ferr := h.resolveBuild(*req.BuildID, principal.UserID)
+		if ferr != nil {
+			return c.Status(ferr.Code).JSON(fiber.Map{
+				"error": ferr.Message,
+			})
+		}
+		existingLog.BuildID = buildID
+	}
+	if existingLog.PieceID == nil && existingLog.BuildID == nil {
+		return &models.ValidationError{Fields: []models.FieldError{models.WearLogTargetRequired()}}
+	}
+	if req.WornOn != nil {
+		wornOn, err := time.Parse("2006-01-02", *req.WornOn)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid worn on date format. Use YYYY-MM-DD",
+			})
+		}
+		existingLog.WornOn = wornOn
+	}
+	if req.Location != nil {
+		existingLog.Location = req.Location
+	}
+	if req.EventName != nil {
+		existingLog.EventName = req.EventName
+	}
+	if req.DurationMinutes != nil {
+		if *req.DurationMinutes < 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Duration must not be negative",
+			})
+		}
+		existingLog.DurationMinutes = req.DurationMinutes
+	}
+	if req.Notes != nil {
+		existingLog.Notes = req.Notes
+	}
+
+	existingLog.UpdatedAt = time.Now()
+
+	if err := h.wearLogRepo.UpdateWearLog(existingLog); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to update wear log",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message":  "Wear log updated successfully",
+		"wear_log": existingLog.ToResponse(),
+	})
+}
+
+// DeleteWearLog deletes a wear log
+func (h *WearLogsHandler) DeleteWearLog(c *fiber.Ctx) error {
+	principal, err := middleware.CurrentPrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	wearLogID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid wear log ID",
+		})
+	}
+
+	if err := h.wearLogRepo.DeleteWearLog(wearLogID, principal.UserID); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Wear log not found or access denied",
+		})
+	}
+
+	return c.Status(fiber.StatusNoContent).JSON(fiber.Map{
+		"message": "Wear log deleted successfully",
+	})
+}
+
+// GetPieceWearLogs retrieves the wear history of a piece, including wears of builds it belongs to
+func (h *WearLogsHandler) GetPieceWearLogs(c *fiber.Ctx) error {
+	principal, err := middleware.CurrentPrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	pieceID, ferr := h.resolvePiece(c.Params("id"), principal.UserID)
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{
+			"error": ferr.Message,
+		})
+	}
+
+	limit, offset := parseLimitOffset(c)
+
+	wearLogs, err := h.wearLogRepo.GetWearLogsByPieceID(principal.UserID, *pieceID, limit, offset)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to retrieve wear logs",
+		})
+	}
+
+	summaries, err := h.wearLogRepo.GetPieceWearSummaries([]uuid.UUID{*pieceID})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to retrieve wear logs",
+		})
+	}
+	summary := summaries[*pieceID]
+
+	return c.JSON(fiber.Map{
+		"wear_logs":  wearLogResponses(wearLogs),
+		"times_worn": summary.TimesWorn,
+		"last_worn":  summary.LastWorn,
+		"limit":      limit,
+		"offset":     offset,
+	})
+}
+
+// GetBuildWearLogs retrieves the wear history of a build
+func (h *WearLogsHandler) GetBuildWearLogs(c *fiber.Ctx) error {
+	principal, err := middleware.CurrentPrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	buildID, ferr := h.resolveBuild(c.Params("id"), principal.UserID)
+	if ferr != nil {
+		return c.Status(ferr.Code).JSON(fiber.Map{
+			"error": ferr.Message,
+		})
+	}
+
+	limit, offset := parseLimitOffset(c)
+
+	wearLogs, err := h.wearLogRepo.GetWearLogsByBuildID(principal.UserID, *buildID, limit, offset)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to retrieve wear logs",
+		})
+	}
+
+	summaries, err := h.wearLogRepo.GetBuildWearSummaries([]uuid.UUID{*buildID})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to retrieve wear logs",
+		})
+	}
+	summary := summaries[*buildID]
+
+	return c.JSON(fiber.Map{
+		"wear_logs":  wearLogResponses(wearLogs),
+		"times_worn": summary.TimesWorn,
+		"last_worn":  summary.LastWorn,
+		"limit":      limit,
+		"offset":     offset,
+	})
+}
+
+func wearLogResponses(wearLogs []*models.WearLog) []models.WearLogResponse {
+	response := make([]models.WearLogResponse, 0, len(wearLogs))
+	for _, wearLog := range wearLogs {
+		response = append(response, wearLog.ToResponse())
+	}
+	return response
+}
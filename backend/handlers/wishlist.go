@@ -0,0 +1,422 @@
+package handlers
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"kyarafit-backend/database"
+	"kyarafit-backend/middleware"
+	"kyarafit-backend/models"
+)
+
+type WishlistHandler struct {
+	wishlistRepo *database.WishlistRepository
+	buildRepo    *database.BuildRepository
+	currencyRepo *database.CurrencyRepository
+}
+
+func NewWishlistHandler(wishlistRepo *database.WishlistRepository, buildRepo *database.BuildRepository, currencyRepo *database.CurrencyRepository) *WishlistHandler {
+	return &WishlistHandler{
+		wishlistRepo: wishlistRepo,
+		buildRepo:    buildRepo,
+		currencyRepo: currencyRepo,
+	}
+}
+
+// resolveBuild parses a build ID and checks the build belongs to the user.
+// An empty string resolves to no build.
+func (h *WishlistHandler) resolveBuild(idStr string, userUUID uuid.UUID) (*uuid.UUID, *fiber.Error) {
+	if idStr == "" {
+		return nil, nil
+	}
+	buildID, err := uuid.Parse(idStr)
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Invalid build ID")
+	}
+	build, err := h.buildRepo.GetBuildByID(buildID)
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusNotFound, "Build not found")
+	}
+	if build.UserID != userUUID {
+		return nil, fiber.NewError(fiber.StatusForbidden, "Access denied")
+	}
+	return &build.ID, nil
+}
+
+// parsePrice reads a wishlist price, in the user's home currency when it
+// names no currency. field names the price in errors.
+func (h *WishlistHandler) parsePrice(input models.MoneyInput, userID uuid.UUID, field string) (*models.Money, *fiber.Error) {
+	home, err := h.currencyRepo.GetHomeCurrency(userID)
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "Failed to retrieve home currency")
+	}
+
+	price, ferr := parseMoney(input, home, field)
+	if ferr != nil {
+		return nil, ferr
+	}
+	return &price, nil
+}
+
+// CreateWishlistItem adds a new item to the user's wishlist
+func (h *WishlistHandler) CreateWishlistItem(c *fiber.Ctx) error {
+	principal, err := middleware.CurrentPrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	var req models.CreateWishlistItemRequest
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+
+	item := &models.WishlistItem{
+		ID:          uuid.New(),
+		UserID:      principal.UserID,
+		Name:        req.Name,
+		Description: req.Description,
+		Category:    req.Category,
+		Tags:        req.Tags,
+		SourceLink:  req.SourceLink,
+		ImageURL:    req.ImageURL,
+		Priority:    3,
+		Status:      models.WishlistStatusActive,
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+	}
+
+	var ferr *fiber.Error
+	if req.TargetPrice != nil {
+		if item.TargetPrice, ferr = h.parsePrice(*req.TargetPrice, principal.UserID, "target_price"); ferr != nil {
+			return c.Status(ferr.Code).JSON(fiber.Map{
+				"error": ferr.Message,
+			})
+		}
+	}
+	if req.CurrentPrice != nil {
+		if item.CurrentPrice, ferr = h.parsePrice(*req.CurrentPrice, principal.UserID, "current_price"); ferr != nil {
+			return c.Status(ferr.Code).JSON(fiber.Map{
+				"error": ferr.Message,
+			})
+		}
+	}
+
+	if req.Priority != nil {
+		if *req.Priority < 1 || *req.Priority > 5 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Priority must be between 1 and 5",
+			})
+		}
+		item.Priority = *req.Priority
+	}
+
+	if req.BuildID != nil {
+		buildID, ferr := h.resolveBuild(*req.BuildID, principal.UserID)
+		if ferr != nil {
+			return c.Status(ferr.Code).JSON(fiber.Map{
+				"error": ferr.Message,
+			})
+		}
+		item.BuildID = buildID
+	}
+
+	if err := h.wishlistRepo.CreateWishlistItem(item); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to create wishlist item",
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message": "Wishlist item created successfully",
+		"item":    item.ToResponse(),
+	})
+}
+
+// GetWishlistItems retrieves the user's wishlist. Acquired items are only
+// listed with ?status=acquired.
+func (h *WishlistHandler) GetWishlistItems(c *fiber.Ctx) error {
+	principal, err := middleware.CurrentPrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	limit, offset := parseLimitOffset(c)
+
+	status := models.WishlistStatus(c.Query("status", string(models.WishlistStatusActive)))
+	if status != models.WishlistStatusActive && status != models.WishlistStatusAcquired {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Status must be active or acquired",
+		})
+	}
+
+	items, err := h.wishlistRepo.GetWishlistItemsByUserID(principal.UserID, status, limit, offset)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to retrieve wishlist items",
+		})
+	}
+
+	totalCount, err := h.wishlistRepo.GetWishlistItemCount(principal.UserID, status)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to retrieve wishlist items",
+		})
+	}
+
+	response := make([]models.WishlistItemResponse, 0, len(items))
+	for _, item := range items {
+		response = append(response, item.ToResponse())
+	}
+
+	return c.JSON(fiber.Map{
+		"items":       response,
+		"total_count": totalCount,
+		"limit":       limit,
+		"offset":      offset,
+	})
+}
+
+// GetWishlistItem retrieves a wishlist item together with its price history
+func (h *WishlistHandler) GetWishlistItem(c *fiber.Ctx) error {
+	principal, err := middleware.CurrentPrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	itemID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid wishlist item ID",
+		})
+	}
+
+	item, err := h.wishlistRepo.GetWishlistItemByID(itemID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Wishlist item not found",
+		})
+	}
+
+	if item.UserID != principal.UserID {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Access denied",
+		})
+	}
+
+	history, err := h.wishlistRepo.GetPriceHistory(item.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to retrieve price history",
+		})
+	}
+
+	response := item.ToResponse()
+	response.PriceHistory = history
+
+	return c.JSON(fiber.Map{
+		"item": response,
+	})
+}
+
+// UpdateWishlistItem updates a wishlist item, recording any new current price
+func (h *WishlistHandler) UpdateWishlistItem(c *fiber.Ctx) error {
+	principal, err := middleware.CurrentPrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	itemID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid wishlist item ID",
+		})
+	}
+
+	existingItem, err := h.wishlistRepo.GetWishlistItemByID(itemID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Wishlist item not found",
+		})
+	}
+
+	if existingItem.UserID != principal.UserID {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Access denied",
+		})
+	}
+
+	var req models.UpdateWishlistItemRequest
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+
+	// Update fields if provided
+	if req.Name != nil {
+		existingItem.Name = *req.Name
+	}
+	if req.Description != nil {
+		existingItem.Description = req.Description
+	}
+	if req.Category != nil {
+		existingItem.Category = req.Category
+	}
+	if req.Tags != nil {
+		existingItem.Tags = req.Tags
+	}
+	if req.SourceLink != nil {
+		existingItem.SourceLink = req.SourceLink
+	}
+	if req.ImageURL != nil {
+		existingItem.ImageURL = req.ImageURL
+	}
+	var ferr *fiber.Error
+	if req.TargetPrice != nil {
+		if existingItem.TargetPrice, ferr = h.parsePrice(*req.TargetPrice, principal.UserID, "target_price"); ferr != nil {
+			return c.Status(ferr.Code).JSON(fiber.Map{
+				"error": ferr.Message,
+			})
+		}
+	}
+	priceChanged := false
+	if req.CurrentPrice != nil {
+		price, ferr := h.parsePrice(*req.CurrentPrice, principal.UserID, "current_price")
+		if ferr != nil {
+			return c.Status(ferr.Code).JSON(fiber.Map{
+				"error": ferr.Message,
+			})
+		}
+		priceChanged = existingItem.CurrentPrice == nil || *existingItem.CurrentPrice != *price
+		existingItem.CurrentPrice = price
+	}
+	if req.Priority != nil {
+		if *req.Priority < 1 || *req.Priority > 5 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Priority must be between 1 and 5",
+			})
+		}
+		existingItem.Priority = *req.Priority
+	}
+	if req.BuildID != nil {
+		buildID, ferr := h.resolveBuild(*req.BuildID, principal.UserID)
+		if ferr != nil {
+			return c.Status(ferr.Code).JSON(fiber.Map{
+				"error": ferr.Message,
+			})
+		}
+		existingItem.BuildID = buildID
+	}
+
+	existingItem.UpdatedAt = time.Now()
+
+	if err := h.wishlistRepo.UpdateWishlistItem(existingItem, priceChanged); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to update wishlist item",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Wishlist item updated successfully",
+		"item":    existingItem.ToResponse(),
+	})
+}
+
+// AcquireWishlistItem turns a wishlist item into a piece in the user's closet
+func (h *WishlistHandler) AcquireWishlistItem(c *fiber.Ctx) error {
+	principal, err := middleware.CurrentPrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	itemID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid wishlist item ID",
+		})
+	}
+
+	item, err := h.wishlistRepo.GetWishlistItemByID(itemID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Wishlist item not found",
+		})
+	}
+
+	if item.UserID != principal.UserID {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Access denied",
+		})
+	}
+
+	// The body is optional
+	var req models.AcquireWishlistItemRequest
+	if len(c.Body()) > 0 {
+		if err := parseBody(c, &req); err != nil {
+			return err
+		}
+	}
+
+	var price *models.Money
+	if req.Price != nil {
+		var ferr *fiber.Error
+		if price, ferr = h.parsePrice(*req.Price, principal.UserID, "price"); ferr != nil {
+			return c.Status(ferr.Code).JSON(fiber.Map{
+				"error": ferr.Message,
+			})
+		}
+	}
+
+	var purchaseDate *time.Time
+	if req.PurchaseDate != nil {
+		parsedDate, err := time.Parse("2006-01-02", *req.PurchaseDate)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid purchase date format. Use YYYY-MM-DD",
+			})
+		}
+		purchaseDate = &parsedDate
+	}
+
+	piece, err := h.wishlistRepo.AcquireWishlistItem(item.ID, principal.UserID, price, purchaseDate, req.Remove)
+	if err != nil {
+		if errors.Is(err, database.ErrWishlistItemAcquired) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": "Wishlist item has already been acquired",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to acquire wishlist item",
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message": "Wishlist item acquired successfully",
+		"piece":   piece.ToResponse(),
+	})
+}
+
+// DeleteWishlistItem removes an item from the user's wishlist
+func (h *WishlistHandler) DeleteWishlistItem(c *fiber.Ctx) error {
+	principal, err := middleware.CurrentPrincipal(c)
+	if err != nil {
+		return err
+	}
+
+	itemID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid wishlist item ID",
+		})
+	}
+
+	if err := h.wishlistRepo.DeleteWishlistItem(itemID, principal.UserID); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Wishlist item not found or access denied",
+		})
+	}
+
+	return c.Status(fiber.StatusNoContent).JSON(fiber.Map{
+		"message": "Wishlist item deleted successfully",
+	})
+}
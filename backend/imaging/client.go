@@ -0,0 +1,98 @@
+package imaging
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// maxCutoutSize bounds how much of the image service's response is read
+const maxCutoutSize = 4 * MaxUploadSize
+
+// BackgroundRemover removes the background from an image and returns a PNG
+// cutout. Client calls the image service; tests can swap in a local stub.
+type BackgroundRemover interface {
+	RemoveBackground(ctx context.Context, data []byte, contentType string) ([]byte, error)
+}
+
+// Client talks to the Python image service
+type Client struct {
+	baseURL    string
+	model      string
+	httpClient *http.Client
+}
+
+// NewClient creates a client for the image service at baseURL. An empty model
+// uses the service's default rembg model.
+func NewClient(baseURL, model string) *Client {
+	return &Client{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		model:   model,
+		// rembg can take a while on large images, especially on first use of a model
+		httpClient: &http.Client{Timeout: 2 * time.Minute},
+	}
+}
+
+// RemoveBackground posts the image to /process/remove-background
+func (c *Client) RemoveBackground(ctx context.Context, data []byte, contentType string) ([]byte, error) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+
+	// The service rejects parts that don't carry an image content type, which
+	// CreateFormFile wouldn't set
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="file"; filename="upload.%s"`, Extensions[contentType]))
+	header.Set("Content-Type", contentType)
+	part, err := writer.CreatePart(header)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build image service request: %w", err)
+	}
+	if _, err := part.Write(data); err != nil {
+		return nil, fmt.Errorf("failed to build image service request: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("failed to build image service request: %w", err)
+	}
+
+	endpoint := c.baseURL + "/process/remove-background"
+	if c.model != "" {
+		endpoint += "?model=" + url.QueryEscape(c.model)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, &body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build image service request: %w", err)
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call image service: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("image service returned %d: %s", resp.StatusCode, strings.TrimSpace(string(message)))
+	}
+
+	cutout, err := io.ReadAll(io.LimitReader(resp.Body, maxCutoutSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image service response: %w", err)
+	}
+	if len(cutout) > maxCutoutSize {
+		return nil, fmt.Errorf("image service response is too large")
+	}
+	if http.DetectContentType(cutout) != "image/png" {
+		return nil, fmt.Errorf("image service did not return a PNG")
+	}
+
+	return cutout, nil
+}
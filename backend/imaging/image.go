@@ -0,0 +1,88 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	"image/png"
+	"net/http"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+const (
+	// MaxUploadSize is the largest image a client may upload
+	MaxUploadSize = 10 << 20
+	// ThumbnailSize is the longest edge of a generated thumbnail
+	ThumbnailSize = 400
+
+	// maxPixels guards against images that are small on disk but huge once decoded
+	maxPixels = 50_000_000
+)
+
+var (
+	// ErrUnsupportedType is returned for files that aren't JPEG, PNG or WebP images
+	ErrUnsupportedType = errors.New("unsupported image type")
+	// ErrTooLarge is returned for images whose dimensions exceed maxPixels
+	ErrTooLarge = errors.New("image dimensions are too large")
+)
+
+// Extensions maps the accepted content types to file extensions
+var Extensions = map[string]string{
+	"image/jpeg": "jpg",
+	"image/png":  "png",
+	"image/webp": "webp",
+}
+
+// DetectType sniffs the content type of an uploaded image from its bytes rather
+// than trusting the client, and checks that its header decodes
+func DetectType(data []byte) (string, error) {
+	contentType := http.DetectContentType(data)
+	if _, ok := Extensions[contentType]; !ok {
+		return "", ErrUnsupportedType
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return "", ErrUnsupportedType
+	}
+	if config.Width*config.Height > maxPixels {
+		return "", ErrTooLarge
+	}
+
+	return contentType, nil
+}
+
+// Thumbnail scales an image to fit within ThumbnailSize on its longest edge and
+// encodes it as a PNG, which keeps the transparency of background-removed cutouts.
+// Images already small enough are re-encoded at their original size.
+func Thumbnail(data []byte) ([]byte, error) {
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width > ThumbnailSize || height > ThumbnailSize {
+		if width >= height {
+			height = max(1, height*ThumbnailSize/width)
+			width = ThumbnailSize
+		} else {
+			width = max(1, width*ThumbnailSize/height)
+			height = ThumbnailSize
+		}
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, bounds, draw.Src, nil)
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, dst); err != nil {
+		return nil, fmt.Errorf("failed to encode thumbnail: %w", err)
+	}
+	return buf.Bytes(), nil
+}
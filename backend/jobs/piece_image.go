@@ -0,0 +1,95 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"kyarafit-backend/database"
+	"kyarafit-backend/imaging"
+	"kyarafit-backend/models"
+	"kyarafit-backend/storage"
+)
+
+// PieceImageResult is the result of a JobKindPieceImage job
+type PieceImageResult struct {
+	BackgroundRemoved bool `json:"background_removed"`
+	// Superseded is set when the piece was deleted or got a new image before
+	// the job finished, so its result was discarded
+	Superseded bool `json:"superseded,omitempty"`
+}
+
+// PieceImageHandler removes the background of a piece's uploaded image, then
+// stores the cutout and replaces the piece's placeholder thumbnail with one
+// made from the cutout
+type PieceImageHandler struct {
+	pieceRepo *database.PieceRepository
+	blobs     storage.BlobStore
+	remover   imaging.BackgroundRemover
+}
+
+func NewPieceImageHandler(pieceRepo *database.PieceRepository, blobs storage.BlobStore, remover imaging.BackgroundRemover) *PieceImageHandler {
+	return &PieceImageHandler{
+		pieceRepo: pieceRepo,
+		blobs:     blobs,
+		remover:   remover,
+	}
+}
+
+// Handle processes one piece image
+func (h *PieceImageHandler) Handle(ctx context.Context, job *models.Job, report func(progress int)) (interface{}, error) {
+	var payload models.PieceImageJob
+	if err := json.Unmarshal(job.Payload, &payload); err != nil {
+		return nil, Permanent(fmt.Errorf("invalid payload: %w", err))
+	}
+
+	piece, err := h.pieceRepo.GetPieceByID(payload.PieceID)
+	if err != nil {
+		if errors.Is(err, database.ErrPieceNotFound) {
+			return PieceImageResult{Superseded: true}, nil
+		}
+		return nil, err
+	}
+	if piece.ImageKey == nil || *piece.ImageKey != payload.ImageKey {
+		return PieceImageResult{Superseded: true}, nil
+	}
+
+	original, err := h.blobs.Get(ctx, payload.ImageKey)
+	if err != nil {
+		return nil, err
+	}
+	report(10)
+
+	cutout, err := h.remover.RemoveBackground(ctx, original, payload.ContentType)
+	if err != nil {
+		return nil, err
+	}
+	report(70)
+
+	thumbnail, err := imaging.Thumbnail(cutout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create thumbnail: %w", err)
+	}
+
+	keyPrefix := "users/" + piece.UserID.String()
+	cutoutKey, err := storage.PutContent(ctx, h.blobs, keyPrefix, cutout, "image/png", "png")
+	if err != nil {
+		return nil, err
+	}
+	thumbnailKey, err := storage.PutContent(ctx, h.blobs, keyPrefix, thumbnail, "image/png", "png")
+	if err != nil {
+		return nil, err
+	}
+	report(90)
+
+	applied, err := h.pieceRepo.SetPieceCutout(piece.ID, payload.ImageKey, cutoutKey, thumbnailKey)
+	if err != nil {
+		return nil, err
+	}
+	if !applied {
+		return PieceImageResult{Superseded: true}, nil
+	}
+
+	return PieceImageResult{BackgroundRemoved: true}, nil
+}
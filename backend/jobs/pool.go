@@ -0,0 +1,246 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"sync"
+	"time"
+
+	"kyarafit-backend/database"
+	"kyarafit-backend/models"
+)
+
+// Handler runs one kind of job. It reports progress (0-100) through report
+// and returns a result that is stored on the job as JSON.
+type Handler interface {
+	Handle(ctx context.Context, job *models.Job, report func(progress int)) (interface{}, error)
+}
+
+// permanentError marks a failure that retrying won't fix
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+// Permanent wraps err so the job goes straight to the dead-letter state
+// instead of being retried
+func Permanent(err error) error {
+	return permanentError{err: err}
+}
+
+// Config tunes a worker pool. Zero values fall back to the defaults.
+type Config struct {
+	// Workers is how many jobs run at once (default 2)
+	Workers int
+	// PollInterval is how long an idle worker waits before looking for work again (default 2s)
+	PollInterval time.Duration
+	// Lease is how long a running job may go without reporting progress before
+	// it's assumed abandoned and requeued (default 5m)
+	Lease time.Duration
+	// BaseBackoff is the wait before the first retry; each further retry waits
+	// twice as long, up to MaxBackoff (defaults 30s and 1h)
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+}
+
+// Pool runs queued jobs on a fixed number of workers
+type Pool struct {
+	repo     *database.JobRepository
+	config   Config
+	handlers map[string]Handler
+	wg       sync.WaitGroup
+}
+
+// NewPool creates a worker pool. Register handlers before calling Start.
+func NewPool(repo *database.JobRepository, config Config) *Pool {
+	if config.Workers <= 0 {
+		config.Workers = 2
+	}
+	if config.PollInterval <= 0 {
+		config.PollInterval = 2 * time.Second
+	}
+	if config.Lease <= 0 {
+		config.Lease = 5 * time.Minute
+	}
+	if config.BaseBackoff <= 0 {
+		config.BaseBackoff = 30 * time.Second
+	}
+	if config.MaxBackoff <= 0 {
+		config.MaxBackoff = time.Hour
+	}
+
+	return &Pool{
+		repo:     repo,
+		config:   config,
+		handlers: make(map[string]Handler),
+	}
+}
+
+// Register sets the handler for a kind of job
+func (p *Pool) Register(kind string, handler Handler) {
+	p.handlers[kind] = handler
+}
+
+// Start launches the workers. They stop taking new jobs once ctx is done;
+// Wait blocks until the jobs they're running have finished.
+func (p *Pool) Start(ctx context.Context) {
+	kinds := make([]string, 0, len(p.handlers))
+	for kind := range p.handlers {
+		kinds = append(kinds, kind)
+	}
+
+	for i := 0; i < p.config.Workers; i++ {
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			p.work(ctx, kinds)
+		}()
+	}
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		p.reap(ctx)
+	}()
+}
+
+// Wait blocks until every worker has stopped
+func (p *Pool) Wait() {
+	p.wg.Wait()
+}
+
+// work claims and runs jobs until ctx is done, sleeping whenever the queue is empty
+func (p *Pool) work(ctx context.Context, kinds []string) {
+	for {
+		if ctx.Err() != nil {
+			return
+		}
+
+		job, err := p.repo.ClaimJob(kinds)
+		if err != nil {
+			log.Printf("Failed to claim job: %v", err)
+		}
+		if job == nil {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(p.config.PollInterval):
+			}
+			continue
+		}
+
+		p.run(job)
+	}
+}
+
+// run executes a claimed job and records how it went. Jobs aren't tied to
+// the pool's context, so a shutdown lets the running ones finish.
+//
+// A job is cancelled once it goes a lease without reporting progress, as by
+// then it may be taken for abandoned and run again. Each report extends the
+// deadline, and a report that finds the job taken over cancels it at once.
+func (p *Pool) run(job *models.Job) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	deadline := time.AfterFunc(p.config.Lease, cancel)
+	defer deadline.Stop()
+
+	report := func(progress int) {
+		err := p.repo.UpdateJobProgress(job.ID, job.Attempts, progress)
+		switch {
+		case errors.Is(err, database.ErrJobLeaseLost):
+			cancel()
+		case err != nil:
+			log.Printf("Job %s: %v", job.ID, err)
+		default:
+			deadline.Reset(p.config.Lease)
+		}
+	}
+
+	result, err := p.handle(ctx, job, report)
+	if err == nil {
+		encoded, marshalErr := json.Marshal(result)
+		if marshalErr == nil {
+			p.record(job, p.repo.CompleteJob(job.ID, job.Attempts, encoded))
+			return
+		}
+		err = Permanent(fmt.Errorf("failed to encode result: %w", marshalErr))
+	}
+
+	var retryAt *time.Time
+	var permanent permanentError
+	if !errors.As(err, &permanent) && job.Attempts < job.MaxAttempts {
+		next := time.Now().Add(p.backoff(job.Attempts))
+		retryAt = &next
+		log.Printf("Job %s (%s) failed attempt %d of %d, retrying at %s: %v", job.ID, job.Kind, job.Attempts, job.MaxAttempts, next.Format(time.RFC3339), err)
+	} else {
+		log.Printf("Job %s (%s) is dead after %d attempts: %v", job.ID, job.Kind, job.Attempts, err)
+	}
+
+	p.record(job, p.repo.FailJob(job.ID, job.Attempts, err.Error(), retryAt))
+}
+
+// record logs the outcome of saving how a job went. A job that was taken
+// over has its outcome dropped, as the run that replaced it owns the job now.
+func (p *Pool) record(job *models.Job, err error) {
+	if errors.Is(err, database.ErrJobLeaseLost) {
+		log.Printf("Job %s (%s) attempt %d outlived its lease; its outcome was dropped", job.ID, job.Kind, job.Attempts)
+	} else if err != nil {
+		log.Printf("Job %s: %v", job.ID, err)
+	}
+}
+
+// handle runs the job's handler, turning a panic into a failed attempt
+func (p *Pool) handle(ctx context.Context, job *models.Job, report func(int)) (result interface{}, err error) {
+	handler, ok := p.handlers[job.Kind]
+	if !ok {
+		return nil, Permanent(fmt.Errorf("no handler for job kind %q", job.Kind))
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+
+	return handler.Handle(ctx, job, report)
+}
+
+// backoff returns the exponential wait after the given failed attempt, with
+// up to 20% jitter so jobs that failed together don't retry together
+func (p *Pool) backoff(attempt int) time.Duration {
+	wait := p.config.BaseBackoff
+	for i := 1; i < attempt && wait < p.config.MaxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > p.config.MaxBackoff {
+		wait = p.config.MaxBackoff
+	}
+	return wait + time.Duration(rand.Int63n(int64(wait)/5+1))
+}
+
+// reap requeues abandoned jobs once per lease until ctx is done
+func (p *Pool) reap(ctx context.Context) {
+	ticker := time.NewTicker(p.config.Lease)
+	defer ticker.Stop()
+
+	for {
+		if released, err := p.repo.RequeueStaleJobs(p.config.Lease); err != nil {
+			log.Printf("Failed to requeue stale jobs: %v", err)
+		} else if released > 0 {
+			log.Printf("Requeued %d abandoned jobs", released)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
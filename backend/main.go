@@ -1,15 +1,27 @@
 package main
 
 import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
 	"log"
 	"os"
+	"strconv"
+	"time"
+	_ "time/tzdata" // convention timezones must resolve even without system zoneinfo
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/joho/godotenv"
+	"kyarafit-backend/auth"
 	"kyarafit-backend/middleware"
 	"kyarafit-backend/database"
 	"kyarafit-backend/handlers"
+	"kyarafit-backend/imaging"
+	"kyarafit-backend/jobs"
+	"kyarafit-backend/models"
+	"kyarafit-backend/storage"
 )
 
 func main() {
@@ -29,9 +41,38 @@ func main() {
 		log.Fatal("Failed to run migrations:", err)
 	}
 
+	// Amounts can't be parsed or formatted until the currencies are known
+	currencyRepo := database.NewCurrencyRepository(database.DB)
+	if err := currencyRepo.LoadCurrencies(); err != nil {
+		log.Fatal("Failed to load currencies:", err)
+	}
+
+	// "load-exchange-rates <file.csv>" loads exchange rates and exits instead
+	// of starting the server
+	if len(os.Args) > 1 && os.Args[1] == "load-exchange-rates" {
+		if len(os.Args) != 3 {
+			log.Fatal("Usage: load-exchange-rates <file.csv>")
+		}
+		if err := loadExchangeRates(currencyRepo, os.Args[2]); err != nil {
+			log.Fatal("Failed to load exchange rates:", err)
+		}
+		return
+	}
+
 	// Create Fiber app
 	app := fiber.New(fiber.Config{
+		// Leave room for multipart overhead around the largest accepted image
+		BodyLimit: imaging.MaxUploadSize + 1<<20,
 		ErrorHandler: func(c *fiber.Ctx, err error) error {
+			// Requests breaking their rules list every failed field
+			var validationErr *models.ValidationError
+			if errors.As(err, &validationErr) {
+				return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+					"error":  "Validation failed",
+					"fields": validationErr.Fields,
+				})
+			}
+
 			code := fiber.StatusInternalServerError
 			if e, ok := err.(*fiber.Error); ok {
 				code = e.Code
@@ -50,21 +91,106 @@ func main() {
 		AllowCredentials: true,
 	}))
 
-	// JWT middleware configuration
+	// JWT middleware configuration. Tokens can be verified with a shared
+	// secret, a PEM public key file and/or a JWKS URL; with none of them the
+	// server refuses to start.
 	jwtSecret := os.Getenv("JWT_SECRET")
-	if jwtSecret == "" {
-		jwtSecret = "your-super-secret-jwt-key-here"
-	}
-	authMiddleware := middleware.NewJWTMiddleware(middleware.JWTConfig{
-		Secret: jwtSecret,
+	jwtIssuer := os.Getenv("JWT_ISSUER")
+	jwtAudience := os.Getenv("JWT_AUDIENCE")
+	authMiddleware, err := middleware.NewJWTMiddleware(middleware.JWTConfig{
+		Secret:              jwtSecret,
+		PublicKeyFile:       os.Getenv("JWT_PUBLIC_KEY_FILE"),
+		JWKSURL:             os.Getenv("JWT_JWKS_URL"),
+		JWKSRefreshInterval: durationEnv("JWT_JWKS_REFRESH_INTERVAL", time.Hour),
+		Issuer:              jwtIssuer,
+		Audience:            jwtAudience,
+		Leeway:              durationEnv("JWT_LEEWAY", 30*time.Second),
 	})
+	if err != nil {
+		log.Fatal("Failed to configure JWT verification:", err)
+	}
+
+	// Token issuance for the built-in account endpoints needs the shared secret
+	var tokenIssuer *auth.TokenIssuer
+	if jwtSecret != "" {
+		issuer := jwtIssuer
+		if issuer == "" {
+			issuer = "kyarafit-backend"
+		}
+		tokenIssuer = auth.NewTokenIssuer(auth.TokenConfig{
+			Secret:          jwtSecret,
+			Issuer:          issuer,
+			Audience:        jwtAudience,
+			AccessTokenTTL:  durationEnv("ACCESS_TOKEN_TTL", 15*time.Minute),
+			RefreshTokenTTL: durationEnv("REFRESH_TOKEN_TTL", 30*24*time.Hour),
+		})
+	}
 
 	// Initialize repositories and handlers
-	pieceRepo := database.NewPieceRepository(database.DB)
-	piecesHandler := handlers.NewPiecesHandler(pieceRepo)
+	userRepo := database.NewUserRepository(database.DB)
+	refreshTokenRepo := database.NewRefreshTokenRepository(database.DB)
+	var authHandler *handlers.AuthHandler
+	if tokenIssuer != nil {
+		authHandler = handlers.NewAuthHandler(userRepo, refreshTokenRepo, tokenIssuer)
+	}
+
+	wearLogRepo := database.NewWearLogRepository(database.DB)
+
+	// Uploaded images go to the local filesystem or an S3-compatible bucket
+	blobs, localStore, err := newBlobStore()
+	if err != nil {
+		log.Fatal("Failed to configure image storage:", err)
+	}
+
+	imageServiceURL := os.Getenv("IMAGE_SERVICE_URL")
+	if imageServiceURL == "" {
+		imageServiceURL = "http://localhost:8001"
+	}
+	imageClient := imaging.NewClient(imageServiceURL, os.Getenv("IMAGE_SERVICE_MODEL"))
+
+	pieceRepo := database.NewPieceRepository(database.DB, blobs)
+	jobRepo := database.NewJobRepository(database.DB)
+	piecesHandler := handlers.NewPiecesHandler(pieceRepo, wearLogRepo, jobRepo, currencyRepo, blobs)
+	jobsHandler := handlers.NewJobsHandler(jobRepo)
+
+	// Slow work such as background removal runs on a pool of workers fed
+	// from the jobs table
+	jobPool := jobs.NewPool(jobRepo, jobs.Config{
+		Workers:     intEnv("JOB_WORKERS", 2),
+		BaseBackoff: durationEnv("JOB_RETRY_BACKOFF", 30*time.Second),
+	})
+	jobPool.Register(models.JobKindPieceImage, jobs.NewPieceImageHandler(pieceRepo, blobs, imageClient))
+	jobPool.Start(context.Background())
 	
 	buildRepo := database.NewBuildRepository(database.DB)
-	buildsHandler := handlers.NewBuildsHandler(buildRepo)
+	buildPieceRepo := database.NewBuildPieceRepository(database.DB)
+	buildTaskRepo := database.NewBuildTaskRepository(database.DB)
+	buildsHandler := handlers.NewBuildsHandler(buildRepo, buildPieceRepo, buildTaskRepo, wearLogRepo, currencyRepo, blobs)
+	buildPiecesHandler := handlers.NewBuildPiecesHandler(buildRepo, pieceRepo, buildPieceRepo, blobs)
+	buildTasksHandler := handlers.NewBuildTasksHandler(buildRepo, pieceRepo, buildTaskRepo)
+	buildExpenseRepo := database.NewBuildExpenseRepository(database.DB)
+	buildExpensesHandler := handlers.NewBuildExpensesHandler(buildRepo, pieceRepo, buildExpenseRepo, currencyRepo)
+
+	wearLogsHandler := handlers.NewWearLogsHandler(wearLogRepo, pieceRepo, buildRepo)
+
+	coordRepo := database.NewCoordRepository(database.DB)
+	coordsHandler := handlers.NewCoordsHandler(coordRepo, pieceRepo, buildRepo, blobs)
+
+	wishlistRepo := database.NewWishlistRepository(database.DB)
+	wishlistHandler := handlers.NewWishlistHandler(wishlistRepo, buildRepo, currencyRepo)
+
+	conventionRepo := database.NewConventionRepository(database.DB)
+	conventionsHandler := handlers.NewConventionsHandler(conventionRepo, buildRepo, buildTaskRepo, coordRepo, blobs)
+
+	searchRepo := database.NewSearchRepository(database.DB)
+	searchHandler := handlers.NewSearchHandler(searchRepo)
+
+	categoryRepo := database.NewCategoryRepository(database.DB)
+	categoriesHandler := handlers.NewCategoriesHandler(categoryRepo)
+	tagRepo := database.NewTagRepository(database.DB)
+	tagsHandler := handlers.NewTagsHandler(tagRepo)
+
+	currencyHandler := handlers.NewCurrencyHandler(currencyRepo)
 
 	// Health check endpoint
 	app.Get("/health", func(c *fiber.Ctx) error {
@@ -74,8 +200,24 @@ func main() {
 		})
 	})
 
+	// Uploaded images in local storage, served through signed URLs
+	if localStore != nil {
+		app.Get("/uploads/*", handlers.NewUploadsHandler(localStore).ServeBlob)
+	}
+
 	// API routes
 	api := app.Group("/api/v1")
+
+	// Auth routes (public). Registered before the protected group so its
+	// middleware never runs for them.
+	if authHandler != nil {
+		api.Post("/auth/register", authHandler.Register)
+		api.Post("/auth/login", authHandler.Login)
+		api.Post("/auth/refresh", authHandler.Refresh)
+		api.Post("/auth/logout", authHandler.Logout)
+	} else {
+		log.Println("JWT_SECRET is not set; built-in account endpoints are disabled")
+	}
 	
 	// Protected routes (require authentication)
 	protected := api.Group("/", authMiddleware)
@@ -83,10 +225,14 @@ func main() {
 	// Pieces routes (protected)
 	protected.Get("/pieces", piecesHandler.GetPieces)
 	protected.Post("/pieces", piecesHandler.CreatePiece)
+	// Registered before /pieces/:id so these aren't taken for an ID
+	protected.Get("/pieces/categories", piecesHandler.GetCategories)
+	protected.Get("/pieces/stats", piecesHandler.GetPieceStats)
 	protected.Get("/pieces/:id", piecesHandler.GetPiece)
 	protected.Put("/pieces/:id", piecesHandler.UpdatePiece)
 	protected.Delete("/pieces/:id", piecesHandler.DeletePiece)
-	protected.Get("/pieces/categories", piecesHandler.GetCategories)
+	protected.Post("/pieces/:id/image", piecesHandler.UploadPieceImage)
+	protected.Get("/pieces/:id/wear-logs", wearLogsHandler.GetPieceWearLogs)
 	
 	// Legacy closet routes (redirect to pieces)
 	protected.Get("/closet", piecesHandler.GetPieces)
@@ -98,30 +244,98 @@ func main() {
 	// Build routes (protected)
 	protected.Get("/builds", buildsHandler.GetBuilds)
 	protected.Post("/builds", buildsHandler.CreateBuild)
+	// Registered before /builds/:id so "stats" isn't taken for an ID
+	protected.Get("/builds/stats", buildsHandler.GetBuildStats)
 	protected.Get("/builds/:id", buildsHandler.GetBuild)
 	protected.Put("/builds/:id", buildsHandler.UpdateBuild)
 	protected.Delete("/builds/:id", buildsHandler.DeleteBuild)
-	protected.Get("/builds/stats", buildsHandler.GetBuildStats)
+
+	// Build piece routes (protected)
+	protected.Get("/builds/:id/pieces", buildPiecesHandler.GetBuildPieces)
+	protected.Post("/builds/:id/pieces", buildPiecesHandler.AddBuildPiece)
+	protected.Put("/builds/:id/pieces/order", buildPiecesHandler.ReorderBuildPieces)
+	protected.Put("/builds/:id/pieces/:pieceId", buildPiecesHandler.UpdateBuildPiece)
+	protected.Delete("/builds/:id/pieces/:pieceId", buildPiecesHandler.RemoveBuildPiece)
+	protected.Get("/builds/:id/wear-logs", wearLogsHandler.GetBuildWearLogs)
+	protected.Get("/builds/:id/history", buildsHandler.GetBuildHistory)
+
+	// Build task routes (protected)
+	protected.Get("/builds/:id/tasks", buildTasksHandler.GetBuildTasks)
+	protected.Post("/builds/:id/tasks", buildTasksHandler.CreateBuildTask)
+	protected.Put("/builds/:id/tasks/order", buildTasksHandler.ReorderBuildTasks)
+	protected.Put("/builds/:id/tasks/:taskId", buildTasksHandler.UpdateBuildTask)
+	protected.Delete("/builds/:id/tasks/:taskId", buildTasksHandler.DeleteBuildTask)
+	protected.Get("/tasks", buildTasksHandler.GetTasks)
+
+	// Build expense routes (protected)
+	protected.Get("/builds/:id/expenses", buildExpensesHandler.GetBuildExpenses)
+	protected.Post("/builds/:id/expenses", buildExpensesHandler.CreateBuildExpense)
+	protected.Put("/builds/:id/expenses/:expenseId", buildExpensesHandler.UpdateBuildExpense)
+	protected.Delete("/builds/:id/expenses/:expenseId", buildExpensesHandler.DeleteBuildExpense)
+	protected.Get("/expenses/stats", buildExpensesHandler.GetExpenseStats)
+
+	// Wear log routes (protected)
+	protected.Get("/wear-logs", wearLogsHandler.GetWearLogs)
+	protected.Post("/wear-logs", wearLogsHandler.CreateWearLog)
+	protected.Get("/wear-logs/:id", wearLogsHandler.GetWearLog)
+	protected.Put("/wear-logs/:id", wearLogsHandler.UpdateWearLog)
+	protected.Delete("/wear-logs/:id", wearLogsHandler.DeleteWearLog)
 
 	// Coord routes (protected)
-	protected.Get("/coords", getCoords)
-	protected.Post("/coords", createCoord)
-	protected.Get("/coords/:id", getCoord)
-	protected.Put("/coords/:id", updateCoord)
-	protected.Delete("/coords/:id", deleteCoord)
+	protected.Get("/coords", coordsHandler.GetCoords)
+	protected.Post("/coords", coordsHandler.CreateCoord)
+	protected.Get("/coords/:id", coordsHandler.GetCoord)
+	protected.Put("/coords/:id", coordsHandler.UpdateCoord)
+	protected.Delete("/coords/:id", coordsHandler.DeleteCoord)
 
 	// Wishlist routes (protected)
-	protected.Get("/wishlist", getWishlistItems)
-	protected.Post("/wishlist", createWishlistItem)
-	protected.Put("/wishlist/:id", updateWishlistItem)
-	protected.Delete("/wishlist/:id", deleteWishlistItem)
+	protected.Get("/wishlist", wishlistHandler.GetWishlistItems)
+	protected.Post("/wishlist", wishlistHandler.CreateWishlistItem)
+	protected.Get("/wishlist/:id", wishlistHandler.GetWishlistItem)
+	protected.Put("/wishlist/:id", wishlistHandler.UpdateWishlistItem)
+	protected.Delete("/wishlist/:id", wishlistHandler.DeleteWishlistItem)
+	protected.Post("/wishlist/:id/acquire", wishlistHandler.AcquireWishlistItem)
 
 	// Convention routes (protected)
-	protected.Get("/conventions", getConventions)
-	protected.Post("/conventions", createConvention)
-	protected.Get("/conventions/:id", getConvention)
-	protected.Put("/conventions/:id", updateConvention)
-	protected.Delete("/conventions/:id", deleteConvention)
+	protected.Get("/conventions", conventionsHandler.GetConventions)
+	protected.Post("/conventions", conventionsHandler.CreateConvention)
+	protected.Get("/conventions/:id", conventionsHandler.GetConvention)
+	protected.Put("/conventions/:id", conventionsHandler.UpdateConvention)
+	protected.Delete("/conventions/:id", conventionsHandler.DeleteConvention)
+	protected.Get("/conventions/:id/at-risk-builds", conventionsHandler.GetAtRiskBuilds)
+	protected.Get("/conventions/:id/packing-list", conventionsHandler.GetPackingList)
+	protected.Put("/conventions/:id/packing-list/:pieceId", conventionsHandler.UpdatePackingItem)
+	protected.Get("/conventions/:id/schedule", conventionsHandler.GetSchedule)
+	protected.Post("/conventions/:id/schedule", conventionsHandler.CreateScheduleEntry)
+	protected.Put("/conventions/:id/schedule/:entryId", conventionsHandler.UpdateScheduleEntry)
+	protected.Delete("/conventions/:id/schedule/:entryId", conventionsHandler.DeleteScheduleEntry)
+
+	// Search routes
+	protected.Get("/search", searchHandler.Search)
+
+	// Category and tag vocabulary routes (protected)
+	protected.Get("/categories", categoriesHandler.GetCategories)
+	protected.Post("/categories", categoriesHandler.CreateCategory)
+	protected.Get("/categories/:id", categoriesHandler.GetCategory)
+	protected.Put("/categories/:id", categoriesHandler.UpdateCategory)
+	protected.Delete("/categories/:id", categoriesHandler.DeleteCategory)
+	protected.Post("/categories/:id/merge", categoriesHandler.MergeCategory)
+	protected.Get("/tags", tagsHandler.GetTags)
+	protected.Post("/tags", tagsHandler.CreateTag)
+	// Registered before /tags/:id so "autocomplete" isn't taken for an ID
+	protected.Get("/tags/autocomplete", tagsHandler.AutocompleteTags)
+	protected.Get("/tags/:id", tagsHandler.GetTag)
+	protected.Put("/tags/:id", tagsHandler.UpdateTag)
+	protected.Delete("/tags/:id", tagsHandler.DeleteTag)
+	protected.Post("/tags/:id/merge", tagsHandler.MergeTag)
+
+	// Home currency routes (protected)
+	protected.Get("/settings/currency", currencyHandler.GetHomeCurrency)
+	protected.Put("/settings/currency", currencyHandler.UpdateHomeCurrency)
+
+	// Job routes
+	protected.Get("/jobs/:id", jobsHandler.GetJob)
+	protected.Get("/jobs/:id/events", jobsHandler.JobEvents)
 
 	// Start server
 	port := os.Getenv("PORT")
@@ -133,119 +347,77 @@ func main() {
 	log.Fatal(app.Listen(":" + port))
 }
 
-// Placeholder handlers - implement these based on your data models
-func getClosetItems(c *fiber.Ctx) error {
-	userID := c.Locals("userID").(string)
-	return c.JSON(fiber.Map{
-		"items": []interface{}{},
-		"userID": userID,
-	})
-}
-
-func createClosetItem(c *fiber.Ctx) error {
-	userID := c.Locals("userID").(string)
-	return c.Status(201).JSON(fiber.Map{
-		"message": "Closet item created",
-		"userID": userID,
-	})
-}
-
-func getClosetItem(c *fiber.Ctx) error {
-	userID := c.Locals("userID").(string)
-	return c.JSON(fiber.Map{
-		"id": c.Params("id"),
-		"userID": userID,
-	})
-}
-
-func updateClosetItem(c *fiber.Ctx) error {
-	userID := c.Locals("userID").(string)
-	return c.JSON(fiber.Map{
-		"message": "Closet item updated",
-		"userID": userID,
-	})
-}
-
-func deleteClosetItem(c *fiber.Ctx) error {
-	userID := c.Locals("userID").(string)
-	return c.Status(204).JSON(fiber.Map{
-		"message": "Closet item deleted",
-		"userID": userID,
-	})
-}
-
-func getBuilds(c *fiber.Ctx) error {
-	return c.JSON(fiber.Map{"builds": []interface{}{}})
-}
-
-func createBuild(c *fiber.Ctx) error {
-	return c.Status(201).JSON(fiber.Map{"message": "Build created"})
-}
-
-func getBuild(c *fiber.Ctx) error {
-	return c.JSON(fiber.Map{"id": c.Params("id")})
-}
-
-func updateBuild(c *fiber.Ctx) error {
-	return c.JSON(fiber.Map{"message": "Build updated"})
-}
-
-func deleteBuild(c *fiber.Ctx) error {
-	return c.Status(204).Send(nil)
-}
-
-func getCoords(c *fiber.Ctx) error {
-	return c.JSON(fiber.Map{"coords": []interface{}{}})
-}
-
-func createCoord(c *fiber.Ctx) error {
-	return c.Status(201).JSON(fiber.Map{"message": "Coord created"})
-}
-
-func getCoord(c *fiber.Ctx) error {
-	return c.JSON(fiber.Map{"id": c.Params("id")})
-}
-
-func updateCoord(c *fiber.Ctx) error {
-	return c.JSON(fiber.Map{"message": "Coord updated"})
-}
-
-func deleteCoord(c *fiber.Ctx) error {
-	return c.Status(204).Send(nil)
-}
-
-func getWishlistItems(c *fiber.Ctx) error {
-	return c.JSON(fiber.Map{"items": []interface{}{}})
-}
-
-func createWishlistItem(c *fiber.Ctx) error {
-	return c.Status(201).JSON(fiber.Map{"message": "Wishlist item created"})
-}
-
-func updateWishlistItem(c *fiber.Ctx) error {
-	return c.JSON(fiber.Map{"message": "Wishlist item updated"})
-}
-
-func deleteWishlistItem(c *fiber.Ctx) error {
-	return c.Status(204).Send(nil)
-}
-
-func getConventions(c *fiber.Ctx) error {
-	return c.JSON(fiber.Map{"conventions": []interface{}{}})
-}
-
-func createConvention(c *fiber.Ctx) error {
-	return c.Status(201).JSON(fiber.Map{"message": "Convention created"})
+// durationEnv reads a duration such as "15m" from the environment
+func durationEnv(name string, fallback time.Duration) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		log.Fatalf("Invalid %s: %q", name, value)
+	}
+	return d
 }
 
-func getConvention(c *fiber.Ctx) error {
-	return c.JSON(fiber.Map{"id": c.Params("id")})
+// intEnv reads a positive integer from the environment
+func intEnv(name string, fallback int) int {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= 0 {
+		log.Fatalf("Invalid %s: %q", name, value)
+	}
+	return n
 }
 
-func updateConvention(c *fiber.Ctx) error {
-	return c.JSON(fiber.Map{"message": "Convention updated"})
-}
+// newBlobStore configures blob storage from STORAGE_BACKEND ("local" or "s3").
+// The local store is also returned so its files can be served.
+func newBlobStore() (storage.BlobStore, *storage.LocalStore, error) {
+	switch backend := os.Getenv("STORAGE_BACKEND"); backend {
+	case "", "local":
+		dir := os.Getenv("UPLOAD_DIR")
+		if dir == "" {
+			dir = "uploads"
+		}
+		baseURL := os.Getenv("UPLOAD_BASE_URL")
+		if baseURL == "" {
+			baseURL = "http://localhost:8080/uploads"
+		}
+
+		signingKey := []byte(os.Getenv("STORAGE_SIGNING_KEY"))
+		if len(signingKey) == 0 {
+			// Download URLs won't survive a restart, but nothing else depends on the key
+			log.Println("STORAGE_SIGNING_KEY is not set; using a random key for this run")
+			signingKey = make([]byte, 32)
+			if _, err := rand.Read(signingKey); err != nil {
+				return nil, nil, err
+			}
+		}
+
+		store, err := storage.NewLocalStore(dir, baseURL, signingKey)
+		if err != nil {
+			return nil, nil, err
+		}
+		return store, store, nil
+
+	case "s3":
+		store, err := storage.NewS3Store(storage.S3Config{
+			Endpoint:        os.Getenv("S3_ENDPOINT"),
+			Region:          os.Getenv("S3_REGION"),
+			Bucket:          os.Getenv("S3_BUCKET"),
+			AccessKeyID:     os.Getenv("S3_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("S3_SECRET_ACCESS_KEY"),
+			PathStyle:       os.Getenv("S3_PATH_STYLE") == "true",
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		return store, nil, nil
 
-func deleteConvention(c *fiber.Ctx) error {
-	return c.Status(204).Send(nil)
+	default:
+		return nil, nil, fmt.Errorf("unknown STORAGE_BACKEND %q", backend)
+	}
 }
@@ -1,19 +1,153 @@
 package middleware
 
 import (
+	"crypto"
+	"errors"
 	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/golang-jwt/jwt/v5"
 )
 
-// JWTConfig holds the JWT configuration
+// JWTConfig holds the JWT configuration. At least one of Secret,
+// PublicKeyFile and JWKSURL must be set.
 type JWTConfig struct {
+	// Secret verifies HS256/HS384/HS512 tokens
 	Secret string
+	// PublicKeyFile is a PEM file of RSA, ECDSA or Ed25519 public keys
+	PublicKeyFile string
+	// JWKSURL is a JSON Web Key Set document of public keys
+	JWKSURL string
+	// JWKSRefreshInterval is how long fetched JWKS keys are trusted before refetching (default 1h)
+	JWKSRefreshInterval time.Duration
+
+	// Issuer, when set, must match the iss claim
+	Issuer string
+	// Audience, when set, must be one of the aud claim's values
+	Audience string
+	// Leeway allows for clock skew when checking exp and nbf
+	Leeway time.Duration
 }
 
-// NewJWTMiddleware creates a new JWT middleware
-func NewJWTMiddleware(config JWTConfig) fiber.Handler {
+// ErrNoVerificationKey is returned when a JWTConfig has no secret or public key
+var ErrNoVerificationKey = errors.New("no JWT secret, public key file or JWKS URL configured")
+
+var (
+	hmacMethods       = []string{"HS256", "HS384", "HS512"}
+	asymmetricMethods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}
+)
+
+// tokenVerifier checks a token's signature and registered claims
+type tokenVerifier struct {
+	secret     []byte
+	staticKeys []crypto.PublicKey
+	jwks       *jwksCache
+	parser     *jwt.Parser
+}
+
+func newTokenVerifier(config JWTConfig) (*tokenVerifier, error) {
+	v := &tokenVerifier{}
+	var methods []string
+
+	if config.Secret != "" {
+		v.secret = []byte(config.Secret)
+		methods = append(methods, hmacMethods...)
+	}
+	if config.PublicKeyFile != "" {
+		keys, err := loadPEMKeys(config.PublicKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		v.staticKeys = keys
+	}
+	if config.JWKSURL != "" {
+		refreshInterval := config.JWKSRefreshInterval
+		if refreshInterval <= 0 {
+			refreshInterval = time.Hour
+		}
+		cache, err := newJWKSCache(config.JWKSURL, refreshInterval)
+		if err != nil {
+			return nil, err
+		}
+		v.jwks = cache
+	}
+	if v.staticKeys != nil || v.jwks != nil {
+		methods = append(methods, asymmetricMethods...)
+	}
+	if len(methods) == 0 {
+		return nil, ErrNoVerificationKey
+	}
+
+	options := []jwt.ParserOption{
+		jwt.WithValidMethods(methods),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(config.Leeway),
+	}
+	if config.Issuer != "" {
+		options = append(options, jwt.WithIssuer(config.Issuer))
+	}
+	if config.Audience != "" {
+		options = append(options, jwt.WithAudience(config.Audience))
+	}
+	v.parser = jwt.NewParser(options...)
+
+	return v, nil
+}
+
+// keyFunc picks the keys that can verify the token's signing method
+func (v *tokenVerifier) keyFunc(token *jwt.Token) (interface{}, error) {
+	if _, ok := token.Method.(*jwt.SigningMethodHMAC); ok {
+		if v.secret == nil {
+			return nil, errors.New("HMAC tokens are not accepted")
+		}
+		return v.secret, nil
+	}
+
+	candidates := v.staticKeys
+	if v.jwks != nil {
+		kid, _ := token.Header["kid"].(string)
+		candidates = append(append([]crypto.PublicKey{}, candidates...), v.jwks.lookup(kid)...)
+	}
+
+	keySet := jwt.VerificationKeySet{}
+	for _, key := range candidates {
+		if keyMatchesMethod(key, token.Method) {
+			keySet.Keys = append(keySet.Keys, key)
+		}
+	}
+	if len(keySet.Keys) == 0 {
+		return nil, errors.New("no key matches the token")
+	}
+	return keySet, nil
+}
+
+// verify parses and validates a token and returns the principal it identifies
+func (v *tokenVerifier) verify(tokenString string) (*Principal, error) {
+	claims := jwt.MapClaims{}
+	token, err := v.parser.ParseWithClaims(tokenString, claims, v.keyFunc)
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid {
+		return nil, errors.New("invalid token")
+	}
+
+	principal, err := newPrincipal(claims)
+	if err != nil {
+		return nil, errors.New("invalid user ID in token")
+	}
+	return principal, nil
+}
+
+// NewJWTMiddleware creates a new JWT middleware. It fails if the config has no
+// verification key, or if a configured key can't be loaded.
+func NewJWTMiddleware(config JWTConfig) (fiber.Handler, error) {
+	verifier, err := newTokenVerifier(config)
+	if err != nil {
+		return nil, err
+	}
+
 	return func(c *fiber.Ctx) error {
 		// Get the Authorization header
 		authHeader := c.Get("Authorization")
@@ -33,53 +167,28 @@ func NewJWTMiddleware(config JWTConfig) fiber.Handler {
 		// Extract the token
 		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
 
-		// Parse and validate the token
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			// Validate the signing method
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fiber.NewError(401, "Invalid signing method")
-			}
-			return []byte(config.Secret), nil
-		})
-
+		// Verify the signature and the iss, aud, exp and nbf claims
+		principal, err := verifier.verify(tokenString)
 		if err != nil {
 			return c.Status(401).JSON(fiber.Map{
 				"error": "Invalid token",
 			})
 		}
 
-		// Check if the token is valid
-		if !token.Valid {
-			return c.Status(401).JSON(fiber.Map{
-				"error": "Invalid token",
-			})
-		}
-
-		// Extract claims
-		claims, ok := token.Claims.(jwt.MapClaims)
-		if !ok {
-			return c.Status(401).JSON(fiber.Map{
-				"error": "Invalid token claims",
-			})
-		}
-
-		// Extract user ID from claims
-		userID, ok := claims["sub"].(string)
-		if !ok {
-			return c.Status(401).JSON(fiber.Map{
-				"error": "Invalid user ID in token",
-			})
-		}
-
-		// Store user ID in context
-		c.Locals("userID", userID)
+		// Store the principal in context
+		setPrincipal(c, principal)
 
 		return c.Next()
-	}
+	}, nil
 }
 
 // OptionalJWTMiddleware creates a JWT middleware that doesn't require authentication
-func OptionalJWTMiddleware(config JWTConfig) fiber.Handler {
+func OptionalJWTMiddleware(config JWTConfig) (fiber.Handler, error) {
+	verifier, err := newTokenVerifier(config)
+	if err != nil {
+		return nil, err
+	}
+
 	return func(c *fiber.Ctx) error {
 		// Get the Authorization header
 		authHeader := c.Get("Authorization")
@@ -95,39 +204,14 @@ func OptionalJWTMiddleware(config JWTConfig) fiber.Handler {
 		// Extract the token
 		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
 
-		// Parse and validate the token
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			// Validate the signing method
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fiber.NewError(401, "Invalid signing method")
-			}
-			return []byte(config.Secret), nil
-		})
-
+		principal, err := verifier.verify(tokenString)
 		if err != nil {
 			return c.Next()
 		}
 
-		// Check if the token is valid
-		if !token.Valid {
-			return c.Next()
-		}
-
-		// Extract claims
-		claims, ok := token.Claims.(jwt.MapClaims)
-		if !ok {
-			return c.Next()
-		}
-
-		// Extract user ID from claims
-		userID, ok := claims["sub"].(string)
-		if !ok {
-			return c.Next()
-		}
-
-		// Store user ID in context
-		c.Locals("userID", userID)
+		// Store the principal in context
+		setPrincipal(c, principal)
 
 		return c.Next()
-	}
+	}, nil
 }
@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// principalKey is the Locals key the authenticated Principal is stored under
+type principalKey struct{}
+
+// Principal is the authenticated caller of a request
+type Principal struct {
+	UserID uuid.UUID
+	Claims jwt.MapClaims
+	Scopes []string
+}
+
+// HasScope reports whether the token was granted scope
+func (p *Principal) HasScope(scope string) bool {
+	for _, s := range p.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// CurrentPrincipal returns the request's authenticated Principal, or a 401
+// error if the request didn't pass through the JWT middleware
+func CurrentPrincipal(c *fiber.Ctx) (*Principal, error) {
+	principal, ok := c.Locals(principalKey{}).(*Principal)
+	if !ok || principal == nil {
+		return nil, fiber.NewError(fiber.StatusUnauthorized, "User not authenticated")
+	}
+	return principal, nil
+}
+
+func setPrincipal(c *fiber.Ctx, principal *Principal) {
+	c.Locals(principalKey{}, principal)
+}
+
+// newPrincipal builds a Principal from validated token claims
+func newPrincipal(claims jwt.MapClaims) (*Principal, error) {
+	subject, err := claims.GetSubject()
+	if err != nil {
+		return nil, err
+	}
+	userID, err := uuid.Parse(subject)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Principal{
+		UserID: userID,
+		Claims: claims,
+		Scopes: scopesFromClaims(claims),
+	}, nil
+}
+
+// scopesFromClaims reads the space-separated scope claim, falling back to scp,
+// which some issuers send as a list
+func scopesFromClaims(claims jwt.MapClaims) []string {
+	if scope, ok := claims["scope"].(string); ok {
+		return strings.Fields(scope)
+	}
+
+	switch scp := claims["scp"].(type) {
+	case string:
+		return strings.Fields(scp)
+	case []interface{}:
+		scopes := make([]string, 0, len(scp))
+		for _, s := range scp {
+			if str, ok := s.(string); ok {
+				scopes = append(scopes, str)
+			}
+		}
+		return scopes
+	}
+
+	return nil
+}
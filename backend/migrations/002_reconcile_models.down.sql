@@ -0,0 +1,35 @@
+DROP INDEX IF EXISTS idx_builds_user_status;
+DROP INDEX IF EXISTS idx_builds_user_created;
+DROP INDEX IF EXISTS idx_pieces_user_created;
+
+-- Builds
+ALTER TABLE builds DROP CONSTRAINT IF EXISTS builds_status_check;
+
+ALTER TABLE builds ADD COLUMN budget_cents INTEGER;
+UPDATE builds SET budget_cents = ROUND(budget * 100) WHERE budget IS NOT NULL;
+
+ALTER TABLE builds DROP COLUMN IF EXISTS tags;
+ALTER TABLE builds DROP COLUMN IF EXISTS completed_date;
+ALTER TABLE builds DROP COLUMN IF EXISTS target_date;
+ALTER TABLE builds DROP COLUMN IF EXISTS start_date;
+ALTER TABLE builds DROP COLUMN IF EXISTS spent;
+ALTER TABLE builds DROP COLUMN IF EXISTS budget;
+ALTER TABLE builds DROP COLUMN IF EXISTS priority;
+ALTER TABLE builds DROP COLUMN IF EXISTS description;
+ALTER TABLE builds ALTER COLUMN series TYPE VARCHAR(120) USING LEFT(series, 120);
+ALTER TABLE builds ALTER COLUMN character TYPE VARCHAR(120) USING LEFT(character, 120);
+ALTER TABLE builds ALTER COLUMN name TYPE VARCHAR(120) USING LEFT(name, 120);
+ALTER TABLE builds RENAME COLUMN name TO title;
+
+-- Pieces
+ALTER TABLE pieces ADD COLUMN cost_cents INTEGER;
+UPDATE pieces SET cost_cents = ROUND(price * 100) WHERE price IS NOT NULL;
+
+ALTER TABLE pieces DROP COLUMN IF EXISTS price;
+ALTER TABLE pieces DROP COLUMN IF EXISTS source_link;
+ALTER TABLE pieces DROP COLUMN IF EXISTS tags;
+ALTER TABLE pieces DROP COLUMN IF EXISTS thumbnail_url;
+ALTER TABLE pieces RENAME COLUMN purchase_date TO acquired_at;
+ALTER TABLE pieces RENAME COLUMN description TO notes;
+ALTER TABLE pieces ALTER COLUMN category TYPE VARCHAR(60) USING LEFT(category, 60);
+ALTER TABLE pieces ALTER COLUMN name TYPE VARCHAR(120) USING LEFT(name, 120);
@@ -0,0 +1,41 @@
+-- Bring pieces and builds in line with models.Piece and models.Build.
+-- Existing data is carried across; columns without a model counterpart are kept.
+
+-- Pieces
+ALTER TABLE pieces ALTER COLUMN name TYPE VARCHAR(255);
+ALTER TABLE pieces ALTER COLUMN category TYPE VARCHAR(100);
+ALTER TABLE pieces RENAME COLUMN notes TO description;
+ALTER TABLE pieces RENAME COLUMN acquired_at TO purchase_date;
+ALTER TABLE pieces ADD COLUMN IF NOT EXISTS thumbnail_url TEXT;
+ALTER TABLE pieces ADD COLUMN IF NOT EXISTS tags TEXT[] DEFAULT '{}';
+ALTER TABLE pieces ADD COLUMN IF NOT EXISTS source_link TEXT;
+ALTER TABLE pieces ADD COLUMN IF NOT EXISTS price NUMERIC(10,2) CHECK (price >= 0);
+
+UPDATE pieces SET price = cost_cents / 100.0 WHERE cost_cents IS NOT NULL;
+ALTER TABLE pieces DROP COLUMN cost_cents;
+
+-- Builds
+ALTER TABLE builds RENAME COLUMN title TO name;
+ALTER TABLE builds ALTER COLUMN name TYPE VARCHAR(255);
+ALTER TABLE builds ALTER COLUMN character TYPE VARCHAR(255);
+ALTER TABLE builds ALTER COLUMN series TYPE VARCHAR(255);
+ALTER TABLE builds ADD COLUMN IF NOT EXISTS description TEXT;
+ALTER TABLE builds ADD COLUMN IF NOT EXISTS priority INTEGER CHECK (priority >= 1 AND priority <= 5);
+ALTER TABLE builds ADD COLUMN IF NOT EXISTS budget NUMERIC(10,2) CHECK (budget >= 0);
+ALTER TABLE builds ADD COLUMN IF NOT EXISTS spent NUMERIC(10,2) CHECK (spent >= 0);
+ALTER TABLE builds ADD COLUMN IF NOT EXISTS start_date DATE;
+ALTER TABLE builds ADD COLUMN IF NOT EXISTS target_date DATE;
+ALTER TABLE builds ADD COLUMN IF NOT EXISTS completed_date DATE;
+ALTER TABLE builds ADD COLUMN IF NOT EXISTS tags TEXT[] DEFAULT '{}';
+
+UPDATE builds SET budget = budget_cents / 100.0 WHERE budget_cents IS NOT NULL;
+ALTER TABLE builds DROP COLUMN budget_cents;
+
+-- target_event has no model field yet; it is left in place so no data is lost.
+
+ALTER TABLE builds ADD CONSTRAINT builds_status_check
+  CHECK (status IN ('idea', 'sourcing', 'wip', 'complete', 'on_hold', 'cancelled'));
+
+CREATE INDEX IF NOT EXISTS idx_pieces_user_created ON pieces (user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_builds_user_created ON builds (user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_builds_user_status ON builds (user_id, status);
@@ -0,0 +1,5 @@
+DROP TRIGGER IF EXISTS coord_layers_set_updated_at ON coord_layers;
+DROP TRIGGER IF EXISTS coords_set_updated_at ON coords;
+
+DROP TABLE IF EXISTS coord_layers;
+DROP TABLE IF EXISTS coords;
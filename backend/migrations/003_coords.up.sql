@@ -0,0 +1,38 @@
+-- Coords (a named outfit composed on the coord builder canvas)
+CREATE TABLE IF NOT EXISTS coords (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  name VARCHAR(255) NOT NULL,
+  description TEXT,
+  build_id UUID REFERENCES builds(id) ON DELETE SET NULL,
+  canvas_width INTEGER CHECK (canvas_width > 0),
+  canvas_height INTEGER CHECK (canvas_height > 0),
+  background_color VARCHAR(20),
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Layers placing a piece on a coord's canvas
+CREATE TABLE IF NOT EXISTS coord_layers (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  coord_id UUID NOT NULL REFERENCES coords(id) ON DELETE CASCADE,
+  piece_id UUID NOT NULL REFERENCES pieces(id) ON DELETE CASCADE,
+  z_index INTEGER NOT NULL DEFAULT 0,  -- higher layers are drawn on top
+  position_x DOUBLE PRECISION NOT NULL DEFAULT 0,
+  position_y DOUBLE PRECISION NOT NULL DEFAULT 0,
+  scale DOUBLE PRECISION NOT NULL DEFAULT 1 CHECK (scale > 0),
+  rotation DOUBLE PRECISION NOT NULL DEFAULT 0, -- degrees, clockwise
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TRIGGER coords_set_updated_at BEFORE UPDATE ON coords
+FOR EACH ROW EXECUTE FUNCTION set_updated_at();
+
+CREATE TRIGGER coord_layers_set_updated_at BEFORE UPDATE ON coord_layers
+FOR EACH ROW EXECUTE FUNCTION set_updated_at();
+
+CREATE INDEX IF NOT EXISTS idx_coords_user ON coords (user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_coords_build ON coords (build_id);
+CREATE INDEX IF NOT EXISTS idx_coord_layers_coord ON coord_layers (coord_id, z_index);
+CREATE INDEX IF NOT EXISTS idx_coord_layers_piece ON coord_layers (piece_id);
@@ -0,0 +1,4 @@
+DROP TRIGGER IF EXISTS wishlist_items_set_updated_at ON wishlist_items;
+
+DROP TABLE IF EXISTS wishlist_price_history;
+DROP TABLE IF EXISTS wishlist_items;
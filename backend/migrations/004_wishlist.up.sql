@@ -0,0 +1,35 @@
+-- Wishlist items (pieces the user wants to buy)
+CREATE TABLE IF NOT EXISTS wishlist_items (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  name VARCHAR(255) NOT NULL,
+  description TEXT,
+  category VARCHAR(100),
+  tags TEXT[] DEFAULT '{}',
+  source_link TEXT,
+  image_url TEXT,
+  target_price NUMERIC(10,2) CHECK (target_price >= 0),
+  current_price NUMERIC(10,2) CHECK (current_price >= 0),  -- latest entry in wishlist_price_history
+  priority INTEGER NOT NULL DEFAULT 3 CHECK (priority BETWEEN 1 AND 5),
+  build_id UUID REFERENCES builds(id) ON DELETE SET NULL,
+  status VARCHAR(20) NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'acquired')),
+  acquired_piece_id UUID REFERENCES pieces(id) ON DELETE SET NULL,
+  acquired_at TIMESTAMPTZ,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Every price observed for a wishlist item
+CREATE TABLE IF NOT EXISTS wishlist_price_history (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  wishlist_item_id UUID NOT NULL REFERENCES wishlist_items(id) ON DELETE CASCADE,
+  price NUMERIC(10,2) NOT NULL CHECK (price >= 0),
+  recorded_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TRIGGER wishlist_items_set_updated_at BEFORE UPDATE ON wishlist_items
+FOR EACH ROW EXECUTE FUNCTION set_updated_at();
+
+CREATE INDEX IF NOT EXISTS idx_wishlist_items_user_status ON wishlist_items (user_id, status, priority DESC, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_wishlist_items_build ON wishlist_items (build_id);
+CREATE INDEX IF NOT EXISTS idx_wishlist_price_history_item ON wishlist_price_history (wishlist_item_id, recorded_at DESC);
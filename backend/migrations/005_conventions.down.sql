@@ -0,0 +1,5 @@
+DROP TRIGGER IF EXISTS convention_schedule_entries_set_updated_at ON convention_schedule_entries;
+DROP TRIGGER IF EXISTS conventions_set_updated_at ON conventions;
+
+DROP TABLE IF EXISTS convention_schedule_entries;
+DROP TABLE IF EXISTS conventions;
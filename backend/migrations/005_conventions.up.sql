@@ -0,0 +1,48 @@
+-- Conventions the user is attending
+CREATE TABLE IF NOT EXISTS conventions (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  name VARCHAR(255) NOT NULL,
+  venue VARCHAR(255),
+  city VARCHAR(120),
+  start_date DATE NOT NULL,
+  end_date DATE NOT NULL,
+  timezone VARCHAR(64) NOT NULL DEFAULT 'UTC',  -- IANA name, e.g. America/Los_Angeles
+  hotel_name VARCHAR(255),
+  hotel_address TEXT,
+  hotel_confirmation VARCHAR(120),
+  website TEXT,
+  notes TEXT,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  CONSTRAINT conventions_dates_check CHECK (end_date >= start_date)
+);
+
+-- Timed entries on a convention's per-day schedule. Times are local to the convention's timezone.
+CREATE TABLE IF NOT EXISTS convention_schedule_entries (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  convention_id UUID NOT NULL REFERENCES conventions(id) ON DELETE CASCADE,
+  entry_type VARCHAR(20) NOT NULL DEFAULT 'cosplay' CHECK (entry_type IN ('cosplay', 'photoshoot', 'meetup', 'other')),
+  day DATE NOT NULL,
+  start_time TIME NOT NULL,
+  end_time TIME NOT NULL,
+  build_id UUID REFERENCES builds(id) ON DELETE SET NULL,
+  coord_id UUID REFERENCES coords(id) ON DELETE SET NULL,
+  title VARCHAR(255),
+  location VARCHAR(255),
+  notes TEXT,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  CONSTRAINT convention_schedule_entries_times_check CHECK (end_time > start_time)
+);
+
+CREATE TRIGGER conventions_set_updated_at BEFORE UPDATE ON conventions
+FOR EACH ROW EXECUTE FUNCTION set_updated_at();
+
+CREATE TRIGGER convention_schedule_entries_set_updated_at BEFORE UPDATE ON convention_schedule_entries
+FOR EACH ROW EXECUTE FUNCTION set_updated_at();
+
+CREATE INDEX IF NOT EXISTS idx_conventions_user_start ON conventions (user_id, start_date);
+CREATE INDEX IF NOT EXISTS idx_convention_schedule_day ON convention_schedule_entries (convention_id, day, start_time);
+CREATE INDEX IF NOT EXISTS idx_convention_schedule_build ON convention_schedule_entries (build_id);
+CREATE INDEX IF NOT EXISTS idx_convention_schedule_coord ON convention_schedule_entries (coord_id);
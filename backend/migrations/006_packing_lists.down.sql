@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS convention_packing_items_set_updated_at ON convention_packing_items;
+
+DROP TABLE IF EXISTS convention_packing_items;
@@ -0,0 +1,17 @@
+-- Packed state of the pieces on a convention's packing list. The list itself is
+-- derived from the builds on the convention's schedule; rows only exist once a
+-- piece has been ticked off or unticked.
+CREATE TABLE IF NOT EXISTS convention_packing_items (
+  convention_id UUID NOT NULL REFERENCES conventions(id) ON DELETE CASCADE,
+  piece_id UUID NOT NULL REFERENCES pieces(id) ON DELETE CASCADE,
+  packed BOOLEAN NOT NULL DEFAULT FALSE,
+  packed_at TIMESTAMPTZ,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (convention_id, piece_id)
+);
+
+CREATE TRIGGER convention_packing_items_set_updated_at BEFORE UPDATE ON convention_packing_items
+FOR EACH ROW EXECUTE FUNCTION set_updated_at();
+
+CREATE INDEX IF NOT EXISTS idx_convention_packing_items_piece ON convention_packing_items (piece_id);
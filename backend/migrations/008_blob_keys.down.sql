@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_pieces_thumbnail_key;
+DROP INDEX IF EXISTS idx_pieces_image_bg_removed_key;
+DROP INDEX IF EXISTS idx_pieces_image_key;
+
+ALTER TABLE pieces DROP COLUMN IF EXISTS thumbnail_key;
+ALTER TABLE pieces DROP COLUMN IF EXISTS image_bg_removed_key;
+ALTER TABLE pieces DROP COLUMN IF EXISTS image_key;
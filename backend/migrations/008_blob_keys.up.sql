@@ -0,0 +1,12 @@
+-- Storage keys of piece images uploaded through the backend. The *_url columns
+-- keep URLs that clients set directly; when a key is set, responses carry a
+-- signed download URL for it instead.
+ALTER TABLE pieces ADD COLUMN IF NOT EXISTS image_key TEXT;
+ALTER TABLE pieces ADD COLUMN IF NOT EXISTS image_bg_removed_key TEXT;
+ALTER TABLE pieces ADD COLUMN IF NOT EXISTS thumbnail_key TEXT;
+
+-- Keys are content-addressed, so pieces can share a blob. These let cleanup
+-- check whether a blob is still referenced before deleting it.
+CREATE INDEX IF NOT EXISTS idx_pieces_image_key ON pieces (image_key) WHERE image_key IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_pieces_image_bg_removed_key ON pieces (image_bg_removed_key) WHERE image_bg_removed_key IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_pieces_thumbnail_key ON pieces (thumbnail_key) WHERE thumbnail_key IS NOT NULL;
@@ -0,0 +1,23 @@
+DROP INDEX IF EXISTS idx_conventions_name_trgm;
+DROP INDEX IF EXISTS idx_wishlist_items_name_trgm;
+DROP INDEX IF EXISTS idx_coords_name_trgm;
+DROP INDEX IF EXISTS idx_builds_series_trgm;
+DROP INDEX IF EXISTS idx_builds_character_trgm;
+DROP INDEX IF EXISTS idx_builds_name_trgm;
+DROP INDEX IF EXISTS idx_pieces_name_trgm;
+
+DROP INDEX IF EXISTS idx_conventions_search;
+DROP INDEX IF EXISTS idx_wishlist_items_search;
+DROP INDEX IF EXISTS idx_coords_search;
+DROP INDEX IF EXISTS idx_builds_search;
+DROP INDEX IF EXISTS idx_pieces_search;
+
+ALTER TABLE conventions DROP COLUMN IF EXISTS search_vector;
+ALTER TABLE wishlist_items DROP COLUMN IF EXISTS search_vector;
+ALTER TABLE coords DROP COLUMN IF EXISTS search_vector;
+ALTER TABLE builds DROP COLUMN IF EXISTS search_vector;
+ALTER TABLE pieces DROP COLUMN IF EXISTS search_vector;
+
+DROP FUNCTION IF EXISTS tags_to_text(TEXT[]);
+
+-- pg_trgm is left installed; other objects may depend on it.
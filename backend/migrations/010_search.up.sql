@@ -0,0 +1,53 @@
+-- Full-text and fuzzy search. Each searchable table gets a generated tsvector
+-- of its text, weighted so name matches rank above description matches, and
+-- trigram indexes on the names people mistype.
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- array_to_string is only STABLE, which generated columns don't allow. Tags
+-- are plain text, so joining them is immutable in practice.
+CREATE OR REPLACE FUNCTION tags_to_text(tags TEXT[]) RETURNS TEXT
+  LANGUAGE sql IMMUTABLE PARALLEL SAFE
+  AS $$ SELECT array_to_string(tags, ' ') $$;
+
+ALTER TABLE pieces ADD COLUMN IF NOT EXISTS search_vector TSVECTOR GENERATED ALWAYS AS (
+  setweight(to_tsvector('english', coalesce(name, '')), 'A') ||
+  setweight(to_tsvector('english', coalesce(category, '') || ' ' || coalesce(tags_to_text(tags), '')), 'B') ||
+  setweight(to_tsvector('english', coalesce(description, '')), 'C')
+) STORED;
+
+ALTER TABLE builds ADD COLUMN IF NOT EXISTS search_vector TSVECTOR GENERATED ALWAYS AS (
+  setweight(to_tsvector('english', coalesce(name, '')), 'A') ||
+  setweight(to_tsvector('english', coalesce(character, '') || ' ' || coalesce(series, '') || ' ' || coalesce(tags_to_text(tags), '')), 'B') ||
+  setweight(to_tsvector('english', coalesce(description, '') || ' ' || coalesce(notes, '')), 'C')
+) STORED;
+
+ALTER TABLE coords ADD COLUMN IF NOT EXISTS search_vector TSVECTOR GENERATED ALWAYS AS (
+  setweight(to_tsvector('english', coalesce(name, '')), 'A') ||
+  setweight(to_tsvector('english', coalesce(description, '')), 'C')
+) STORED;
+
+ALTER TABLE wishlist_items ADD COLUMN IF NOT EXISTS search_vector TSVECTOR GENERATED ALWAYS AS (
+  setweight(to_tsvector('english', coalesce(name, '')), 'A') ||
+  setweight(to_tsvector('english', coalesce(category, '') || ' ' || coalesce(tags_to_text(tags), '')), 'B') ||
+  setweight(to_tsvector('english', coalesce(description, '')), 'C')
+) STORED;
+
+ALTER TABLE conventions ADD COLUMN IF NOT EXISTS search_vector TSVECTOR GENERATED ALWAYS AS (
+  setweight(to_tsvector('english', coalesce(name, '')), 'A') ||
+  setweight(to_tsvector('english', coalesce(venue, '') || ' ' || coalesce(city, '') || ' ' || coalesce(hotel_name, '')), 'B') ||
+  setweight(to_tsvector('english', coalesce(notes, '')), 'C')
+) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_pieces_search ON pieces USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS idx_builds_search ON builds USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS idx_coords_search ON coords USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS idx_wishlist_items_search ON wishlist_items USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS idx_conventions_search ON conventions USING GIN (search_vector);
+
+CREATE INDEX IF NOT EXISTS idx_pieces_name_trgm ON pieces USING GIN (name gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_builds_name_trgm ON builds USING GIN (name gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_builds_character_trgm ON builds USING GIN (character gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_builds_series_trgm ON builds USING GIN (series gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_coords_name_trgm ON coords USING GIN (name gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_wishlist_items_name_trgm ON wishlist_items USING GIN (name gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_conventions_name_trgm ON conventions USING GIN (name gin_trgm_ops);
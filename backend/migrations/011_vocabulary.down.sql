@@ -0,0 +1,13 @@
+DROP TRIGGER IF EXISTS builds_canonical_vocabulary ON builds;
+DROP TRIGGER IF EXISTS pieces_canonical_vocabulary ON pieces;
+
+DROP FUNCTION IF EXISTS builds_canonical_vocabulary();
+DROP FUNCTION IF EXISTS pieces_canonical_vocabulary();
+DROP FUNCTION IF EXISTS canonical_tags(UUID, TEXT[]);
+DROP FUNCTION IF EXISTS canonical_category(UUID, TEXT);
+
+DROP TRIGGER IF EXISTS tags_set_updated_at ON tags;
+DROP TRIGGER IF EXISTS categories_set_updated_at ON categories;
+
+DROP TABLE IF EXISTS tags;
+DROP TABLE IF EXISTS categories;
@@ -0,0 +1,118 @@
+-- Per-user vocabularies of piece categories and piece and build tags. Names
+-- are unique per user regardless of case. Pieces and builds keep storing the
+-- names as text; triggers register new names and rewrite any casing of a
+-- known name to its vocabulary spelling, so "Wig" and "wig" can't coexist.
+CREATE TABLE IF NOT EXISTS categories (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  name VARCHAR(100) NOT NULL CHECK (btrim(name) <> ''),
+  color VARCHAR(7) CHECK (color ~ '^#[0-9a-fA-F]{6}$'),
+  parent_id UUID REFERENCES categories(id) ON DELETE SET NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  CONSTRAINT categories_parent_check CHECK (parent_id <> id)
+);
+
+CREATE TABLE IF NOT EXISTS tags (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  name VARCHAR(100) NOT NULL CHECK (btrim(name) <> ''),
+  color VARCHAR(7) CHECK (color ~ '^#[0-9a-fA-F]{6}$'),
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_categories_user_name ON categories (user_id, lower(name));
+CREATE INDEX IF NOT EXISTS idx_categories_parent ON categories (parent_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tags_user_name ON tags (user_id, lower(name));
+-- Tag autocomplete matches prefixes and, failing that, similar names
+CREATE INDEX IF NOT EXISTS idx_tags_user_prefix ON tags (user_id, lower(name) text_pattern_ops);
+CREATE INDEX IF NOT EXISTS idx_tags_name_trgm ON tags USING GIN (name gin_trgm_ops);
+
+CREATE TRIGGER categories_set_updated_at BEFORE UPDATE ON categories
+FOR EACH ROW EXECUTE FUNCTION set_updated_at();
+
+CREATE TRIGGER tags_set_updated_at BEFORE UPDATE ON tags
+FOR EACH ROW EXECUTE FUNCTION set_updated_at();
+
+-- canonical_category registers a category name and returns its vocabulary spelling
+CREATE OR REPLACE FUNCTION canonical_category(owner UUID, raw TEXT) RETURNS TEXT AS $$
+DECLARE
+  canonical TEXT;
+BEGIN
+  IF raw IS NULL OR btrim(raw) = '' THEN
+    RETURN NULL;
+  END IF;
+
+  INSERT INTO categories (user_id, name) VALUES (owner, btrim(raw))
+  ON CONFLICT (user_id, lower(name)) DO NOTHING;
+
+  SELECT name INTO canonical FROM categories WHERE user_id = owner AND lower(name) = lower(btrim(raw));
+  RETURN canonical;
+END;
+$$ LANGUAGE plpgsql;
+
+-- canonical_tags registers tag names and returns them in their vocabulary
+-- spelling, in their original order with duplicates and blanks dropped
+CREATE OR REPLACE FUNCTION canonical_tags(owner UUID, raw TEXT[]) RETURNS TEXT[] AS $$
+BEGIN
+  IF raw IS NULL THEN
+    RETURN NULL;
+  END IF;
+
+  INSERT INTO tags (user_id, name)
+  SELECT DISTINCT ON (lower(btrim(t))) owner, btrim(t)
+  FROM unnest(raw) AS t
+  WHERE btrim(t) <> ''
+  ON CONFLICT (user_id, lower(name)) DO NOTHING;
+
+  RETURN ARRAY(
+    SELECT tg.name
+    FROM (
+      SELECT lower(btrim(t)) AS key, MIN(i) AS position
+      FROM unnest(raw) WITH ORDINALITY AS u(t, i)
+      WHERE btrim(t) <> ''
+      GROUP BY 1
+    ) u
+    JOIN tags tg ON tg.user_id = owner AND lower(tg.name) = u.key
+    ORDER BY u.position
+  );
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION pieces_canonical_vocabulary() RETURNS TRIGGER AS $$
+BEGIN
+  NEW.category := canonical_category(NEW.user_id, NEW.category);
+  NEW.tags := canonical_tags(NEW.user_id, NEW.tags);
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION builds_canonical_vocabulary() RETURNS TRIGGER AS $$
+BEGIN
+  NEW.tags := canonical_tags(NEW.user_id, NEW.tags);
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Backfill the vocabularies from existing pieces and builds without touching
+-- their updated_at
+ALTER TABLE pieces DISABLE TRIGGER pieces_set_updated_at;
+ALTER TABLE builds DISABLE TRIGGER builds_set_updated_at;
+
+UPDATE pieces
+SET category = canonical_category(user_id, category), tags = canonical_tags(user_id, tags)
+WHERE category IS NOT NULL OR cardinality(tags) > 0;
+
+UPDATE builds
+SET tags = canonical_tags(user_id, tags)
+WHERE cardinality(tags) > 0;
+
+ALTER TABLE pieces ENABLE TRIGGER pieces_set_updated_at;
+ALTER TABLE builds ENABLE TRIGGER builds_set_updated_at;
+
+CREATE TRIGGER pieces_canonical_vocabulary BEFORE INSERT OR UPDATE OF category, tags ON pieces
+FOR EACH ROW EXECUTE FUNCTION pieces_canonical_vocabulary();
+
+CREATE TRIGGER builds_canonical_vocabulary BEFORE INSERT OR UPDATE OF tags ON builds
+FOR EACH ROW EXECUTE FUNCTION builds_canonical_vocabulary();
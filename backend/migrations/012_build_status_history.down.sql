@@ -0,0 +1,3 @@
+ALTER TABLE builds DROP CONSTRAINT IF EXISTS builds_completed_date_check;
+
+DROP TABLE IF EXISTS build_status_history;
@@ -0,0 +1,31 @@
+-- Every change of a build's status, so the time builds spend in each stage can
+-- be measured. from_status is NULL for the status a build was created in.
+CREATE TABLE IF NOT EXISTS build_status_history (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  build_id UUID NOT NULL REFERENCES builds(id) ON DELETE CASCADE,
+  from_status VARCHAR(20)
+    CHECK (from_status IN ('idea', 'sourcing', 'wip', 'complete', 'on_hold', 'cancelled')),
+  to_status VARCHAR(20) NOT NULL
+    CHECK (to_status IN ('idea', 'sourcing', 'wip', 'complete', 'on_hold', 'cancelled')),
+  note TEXT,
+  changed_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_build_status_history_build ON build_status_history (build_id, changed_at);
+
+-- Existing builds have no record of earlier statuses, so their history starts
+-- in their current status when they were created
+INSERT INTO build_status_history (build_id, from_status, to_status, changed_at)
+SELECT id, NULL, status, created_at FROM builds;
+
+-- Only complete builds have a completed date. Builds already complete without
+-- one are taken to have been completed when last updated.
+ALTER TABLE builds DISABLE TRIGGER builds_set_updated_at;
+
+UPDATE builds SET completed_date = updated_at::date WHERE status = 'complete' AND completed_date IS NULL;
+UPDATE builds SET completed_date = NULL WHERE status <> 'complete' AND completed_date IS NOT NULL;
+
+ALTER TABLE builds ENABLE TRIGGER builds_set_updated_at;
+
+ALTER TABLE builds ADD CONSTRAINT builds_completed_date_check
+  CHECK ((status = 'complete') = (completed_date IS NOT NULL));
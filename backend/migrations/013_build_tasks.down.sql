@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS build_tasks_set_updated_at ON build_tasks;
+
+DROP TABLE IF EXISTS build_tasks;
@@ -0,0 +1,22 @@
+-- Tasks and milestones on a build's checklist, optionally about one of its pieces
+CREATE TABLE IF NOT EXISTS build_tasks (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  build_id UUID NOT NULL REFERENCES builds(id) ON DELETE CASCADE,
+  kind VARCHAR(20) NOT NULL DEFAULT 'task' CHECK (kind IN ('task', 'milestone')),
+  title VARCHAR(255) NOT NULL,
+  due_date DATE,
+  done BOOLEAN NOT NULL DEFAULT FALSE,
+  done_at TIMESTAMPTZ,
+  sort_order INTEGER NOT NULL DEFAULT 0,
+  piece_id UUID REFERENCES pieces(id) ON DELETE SET NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TRIGGER build_tasks_set_updated_at BEFORE UPDATE ON build_tasks
+FOR EACH ROW EXECUTE FUNCTION set_updated_at();
+
+CREATE INDEX IF NOT EXISTS idx_build_tasks_build ON build_tasks (build_id, sort_order);
+CREATE INDEX IF NOT EXISTS idx_build_tasks_piece ON build_tasks (piece_id);
+-- The task feed lists open tasks across a user's builds by due date
+CREATE INDEX IF NOT EXISTS idx_build_tasks_open_due ON build_tasks (due_date) WHERE NOT done;
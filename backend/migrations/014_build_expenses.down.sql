@@ -0,0 +1,14 @@
+-- builds.spent keeps the ledger total it had last
+DROP TRIGGER IF EXISTS pieces_sync_expenses ON pieces;
+DROP TRIGGER IF EXISTS build_pieces_sync_expenses ON build_pieces;
+DROP TRIGGER IF EXISTS build_expenses_refresh_spent ON build_expenses;
+
+DROP FUNCTION IF EXISTS pieces_sync_expenses();
+DROP FUNCTION IF EXISTS build_pieces_sync_expenses();
+DROP FUNCTION IF EXISTS build_expenses_refresh_spent();
+DROP FUNCTION IF EXISTS refresh_build_spent(UUID);
+DROP FUNCTION IF EXISTS sync_piece_expenses(UUID);
+
+DROP TRIGGER IF EXISTS build_expenses_set_updated_at ON build_expenses;
+
+DROP TABLE IF EXISTS build_expenses;
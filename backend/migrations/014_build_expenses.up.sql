@@ -0,0 +1,112 @@
+-- Itemized spending on a build. builds.spent is derived from this ledger.
+-- Entries with source 'piece' stand for the price of a piece linked to the
+-- build and are kept in step with the piece and the link by triggers.
+CREATE TABLE IF NOT EXISTS build_expenses (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  build_id UUID NOT NULL REFERENCES builds(id) ON DELETE CASCADE,
+  source VARCHAR(20) NOT NULL DEFAULT 'manual' CHECK (source IN ('manual', 'piece')),
+  build_piece_id UUID UNIQUE REFERENCES build_pieces(id) ON DELETE CASCADE,
+  amount NUMERIC(10,2) NOT NULL CHECK (amount >= 0),
+  currency CHAR(3) NOT NULL DEFAULT 'USD' CHECK (currency ~ '^[A-Z]{3}$'),
+  description VARCHAR(255),
+  vendor VARCHAR(255),
+  category VARCHAR(100),
+  spent_on DATE NOT NULL DEFAULT CURRENT_DATE,
+  piece_id UUID REFERENCES pieces(id) ON DELETE SET NULL,
+  notes TEXT,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  CHECK ((source = 'piece') = (build_piece_id IS NOT NULL))
+);
+
+CREATE TRIGGER build_expenses_set_updated_at BEFORE UPDATE ON build_expenses
+FOR EACH ROW EXECUTE FUNCTION set_updated_at();
+
+CREATE INDEX IF NOT EXISTS idx_build_expenses_build ON build_expenses (build_id, spent_on);
+CREATE INDEX IF NOT EXISTS idx_build_expenses_piece ON build_expenses (piece_id);
+
+-- sync_piece_expenses keeps one ledger entry per build link of a piece for as
+-- long as the piece has a price
+CREATE OR REPLACE FUNCTION sync_piece_expenses(target UUID) RETURNS VOID AS $$
+BEGIN
+  DELETE FROM build_expenses e
+  USING build_pieces bp, pieces p
+  WHERE e.build_piece_id = bp.id AND bp.piece_id = target AND p.id = target AND p.price IS NULL;
+
+  INSERT INTO build_expenses (build_id, source, build_piece_id, amount, description, category, spent_on, piece_id)
+  SELECT bp.build_id, 'piece', bp.id, p.price, p.name, p.category, COALESCE(p.purchase_date, bp.created_at::date), p.id
+  FROM build_pieces bp
+  JOIN pieces p ON p.id = bp.piece_id
+  WHERE bp.piece_id = target AND p.price IS NOT NULL
+  ON CONFLICT (build_piece_id) DO UPDATE
+  SET amount = EXCLUDED.amount, description = EXCLUDED.description, category = EXCLUDED.category, spent_on = EXCLUDED.spent_on
+  WHERE (build_expenses.amount, build_expenses.description, build_expenses.category, build_expenses.spent_on)
+    IS DISTINCT FROM (EXCLUDED.amount, EXCLUDED.description, EXCLUDED.category, EXCLUDED.spent_on);
+END;
+$$ LANGUAGE plpgsql;
+
+-- refresh_build_spent sets a build's spent to the total of its ledger, or NULL
+-- when nothing has been spent on it
+CREATE OR REPLACE FUNCTION refresh_build_spent(target UUID) RETURNS VOID AS $$
+BEGIN
+  UPDATE builds b
+  SET spent = totals.amount
+  FROM (SELECT SUM(amount) AS amount FROM build_expenses WHERE build_id = target) totals
+  WHERE b.id = target AND b.spent IS DISTINCT FROM totals.amount;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION build_expenses_refresh_spent() RETURNS TRIGGER AS $$
+BEGIN
+  IF TG_OP <> 'INSERT' THEN
+    PERFORM refresh_build_spent(OLD.build_id);
+  END IF;
+  IF TG_OP = 'INSERT' OR NEW.build_id <> OLD.build_id THEN
+    PERFORM refresh_build_spent(NEW.build_id);
+  END IF;
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION build_pieces_sync_expenses() RETURNS TRIGGER AS $$
+BEGIN
+  PERFORM sync_piece_expenses(NEW.piece_id);
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION pieces_sync_expenses() RETURNS TRIGGER AS $$
+BEGIN
+  PERFORM sync_piece_expenses(NEW.id);
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Move existing spending into the ledger without touching builds' updated_at.
+-- Linked pieces with a price get their entries, and whatever a build had
+-- spent beyond them is kept as a single opening entry.
+ALTER TABLE builds DISABLE TRIGGER builds_set_updated_at;
+
+SELECT sync_piece_expenses(p.id)
+FROM pieces p
+WHERE p.price IS NOT NULL AND EXISTS (SELECT 1 FROM build_pieces bp WHERE bp.piece_id = p.id);
+
+INSERT INTO build_expenses (build_id, amount, description, spent_on)
+SELECT b.id, b.spent - COALESCE(e.amount, 0), 'Spent before itemized expenses', COALESCE(b.start_date, b.created_at::date)
+FROM builds b
+LEFT JOIN (SELECT build_id, SUM(amount) AS amount FROM build_expenses GROUP BY build_id) e ON e.build_id = b.id
+WHERE b.spent > COALESCE(e.amount, 0);
+
+UPDATE builds b
+SET spent = (SELECT SUM(amount) FROM build_expenses e WHERE e.build_id = b.id);
+
+ALTER TABLE builds ENABLE TRIGGER builds_set_updated_at;
+
+CREATE TRIGGER build_expenses_refresh_spent AFTER INSERT OR UPDATE OF build_id, amount OR DELETE ON build_expenses
+FOR EACH ROW EXECUTE FUNCTION build_expenses_refresh_spent();
+
+CREATE TRIGGER build_pieces_sync_expenses AFTER INSERT ON build_pieces
+FOR EACH ROW EXECUTE FUNCTION build_pieces_sync_expenses();
+
+CREATE TRIGGER pieces_sync_expenses AFTER UPDATE OF name, price, category, purchase_date ON pieces
+FOR EACH ROW EXECUTE FUNCTION pieces_sync_expenses();
@@ -0,0 +1,89 @@
+-- Amounts go back to decimals in their own currency's major units; the
+-- currency of pieces and builds is dropped
+DROP TRIGGER IF EXISTS builds_convert_spent ON builds;
+DROP TRIGGER IF EXISTS pieces_sync_expenses ON pieces;
+DROP TRIGGER IF EXISTS build_expenses_refresh_spent ON build_expenses;
+DROP FUNCTION IF EXISTS builds_convert_spent();
+DROP FUNCTION IF EXISTS ledger_total(UUID, TEXT);
+
+ALTER TABLE builds DISABLE TRIGGER builds_set_updated_at;
+ALTER TABLE pieces DISABLE TRIGGER pieces_set_updated_at;
+
+-- Build expenses
+ALTER TABLE build_expenses DROP CONSTRAINT IF EXISTS build_expenses_currency_fkey;
+ALTER TABLE build_expenses ADD COLUMN IF NOT EXISTS amount NUMERIC(10,2) CHECK (amount >= 0);
+UPDATE build_expenses e
+SET amount = e.amount_minor / power(10::numeric, c.exponent)
+FROM currencies c
+WHERE c.code = e.currency;
+ALTER TABLE build_expenses ALTER COLUMN amount SET NOT NULL;
+ALTER TABLE build_expenses DROP COLUMN IF EXISTS amount_minor;
+
+-- Builds. spent is the plain ledger total again.
+ALTER TABLE builds ADD COLUMN IF NOT EXISTS budget NUMERIC(10,2) CHECK (budget >= 0);
+ALTER TABLE builds ADD COLUMN IF NOT EXISTS spent NUMERIC(10,2) CHECK (spent >= 0);
+UPDATE builds b
+SET budget = b.budget_minor / power(10::numeric, c.exponent)
+FROM currencies c
+WHERE c.code = b.currency AND b.budget_minor IS NOT NULL;
+UPDATE builds b
+SET spent = (SELECT SUM(amount) FROM build_expenses e WHERE e.build_id = b.id);
+ALTER TABLE builds DROP COLUMN IF EXISTS spent_minor;
+ALTER TABLE builds DROP COLUMN IF EXISTS budget_minor;
+ALTER TABLE builds DROP COLUMN IF EXISTS currency;
+
+-- Pieces
+ALTER TABLE pieces DROP CONSTRAINT IF EXISTS pieces_price_check;
+ALTER TABLE pieces ADD COLUMN IF NOT EXISTS price NUMERIC(10,2) CHECK (price >= 0);
+UPDATE pieces p
+SET price = p.price_minor / power(10::numeric, c.exponent)
+FROM currencies c
+WHERE c.code = p.price_currency;
+ALTER TABLE pieces DROP COLUMN IF EXISTS price_currency;
+ALTER TABLE pieces DROP COLUMN IF EXISTS price_minor;
+
+ALTER TABLE pieces ENABLE TRIGGER pieces_set_updated_at;
+ALTER TABLE builds ENABLE TRIGGER builds_set_updated_at;
+
+-- The ledger functions and triggers as 014 left them
+CREATE OR REPLACE FUNCTION sync_piece_expenses(target UUID) RETURNS VOID AS $$
+BEGIN
+  DELETE FROM build_expenses e
+  USING build_pieces bp, pieces p
+  WHERE e.build_piece_id = bp.id AND bp.piece_id = target AND p.id = target AND p.price IS NULL;
+
+  INSERT INTO build_expenses (build_id, source, build_piece_id, amount, description, category, spent_on, piece_id)
+  SELECT bp.build_id, 'piece', bp.id, p.price, p.name, p.category, COALESCE(p.purchase_date, bp.created_at::date), p.id
+  FROM build_pieces bp
+  JOIN pieces p ON p.id = bp.piece_id
+  WHERE bp.piece_id = target AND p.price IS NOT NULL
+  ON CONFLICT (build_piece_id) DO UPDATE
+  SET amount = EXCLUDED.amount, description = EXCLUDED.description, category = EXCLUDED.category, spent_on = EXCLUDED.spent_on
+  WHERE (build_expenses.amount, build_expenses.description, build_expenses.category, build_expenses.spent_on)
+    IS DISTINCT FROM (EXCLUDED.amount, EXCLUDED.description, EXCLUDED.category, EXCLUDED.spent_on);
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION refresh_build_spent(target UUID) RETURNS VOID AS $$
+BEGIN
+  UPDATE builds b
+  SET spent = totals.amount
+  FROM (SELECT SUM(amount) AS amount FROM build_expenses WHERE build_id = target) totals
+  WHERE b.id = target AND b.spent IS DISTINCT FROM totals.amount;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER build_expenses_refresh_spent AFTER INSERT OR UPDATE OF build_id, amount OR DELETE ON build_expenses
+FOR EACH ROW EXECUTE FUNCTION build_expenses_refresh_spent();
+
+CREATE TRIGGER pieces_sync_expenses AFTER UPDATE OF name, price, category, purchase_date ON pieces
+FOR EACH ROW EXECUTE FUNCTION pieces_sync_expenses();
+
+DROP FUNCTION IF EXISTS home_minor(UUID, BIGINT, TEXT, DATE);
+DROP FUNCTION IF EXISTS convert_minor(BIGINT, TEXT, TEXT, DATE);
+DROP FUNCTION IF EXISTS exchange_rate(TEXT, TEXT, DATE);
+
+ALTER TABLE users DROP COLUMN IF EXISTS home_currency;
+
+DROP TABLE IF EXISTS exchange_rates;
+DROP TABLE IF EXISTS currencies;
@@ -0,0 +1,182 @@
+-- Money is stored as an integer count of the currency's minor units (cents for
+-- USD, yen for JPY) next to its ISO 4217 code. Amounts recorded before this
+-- migration had no currency and are taken to be in US dollars, the default
+-- home currency.
+
+-- Currencies amounts may be recorded in, with the number of decimal places
+-- of their minor unit. The server loads this table at startup, so adding a
+-- row is enough to support another currency.
+CREATE TABLE IF NOT EXISTS currencies (
+  code CHAR(3) PRIMARY KEY CHECK (code ~ '^[A-Z]{3}$'),
+  exponent SMALLINT NOT NULL CHECK (exponent BETWEEN 0 AND 4)
+);
+
+INSERT INTO currencies (code, exponent) VALUES
+  ('AUD', 2), ('BHD', 3), ('BRL', 2), ('CAD', 2), ('CHF', 2), ('CLP', 0),
+  ('CNY', 2), ('CZK', 2), ('DKK', 2), ('EUR', 2), ('GBP', 2), ('HKD', 2),
+  ('HUF', 2), ('IDR', 2), ('ILS', 2), ('INR', 2), ('ISK', 0), ('JPY', 0),
+  ('KRW', 0), ('KWD', 3), ('MXN', 2), ('MYR', 2), ('NOK', 2), ('NZD', 2),
+  ('PHP', 2), ('PLN', 2), ('SEK', 2), ('SGD', 2), ('THB', 2), ('TRY', 2),
+  ('TWD', 2), ('USD', 2), ('VND', 0), ('ZAR', 2)
+ON CONFLICT (code) DO NOTHING;
+
+-- Expenses may already be in a currency missing from the list
+INSERT INTO currencies (code, exponent)
+SELECT DISTINCT currency, 2 FROM build_expenses
+ON CONFLICT (code) DO NOTHING;
+
+-- Exchange rates loaded by admins from a file. rate is how many units of
+-- quote one unit of base buys on effective_on.
+CREATE TABLE IF NOT EXISTS exchange_rates (
+  base CHAR(3) NOT NULL REFERENCES currencies(code),
+  quote CHAR(3) NOT NULL REFERENCES currencies(code),
+  effective_on DATE NOT NULL,
+  rate NUMERIC(24,12) NOT NULL CHECK (rate > 0),
+  loaded_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (base, quote, effective_on),
+  CHECK (base <> quote)
+);
+
+CREATE INDEX IF NOT EXISTS idx_exchange_rates_quote ON exchange_rates (quote, base, effective_on);
+
+-- The currency a user's stats are reported in and new amounts default to
+ALTER TABLE users ADD COLUMN IF NOT EXISTS home_currency CHAR(3) NOT NULL DEFAULT 'USD' REFERENCES currencies(code);
+
+-- exchange_rate returns how many units of dst one unit of src buys on a date.
+-- The rate is taken from a direct quote, an inverted one, or a cross rate
+-- through a base both currencies are quoted against on the same day. The
+-- latest rate on or before the date wins, falling back to the earliest one
+-- after it. It returns NULL when the pair has never been quoted.
+CREATE OR REPLACE FUNCTION exchange_rate(src TEXT, dst TEXT, on_date DATE) RETURNS NUMERIC AS $$
+  SELECT CASE WHEN src = dst THEN 1 ELSE (
+    SELECT rate
+    FROM (
+      SELECT r.rate, r.effective_on, 0 AS route
+      FROM exchange_rates r
+      WHERE r.base = src AND r.quote = dst
+      UNION ALL
+      SELECT 1 / r.rate, r.effective_on, 1
+      FROM exchange_rates r
+      WHERE r.base = dst AND r.quote = src
+      UNION ALL
+      SELECT q.rate / s.rate, s.effective_on, 2
+      FROM exchange_rates s
+      JOIN exchange_rates q ON q.base = s.base AND q.effective_on = s.effective_on
+      WHERE s.quote = src AND q.quote = dst
+    ) rates
+    ORDER BY effective_on > on_date, abs(effective_on - on_date), route
+    LIMIT 1
+  ) END
+$$ LANGUAGE sql STABLE;
+
+-- convert_minor converts an amount in minor units between currencies at the
+-- rate of a date, rounding to the nearest minor unit of dst. It returns NULL
+-- without a rate.
+CREATE OR REPLACE FUNCTION convert_minor(amount BIGINT, src TEXT, dst TEXT, on_date DATE) RETURNS BIGINT AS $$
+  SELECT CASE WHEN src = dst THEN amount ELSE (
+    SELECT round(amount * exchange_rate(src, dst, on_date) * power(10::numeric, d.exponent - s.exponent))::bigint
+    FROM currencies s, currencies d
+    WHERE s.code = src AND d.code = dst
+  ) END
+$$ LANGUAGE sql STABLE;
+
+-- home_minor converts an amount into the home currency of its owner
+CREATE OR REPLACE FUNCTION home_minor(owner UUID, amount BIGINT, currency TEXT, on_date DATE) RETURNS BIGINT AS $$
+  SELECT convert_minor(amount, currency, u.home_currency, on_date)
+  FROM users u
+  WHERE u.id = owner
+$$ LANGUAGE sql STABLE;
+
+-- The ledger triggers name columns replaced below and are recreated at the end
+DROP TRIGGER IF EXISTS pieces_sync_expenses ON pieces;
+DROP TRIGGER IF EXISTS build_expenses_refresh_spent ON build_expenses;
+
+ALTER TABLE builds DISABLE TRIGGER builds_set_updated_at;
+ALTER TABLE pieces DISABLE TRIGGER pieces_set_updated_at;
+
+-- Pieces
+ALTER TABLE pieces ADD COLUMN IF NOT EXISTS price_minor BIGINT CHECK (price_minor >= 0);
+ALTER TABLE pieces ADD COLUMN IF NOT EXISTS price_currency CHAR(3) REFERENCES currencies(code);
+UPDATE pieces SET price_minor = ROUND(price * 100), price_currency = 'USD' WHERE price IS NOT NULL;
+ALTER TABLE pieces DROP COLUMN IF EXISTS price;
+ALTER TABLE pieces ADD CONSTRAINT pieces_price_check CHECK ((price_minor IS NULL) = (price_currency IS NULL));
+
+-- Builds. A build's currency is the one its budget is set in, and spent is
+-- totalled in it.
+ALTER TABLE builds ADD COLUMN IF NOT EXISTS currency CHAR(3) NOT NULL DEFAULT 'USD' REFERENCES currencies(code);
+ALTER TABLE builds ADD COLUMN IF NOT EXISTS budget_minor BIGINT CHECK (budget_minor >= 0);
+ALTER TABLE builds ADD COLUMN IF NOT EXISTS spent_minor BIGINT CHECK (spent_minor >= 0);
+UPDATE builds SET budget_minor = ROUND(budget * 100) WHERE budget IS NOT NULL;
+ALTER TABLE builds DROP COLUMN IF EXISTS budget;
+ALTER TABLE builds DROP COLUMN IF EXISTS spent;
+
+-- Build expenses
+ALTER TABLE build_expenses ADD COLUMN IF NOT EXISTS amount_minor BIGINT CHECK (amount_minor >= 0);
+UPDATE build_expenses e
+SET amount_minor = ROUND(e.amount * power(10::numeric, c.exponent))
+FROM currencies c
+WHERE c.code = e.currency;
+ALTER TABLE build_expenses ALTER COLUMN amount_minor SET NOT NULL;
+ALTER TABLE build_expenses DROP COLUMN IF EXISTS amount;
+ALTER TABLE build_expenses ADD CONSTRAINT build_expenses_currency_fkey FOREIGN KEY (currency) REFERENCES currencies(code);
+
+-- Ledger entries of linked pieces take the piece's price and its currency
+CREATE OR REPLACE FUNCTION sync_piece_expenses(target UUID) RETURNS VOID AS $$
+BEGIN
+  DELETE FROM build_expenses e
+  USING build_pieces bp, pieces p
+  WHERE e.build_piece_id = bp.id AND bp.piece_id = target AND p.id = target AND p.price_minor IS NULL;
+
+  INSERT INTO build_expenses (build_id, source, build_piece_id, amount_minor, currency, description, category, spent_on, piece_id)
+  SELECT bp.build_id, 'piece', bp.id, p.price_minor, p.price_currency, p.name, p.category, COALESCE(p.purchase_date, bp.created_at::date), p.id
+  FROM build_pieces bp
+  JOIN pieces p ON p.id = bp.piece_id
+  WHERE bp.piece_id = target AND p.price_minor IS NOT NULL
+  ON CONFLICT (build_piece_id) DO UPDATE
+  SET amount_minor = EXCLUDED.amount_minor, currency = EXCLUDED.currency, description = EXCLUDED.description,
+    category = EXCLUDED.category, spent_on = EXCLUDED.spent_on
+  WHERE (build_expenses.amount_minor, build_expenses.currency, build_expenses.description, build_expenses.category, build_expenses.spent_on)
+    IS DISTINCT FROM (EXCLUDED.amount_minor, EXCLUDED.currency, EXCLUDED.description, EXCLUDED.category, EXCLUDED.spent_on);
+END;
+$$ LANGUAGE plpgsql;
+
+-- ledger_total totals a build's ledger in a currency, converting each entry
+-- at the rate of the day it was spent. Entries in a currency without a rate
+-- are left out until one is loaded.
+CREATE OR REPLACE FUNCTION ledger_total(target UUID, currency TEXT) RETURNS BIGINT AS $$
+  SELECT SUM(convert_minor(e.amount_minor, e.currency, ledger_total.currency, e.spent_on))::bigint
+  FROM build_expenses e
+  WHERE e.build_id = target
+$$ LANGUAGE sql STABLE;
+
+-- refresh_build_spent sets a build's spent to the total of its ledger in the
+-- build's currency, or NULL when nothing has been spent on it
+CREATE OR REPLACE FUNCTION refresh_build_spent(target UUID) RETURNS VOID AS $$
+BEGIN
+  UPDATE builds b
+  SET spent_minor = ledger_total(b.id, b.currency)
+  WHERE b.id = target AND b.spent_minor IS DISTINCT FROM ledger_total(b.id, b.currency);
+END;
+$$ LANGUAGE plpgsql;
+
+-- Moving a build to another currency totals its ledger again in the new one
+CREATE OR REPLACE FUNCTION builds_convert_spent() RETURNS TRIGGER AS $$
+BEGIN
+  NEW.spent_minor := ledger_total(NEW.id, NEW.currency);
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+SELECT refresh_build_spent(id) FROM builds;
+
+ALTER TABLE pieces ENABLE TRIGGER pieces_set_updated_at;
+ALTER TABLE builds ENABLE TRIGGER builds_set_updated_at;
+
+CREATE TRIGGER build_expenses_refresh_spent AFTER INSERT OR UPDATE OF build_id, amount_minor, currency, spent_on OR DELETE ON build_expenses
+FOR EACH ROW EXECUTE FUNCTION build_expenses_refresh_spent();
+
+CREATE TRIGGER pieces_sync_expenses AFTER UPDATE OF name, price_minor, price_currency, category, purchase_date ON pieces
+FOR EACH ROW EXECUTE FUNCTION pieces_sync_expenses();
+
+CREATE TRIGGER builds_convert_spent BEFORE UPDATE OF currency ON builds
+FOR EACH ROW WHEN (OLD.currency IS DISTINCT FROM NEW.currency) EXECUTE FUNCTION builds_convert_spent();
@@ -0,0 +1,30 @@
+DROP TRIGGER IF EXISTS wishlist_items_canonical_vocabulary ON wishlist_items;
+DROP FUNCTION IF EXISTS wishlist_items_canonical_vocabulary();
+
+-- canonical_tags goes back to keeping tags whole. Tags already cut to 100
+-- characters stay cut.
+CREATE OR REPLACE FUNCTION canonical_tags(owner UUID, raw TEXT[]) RETURNS TEXT[] AS $$
+BEGIN
+  IF raw IS NULL THEN
+    RETURN NULL;
+  END IF;
+
+  INSERT INTO tags (user_id, name)
+  SELECT DISTINCT ON (lower(btrim(t))) owner, btrim(t)
+  FROM unnest(raw) AS t
+  WHERE btrim(t) <> ''
+  ON CONFLICT (user_id, lower(name)) DO NOTHING;
+
+  RETURN ARRAY(
+    SELECT tg.name
+    FROM (
+      SELECT lower(btrim(t)) AS key, MIN(i) AS position
+      FROM unnest(raw) WITH ORDINALITY AS u(t, i)
+      WHERE btrim(t) <> ''
+      GROUP BY 1
+    ) u
+    JOIN tags tg ON tg.user_id = owner AND lower(tg.name) = u.key
+    ORDER BY u.position
+  );
+END;
+$$ LANGUAGE plpgsql;
@@ -0,0 +1,69 @@
+-- Tag names are limited to 100 characters. Tags saved before the limit are
+-- cut to it when they're canonicalized, rather than failing the write.
+CREATE OR REPLACE FUNCTION canonical_tags(owner UUID, raw TEXT[]) RETURNS TEXT[] AS $$
+DECLARE
+  cleaned TEXT[];
+BEGIN
+  IF raw IS NULL THEN
+    RETURN NULL;
+  END IF;
+
+  cleaned := ARRAY(
+    SELECT btrim(left(btrim(t), 100))
+    FROM unnest(raw) WITH ORDINALITY AS u(t, i)
+    ORDER BY i
+  );
+
+  INSERT INTO tags (user_id, name)
+  SELECT DISTINCT ON (lower(t)) owner, t
+  FROM unnest(cleaned) AS t
+  WHERE t <> ''
+  ON CONFLICT (user_id, lower(name)) DO NOTHING;
+
+  RETURN ARRAY(
+    SELECT tg.name
+    FROM (
+      SELECT lower(t) AS key, MIN(i) AS position
+      FROM unnest(cleaned) WITH ORDINALITY AS u(t, i)
+      WHERE t <> ''
+      GROUP BY 1
+    ) u
+    JOIN tags tg ON tg.user_id = owner AND lower(tg.name) = u.key
+    ORDER BY u.position
+  );
+END;
+$$ LANGUAGE plpgsql;
+
+-- Wishlist items share the tag vocabulary, so acquiring one copies known tags
+-- onto the new piece
+CREATE OR REPLACE FUNCTION wishlist_items_canonical_vocabulary() RETURNS TRIGGER AS $$
+BEGIN
+  NEW.tags := canonical_tags(NEW.user_id, NEW.tags);
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Cut over-long tags on pieces and builds, and bring wishlist tags into the
+-- vocabulary, without touching updated_at
+ALTER TABLE pieces DISABLE TRIGGER pieces_set_updated_at;
+ALTER TABLE builds DISABLE TRIGGER builds_set_updated_at;
+ALTER TABLE wishlist_items DISABLE TRIGGER wishlist_items_set_updated_at;
+
+UPDATE pieces
+SET tags = canonical_tags(user_id, tags)
+WHERE EXISTS (SELECT 1 FROM unnest(tags) AS t WHERE length(btrim(t)) > 100);
+
+UPDATE builds
+SET tags = canonical_tags(user_id, tags)
+WHERE EXISTS (SELECT 1 FROM unnest(tags) AS t WHERE length(btrim(t)) > 100);
+
+UPDATE wishlist_items
+SET tags = canonical_tags(user_id, tags)
+WHERE cardinality(tags) > 0;
+
+ALTER TABLE pieces ENABLE TRIGGER pieces_set_updated_at;
+ALTER TABLE builds ENABLE TRIGGER builds_set_updated_at;
+ALTER TABLE wishlist_items ENABLE TRIGGER wishlist_items_set_updated_at;
+
+CREATE TRIGGER wishlist_items_canonical_vocabulary BEFORE INSERT OR UPDATE OF tags ON wishlist_items
+FOR EACH ROW EXECUTE FUNCTION wishlist_items_canonical_vocabulary();
@@ -0,0 +1,25 @@
+DROP TRIGGER IF EXISTS build_expenses_sync_pieces ON build_expenses;
+DROP FUNCTION IF EXISTS build_expenses_sync_pieces();
+
+-- Linked pieces get their entries back alongside manual expenses naming them
+CREATE OR REPLACE FUNCTION sync_piece_expenses(target UUID) RETURNS VOID AS $$
+BEGIN
+  DELETE FROM build_expenses e
+  USING build_pieces bp, pieces p
+  WHERE e.build_piece_id = bp.id AND bp.piece_id = target AND p.id = target AND p.price_minor IS NULL;
+
+  INSERT INTO build_expenses (build_id, source, build_piece_id, amount_minor, currency, description, category, spent_on, piece_id)
+  SELECT bp.build_id, 'piece', bp.id, p.price_minor, p.price_currency, p.name, p.category, COALESCE(p.purchase_date, bp.created_at::date), p.id
+  FROM build_pieces bp
+  JOIN pieces p ON p.id = bp.piece_id
+  WHERE bp.piece_id = target AND p.price_minor IS NOT NULL
+  ON CONFLICT (build_piece_id) DO UPDATE
+  SET amount_minor = EXCLUDED.amount_minor, currency = EXCLUDED.currency, description = EXCLUDED.description,
+    category = EXCLUDED.category, spent_on = EXCLUDED.spent_on
+  WHERE (build_expenses.amount_minor, build_expenses.currency, build_expenses.description, build_expenses.category, build_expenses.spent_on)
+    IS DISTINCT FROM (EXCLUDED.amount_minor, EXCLUDED.currency, EXCLUDED.description, EXCLUDED.category, EXCLUDED.spent_on);
+END;
+$$ LANGUAGE plpgsql;
+
+SELECT sync_piece_expenses(m.piece_id)
+FROM (SELECT DISTINCT piece_id FROM build_expenses WHERE source = 'manual' AND piece_id IS NOT NULL) m;
@@ -0,0 +1,56 @@
+-- A manual expense naming a piece stands in for the piece's price on its
+-- build, so the entry kept for the piece's link is dropped while one does and
+-- the price is never counted twice. Syncs lock the piece, so a link and a
+-- manual expense made at the same time see each other.
+CREATE OR REPLACE FUNCTION sync_piece_expenses(target UUID) RETURNS VOID AS $$
+BEGIN
+  PERFORM 1 FROM pieces WHERE id = target FOR NO KEY UPDATE;
+
+  DELETE FROM build_expenses e
+  USING build_pieces bp, pieces p
+  WHERE e.build_piece_id = bp.id AND bp.piece_id = target AND p.id = target
+    AND (p.price_minor IS NULL OR EXISTS (
+      SELECT 1 FROM build_expenses m
+      WHERE m.build_id = bp.build_id AND m.piece_id = target AND m.source = 'manual'
+    ));
+
+  INSERT INTO build_expenses (build_id, source, build_piece_id, amount_minor, currency, description, category, spent_on, piece_id)
+  SELECT bp.build_id, 'piece', bp.id, p.price_minor, p.price_currency, p.name, p.category, COALESCE(p.purchase_date, bp.created_at::date), p.id
+  FROM build_pieces bp
+  JOIN pieces p ON p.id = bp.piece_id
+  WHERE bp.piece_id = target AND p.price_minor IS NOT NULL
+    AND NOT EXISTS (
+      SELECT 1 FROM build_expenses m
+      WHERE m.build_id = bp.build_id AND m.piece_id = target AND m.source = 'manual'
+    )
+  ON CONFLICT (build_piece_id) DO UPDATE
+  SET amount_minor = EXCLUDED.amount_minor, currency = EXCLUDED.currency, description = EXCLUDED.description,
+    category = EXCLUDED.category, spent_on = EXCLUDED.spent_on
+  WHERE (build_expenses.amount_minor, build_expenses.currency, build_expenses.description, build_expenses.category, build_expenses.spent_on)
+    IS DISTINCT FROM (EXCLUDED.amount_minor, EXCLUDED.currency, EXCLUDED.description, EXCLUDED.category, EXCLUDED.spent_on);
+END;
+$$ LANGUAGE plpgsql;
+
+-- Naming a piece on a manual expense, or no longer naming it, syncs the
+-- piece's entries
+CREATE OR REPLACE FUNCTION build_expenses_sync_pieces() RETURNS TRIGGER AS $$
+BEGIN
+  IF TG_OP = 'UPDATE' AND (OLD.build_id, OLD.piece_id) IS NOT DISTINCT FROM (NEW.build_id, NEW.piece_id) THEN
+    RETURN NULL;
+  END IF;
+  IF TG_OP <> 'INSERT' AND OLD.source = 'manual' AND OLD.piece_id IS NOT NULL THEN
+    PERFORM sync_piece_expenses(OLD.piece_id);
+  END IF;
+  IF TG_OP <> 'DELETE' AND NEW.source = 'manual' AND NEW.piece_id IS NOT NULL THEN
+    PERFORM sync_piece_expenses(NEW.piece_id);
+  END IF;
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Drop the entries of linked pieces that manual expenses already name
+SELECT sync_piece_expenses(m.piece_id)
+FROM (SELECT DISTINCT piece_id FROM build_expenses WHERE source = 'manual' AND piece_id IS NOT NULL) m;
+
+CREATE TRIGGER build_expenses_sync_pieces AFTER INSERT OR UPDATE OF build_id, piece_id OR DELETE ON build_expenses
+FOR EACH ROW EXECUTE FUNCTION build_expenses_sync_pieces();
@@ -0,0 +1,31 @@
+-- Prices go back to decimals in their own currency's major units; their
+-- currency is dropped
+ALTER TABLE wishlist_items DISABLE TRIGGER wishlist_items_set_updated_at;
+
+ALTER TABLE wishlist_items ADD COLUMN IF NOT EXISTS target_price NUMERIC(10,2) CHECK (target_price >= 0);
+ALTER TABLE wishlist_items ADD COLUMN IF NOT EXISTS current_price NUMERIC(10,2) CHECK (current_price >= 0);
+UPDATE wishlist_items w
+SET target_price = w.target_price_minor / power(10::numeric, c.exponent)
+FROM currencies c
+WHERE c.code = w.target_price_currency;
+UPDATE wishlist_items w
+SET current_price = w.current_price_minor / power(10::numeric, c.exponent)
+FROM currencies c
+WHERE c.code = w.current_price_currency;
+ALTER TABLE wishlist_items DROP CONSTRAINT IF EXISTS wishlist_items_target_price_check;
+ALTER TABLE wishlist_items DROP CONSTRAINT IF EXISTS wishlist_items_current_price_check;
+ALTER TABLE wishlist_items DROP COLUMN IF EXISTS target_price_currency;
+ALTER TABLE wishlist_items DROP COLUMN IF EXISTS target_price_minor;
+ALTER TABLE wishlist_items DROP COLUMN IF EXISTS current_price_currency;
+ALTER TABLE wishlist_items DROP COLUMN IF EXISTS current_price_minor;
+
+ALTER TABLE wishlist_items ENABLE TRIGGER wishlist_items_set_updated_at;
+
+ALTER TABLE wishlist_price_history ADD COLUMN IF NOT EXISTS price NUMERIC(10,2) CHECK (price >= 0);
+UPDATE wishlist_price_history h
+SET price = h.price_minor / power(10::numeric, c.exponent)
+FROM currencies c
+WHERE c.code = h.price_currency;
+ALTER TABLE wishlist_price_history ALTER COLUMN price SET NOT NULL;
+ALTER TABLE wishlist_price_history DROP COLUMN IF EXISTS price_currency;
+ALTER TABLE wishlist_price_history DROP COLUMN IF EXISTS price_minor;
@@ -0,0 +1,48 @@
+-- Wishlist prices are stored in minor units next to their currency, like
+-- piece prices. Prices recorded before this migration were read in their
+-- owner's home currency and are kept in it.
+ALTER TABLE wishlist_items DISABLE TRIGGER wishlist_items_set_updated_at;
+
+ALTER TABLE wishlist_items ADD COLUMN IF NOT EXISTS target_price_minor BIGINT CHECK (target_price_minor >= 0);
+ALTER TABLE wishlist_items ADD COLUMN IF NOT EXISTS target_price_currency CHAR(3) REFERENCES currencies(code);
+ALTER TABLE wishlist_items ADD COLUMN IF NOT EXISTS current_price_minor BIGINT CHECK (current_price_minor >= 0);
+ALTER TABLE wishlist_items ADD COLUMN IF NOT EXISTS current_price_currency CHAR(3) REFERENCES currencies(code);
+
+UPDATE wishlist_items w
+SET target_price_minor = ROUND(w.target_price * power(10::numeric, c.exponent)), target_price_currency = c.code
+FROM users u, currencies c
+WHERE u.id = w.user_id AND c.code = u.home_currency AND w.target_price IS NOT NULL;
+UPDATE wishlist_items w
+SET current_price_minor = ROUND(w.current_price * power(10::numeric, c.exponent)), current_price_currency = c.code
+FROM users u, currencies c
+WHERE u.id = w.user_id AND c.code = u.home_currency AND w.current_price IS NOT NULL;
+-- Owners without an account row have the default home currency
+UPDATE wishlist_items
+SET target_price_minor = ROUND(target_price * 100), target_price_currency = 'USD'
+WHERE target_price IS NOT NULL AND target_price_minor IS NULL;
+UPDATE wishlist_items
+SET current_price_minor = ROUND(current_price * 100), current_price_currency = 'USD'
+WHERE current_price IS NOT NULL AND current_price_minor IS NULL;
+
+ALTER TABLE wishlist_items DROP COLUMN IF EXISTS target_price;
+ALTER TABLE wishlist_items DROP COLUMN IF EXISTS current_price;
+ALTER TABLE wishlist_items ADD CONSTRAINT wishlist_items_target_price_check CHECK ((target_price_minor IS NULL) = (target_price_currency IS NULL));
+ALTER TABLE wishlist_items ADD CONSTRAINT wishlist_items_current_price_check CHECK ((current_price_minor IS NULL) = (current_price_currency IS NULL));
+
+ALTER TABLE wishlist_items ENABLE TRIGGER wishlist_items_set_updated_at;
+
+-- Price history
+ALTER TABLE wishlist_price_history ADD COLUMN IF NOT EXISTS price_minor BIGINT CHECK (price_minor >= 0);
+ALTER TABLE wishlist_price_history ADD COLUMN IF NOT EXISTS price_currency CHAR(3) REFERENCES currencies(code);
+UPDATE wishlist_price_history h
+SET price_minor = ROUND(h.price * power(10::numeric, c.exponent)), price_currency = c.code
+FROM wishlist_items w
+JOIN users u ON u.id = w.user_id
+JOIN currencies c ON c.code = u.home_currency
+WHERE w.id = h.wishlist_item_id;
+UPDATE wishlist_price_history
+SET price_minor = ROUND(price * 100), price_currency = 'USD'
+WHERE price_minor IS NULL;
+ALTER TABLE wishlist_price_history ALTER COLUMN price_minor SET NOT NULL;
+ALTER TABLE wishlist_price_history ALTER COLUMN price_currency SET NOT NULL;
+ALTER TABLE wishlist_price_history DROP COLUMN IF EXISTS price;
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
 	"github.com/google/uuid"
 )
@@ -17,6 +18,16 @@ const (
 	BuildStatusCancelled BuildStatus = "cancelled"
 )
 
+// BuildStatuses lists every build status in workflow order
+var BuildStatuses = []BuildStatus{
+	BuildStatusIdea,
+	BuildStatusSourcing,
+	BuildStatusWIP,
+	BuildStatusComplete,
+	BuildStatusOnHold,
+	BuildStatusCancelled,
+}
+
 // Build represents a cosplay build project
 type Build struct {
 	ID          uuid.UUID   `json:"id" db:"id"`
@@ -27,8 +38,10 @@ type Build struct {
 	Series      *string     `json:"series,omitempty" db:"series"`
 	Status      BuildStatus `json:"status" db:"status"`
 	Priority    *int        `json:"priority,omitempty" db:"priority"` // 1-5 scale
-	Budget      *float64    `json:"budget,omitempty" db:"budget"`
-	Spent       *float64    `json:"spent,omitempty" db:"spent"`
+	// Currency is the build's budget currency, which spent is totalled in
+	Currency    string      `json:"currency" db:"currency"`
+	Budget      *Money      `json:"budget,omitempty" db:"budget_minor"`
+	Spent       *Money      `json:"spent,omitempty" db:"spent_minor"` // total of the expense ledger
 	StartDate   *time.Time  `json:"start_date,omitempty" db:"start_date"`
 	TargetDate  *time.Time  `json:"target_date,omitempty" db:"target_date"`
 	CompletedDate *time.Time `json:"completed_date,omitempty" db:"completed_date"`
@@ -46,12 +59,15 @@ type CreateBuildRequest struct {
 	Series      *string     `json:"series,omitempty" validate:"omitempty,max=255"`
 	Status      *string     `json:"status,omitempty" validate:"omitempty,oneof=idea sourcing wip complete on_hold cancelled"`
 	Priority    *int        `json:"priority,omitempty" validate:"omitempty,min=1,max=5"`
-	Budget      *float64    `json:"budget,omitempty" validate:"omitempty,min=0"`
-	Spent       *float64    `json:"spent,omitempty" validate:"omitempty,min=0"`
+	// Currency defaults to the budget's currency, or else the home currency
+	Currency    *string     `json:"currency,omitempty" validate:"omitempty,iso4217"`
+	Budget      *MoneyInput `json:"budget,omitempty"` // in the build's currency without one
 	StartDate   *string     `json:"start_date,omitempty" validate:"omitempty,datetime=2006-01-02"`
 	TargetDate  *string     `json:"target_date,omitempty" validate:"omitempty,datetime=2006-01-02"`
-	Tags        []string    `json:"tags,omitempty"`
+	Tags        []string    `json:"tags,omitempty" validate:"omitempty,dive,max=100"`
 	Notes       *string     `json:"notes,omitempty" validate:"omitempty,max=2000"`
+	// Spent is totalled from the build's expenses, so it's rejected here
+	Spent       *json.RawMessage `json:"spent,omitempty"`
 }
 
 // UpdateBuildRequest represents the request payload for updating a build
@@ -62,13 +78,44 @@ type UpdateBuildRequest struct {
 	Series      *string     `json:"series,omitempty" validate:"omitempty,max=255"`
 	Status      *string     `json:"status,omitempty" validate:"omitempty,oneof=idea sourcing wip complete on_hold cancelled"`
 	Priority    *int        `json:"priority,omitempty" validate:"omitempty,min=1,max=5"`
-	Budget      *float64    `json:"budget,omitempty" validate:"omitempty,min=0"`
-	Spent       *float64    `json:"spent,omitempty" validate:"omitempty,min=0"`
+	// Currency moves the build to another currency. A build with a budget
+	// needs its budget set again in the new currency.
+	Currency    *string     `json:"currency,omitempty" validate:"omitempty,iso4217"`
+	Budget      *MoneyInput `json:"budget,omitempty"` // in the build's currency without one
 	StartDate   *string     `json:"start_date,omitempty" validate:"omitempty,datetime=2006-01-02"`
 	TargetDate  *string     `json:"target_date,omitempty" validate:"omitempty,datetime=2006-01-02"`
-	CompletedDate *string   `json:"completed_date,omitempty" validate:"omitempty,datetime=2006-01-02"`
-	Tags        []string    `json:"tags,omitempty"`
+	CompletedDate *string   `json:"completed_date,omitempty" validate:"omitempty,datetime=2006-01-02"` // only on complete builds
+	StatusNote  *string     `json:"status_note,omitempty" validate:"omitempty,max=1000"` // recorded with a status change
+	Tags        []string    `json:"tags,omitempty" validate:"omitempty,dive,max=100"`
 	Notes       *string     `json:"notes,omitempty" validate:"omitempty,max=2000"`
+	// Spent is totalled from the build's expenses, so it's rejected here
+	Spent       *json.RawMessage `json:"spent,omitempty"`
+}
+
+// ValidateFields checks the build's dates are in order and that it doesn't set spent
+func (r CreateBuildRequest) ValidateFields() []FieldError {
+	return validateBuildFields(r.StartDate, r.TargetDate, r.Spent)
+}
+
+// ValidateFields checks dates given together are in order and that it
+// doesn't set spent. Dates are checked against the saved build once merged.
+func (r UpdateBuildRequest) ValidateFields() []FieldError {
+	return validateBuildFields(r.StartDate, r.TargetDate, r.Spent)
+}
+
+func validateBuildFields(startDate, targetDate *string, spent *json.RawMessage) []FieldError {
+	var fields []FieldError
+	if fe := checkDateStrings(startDate, targetDate, "start_date", "target_date"); fe != nil {
+		fields = append(fields, *fe)
+	}
+	if spent != nil {
+		fields = append(fields, FieldError{
+			Field:   "spent",
+			Code:    "read_only",
+			Message: "spent is totalled from the build's expenses; record an expense instead",
+		})
+	}
+	return fields
 }
 
 // BuildResponse represents the response format for build data
@@ -81,19 +128,27 @@ type BuildResponse struct {
 	Series        *string     `json:"series,omitempty"`
 	Status        BuildStatus `json:"status"`
 	Priority      *int        `json:"priority,omitempty"`
-	Budget        *float64    `json:"budget,omitempty"`
-	Spent         *float64    `json:"spent,omitempty"`
+	Currency      string      `json:"currency"`
+	Budget        *Money      `json:"budget,omitempty"`
+	Spent         *Money      `json:"spent,omitempty"`
+	BudgetRemaining *Money    `json:"budget_remaining,omitempty"`
+	BudgetWarning *BudgetWarning `json:"budget_warning,omitempty"`
 	StartDate     *time.Time  `json:"start_date,omitempty"`
 	TargetDate    *time.Time  `json:"target_date,omitempty"`
 	CompletedDate *time.Time  `json:"completed_date,omitempty"`
 	Tags          []string    `json:"tags,omitempty"`
 	Notes         *string     `json:"notes,omitempty"`
+	TimesWorn     int         `json:"times_worn"`
+	LastWorn      *time.Time  `json:"last_worn,omitempty"`
+	ProgressPercent int       `json:"progress_percent"` // share of tasks done
+	Pieces        []BuildPieceResponse `json:"pieces,omitempty"`
 	CreatedAt     time.Time   `json:"created_at"`
 	UpdatedAt     time.Time   `json:"updated_at"`
 }
 
 // ToResponse converts a Build model to BuildResponse
 func (b *Build) ToResponse() BuildResponse {
+	remaining, warning := b.budgetStatus()
 	return BuildResponse{
 		ID:            b.ID,
 		UserID:        b.UserID,
@@ -103,8 +158,11 @@ func (b *Build) ToResponse() BuildResponse {
 		Series:        b.Series,
 		Status:        b.Status,
 		Priority:      b.Priority,
+		Currency:      b.Currency,
 		Budget:        b.Budget,
 		Spent:         b.Spent,
+		BudgetRemaining: remaining,
+		BudgetWarning: warning,
 		StartDate:     b.StartDate,
 		TargetDate:    b.TargetDate,
 		CompletedDate: b.CompletedDate,
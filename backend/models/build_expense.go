@@ -0,0 +1,138 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ExpenseSource says where a ledger entry came from
+type ExpenseSource string
+
+const (
+	// ExpenseSourceManual entries are recorded by the user
+	ExpenseSourceManual ExpenseSource = "manual"
+	// ExpenseSourcePiece entries stand for the price of a piece linked to the
+	// build and follow the piece
+	ExpenseSourcePiece ExpenseSource = "piece"
+)
+
+// BuildExpense is an entry in a build's expense ledger
+type BuildExpense struct {
+	ID          uuid.UUID     `json:"id" db:"id"`
+	BuildID     uuid.UUID     `json:"build_id" db:"build_id"`
+	Source      ExpenseSource `json:"source" db:"source"`
+	Amount      Money         `json:"amount" db:"amount_minor"`
+	Description *string       `json:"description,omitempty" db:"description"`
+	Vendor      *string       `json:"vendor,omitempty" db:"vendor"`
+	Category    *string       `json:"category,omitempty" db:"category"` // e.g., fabric, wig, shipping
+	SpentOn     time.Time     `json:"spent_on" db:"spent_on"`
+	PieceID     *uuid.UUID    `json:"piece_id,omitempty" db:"piece_id"`
+	Notes       *string       `json:"notes,omitempty" db:"notes"`
+	CreatedAt   time.Time     `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time     `json:"updated_at" db:"updated_at"`
+}
+
+// CreateBuildExpenseRequest represents the request payload for recording an
+// expense. An amount without a currency is in the build's currency.
+type CreateBuildExpenseRequest struct {
+	Amount      *MoneyInput `json:"amount" validate:"required"`
+	Description *string     `json:"description,omitempty" validate:"omitempty,max=255"`
+	Vendor      *string     `json:"vendor,omitempty" validate:"omitempty,max=255"`
+	Category    *string     `json:"category,omitempty" validate:"omitempty,max=100"`
+	SpentOn     *string     `json:"spent_on,omitempty" validate:"omitempty,datetime=2006-01-02"`
+	PieceID     *string     `json:"piece_id,omitempty" validate:"omitempty,uuid"`
+	Notes       *string     `json:"notes,omitempty" validate:"omitempty,max=2000"`
+}
+
+// UpdateBuildExpenseRequest represents the request payload for updating an
+// expense. An empty description, vendor, category, piece ID or notes clears
+// it, and an amount without a currency keeps the expense's currency.
+type UpdateBuildExpenseRequest struct {
+	Amount      *MoneyInput `json:"amount,omitempty"`
+	Description *string     `json:"description,omitempty" validate:"omitempty,max=255"`
+	Vendor      *string     `json:"vendor,omitempty" validate:"omitempty,max=255"`
+	Category    *string     `json:"category,omitempty" validate:"omitempty,max=100"`
+	SpentOn     *string     `json:"spent_on,omitempty" validate:"omitempty,datetime=2006-01-02"`
+	PieceID     *string     `json:"piece_id,omitempty"`
+	Notes       *string     `json:"notes,omitempty" validate:"omitempty,max=2000"`
+}
+
+// ExpenseBreakdown totals expense ledger entries several ways. Totals are
+// converted into one currency at the rate of the day each entry was spent,
+// except ByCurrency, which totals each currency as it is.
+type ExpenseBreakdown struct {
+	Total   Money `json:"total"`
+	Entries int   `json:"entries"`
+	// UnconvertedEntries counts entries left out of the converted totals for
+	// lack of an exchange rate
+	UnconvertedEntries int `json:"unconverted_entries"`
+	// ByBuild is left out of the breakdown of a single build
+	ByBuild []BuildExpenseTotal `json:"by_build,omitempty"`
+	// ByCategory has a group with a null name for entries without a category
+	ByCategory []ExpenseGroup   `json:"by_category"`
+	ByCurrency []ExpenseGroup   `json:"by_currency"`
+	ByMonth    []MonthlyExpense `json:"by_month"`
+}
+
+// ExpenseGroup totals the entries in a category or currency
+type ExpenseGroup struct {
+	Name    *string `json:"name"`
+	Entries int     `json:"entries"`
+	Amount  Money   `json:"amount"`
+}
+
+// BuildExpenseTotal totals one build's entries
+type BuildExpenseTotal struct {
+	BuildID uuid.UUID `json:"build_id"`
+	Name    string    `json:"name"`
+	Entries int       `json:"entries"`
+	Amount  Money     `json:"amount"`
+}
+
+// MonthlyExpense totals the entries of one month
+type MonthlyExpense struct {
+	Month   string `json:"month"` // YYYY-MM
+	Entries int    `json:"entries"`
+	Amount  Money  `json:"amount"`
+}
+
+// BudgetWarning flags a build whose spending has reached its budget
+type BudgetWarning string
+
+const (
+	// BudgetWarningNear means at least BudgetNearPercent of the budget is spent
+	BudgetWarningNear BudgetWarning = "near_budget"
+	// BudgetWarningOver means more than the budget is spent
+	BudgetWarningOver BudgetWarning = "over_budget"
+)
+
+// BudgetNearPercent is the percentage of a budget spent at which a build is
+// near its budget
+const BudgetNearPercent = 90
+
+// budgetStatus returns how much of a build's budget is left, and a warning
+// once it's nearly or entirely spent. Both are nil without a budget.
+func (b *Build) budgetStatus() (*Money, *BudgetWarning) {
+	if b.Budget == nil {
+		return nil, nil
+	}
+
+	spent := Money{Currency: b.Budget.Currency}
+	if b.Spent != nil {
+		spent = *b.Spent
+	}
+	remaining := b.Budget.Sub(spent)
+
+	var warning *BudgetWarning
+	switch {
+	case spent.MinorUnits > b.Budget.MinorUnits:
+		over := BudgetWarningOver
+		warning = &over
+	case spent.MinorUnits > 0 && spent.MinorUnits*100 >= b.Budget.MinorUnits*BudgetNearPercent:
+		near := BudgetWarningNear
+		warning = &near
+	}
+
+	return &remaining, warning
+}
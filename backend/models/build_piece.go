@@ -0,0 +1,74 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// BuildPiece links a closet piece to a build
+type BuildPiece struct {
+	ID        uuid.UUID `json:"id" db:"id"`
+	BuildID   uuid.UUID `json:"build_id" db:"build_id"`
+	PieceID   uuid.UUID `json:"piece_id" db:"piece_id"`
+	Role      *string   `json:"role,omitempty" db:"role"` // e.g., wig, top, bottom, prop, accessory
+	Quantity  int       `json:"quantity" db:"quantity"`
+	SortOrder int       `json:"sort_order" db:"sort_order"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+
+	// Piece is populated when the link is loaded together with its piece
+	Piece *Piece `json:"piece,omitempty" db:"-"`
+}
+
+// AddBuildPieceRequest represents the request payload for attaching a piece to a build
+type AddBuildPieceRequest struct {
+	PieceID   string  `json:"piece_id" validate:"required,uuid"`
+	Role      *string `json:"role,omitempty" validate:"omitempty,max=80"`
+	Quantity  *int    `json:"quantity,omitempty" validate:"omitempty,min=1"`
+	SortOrder *int    `json:"sort_order,omitempty" validate:"omitempty,min=0"`
+}
+
+// UpdateBuildPieceRequest represents the request payload for updating a build piece link
+type UpdateBuildPieceRequest struct {
+	Role      *string `json:"role,omitempty" validate:"omitempty,max=80"`
+	Quantity  *int    `json:"quantity,omitempty" validate:"omitempty,min=1"`
+	SortOrder *int    `json:"sort_order,omitempty" validate:"omitempty,min=0"`
+}
+
+// ReorderBuildPiecesRequest lists a build's piece IDs in their new order
+type ReorderBuildPiecesRequest struct {
+	PieceIDs []string `json:"piece_ids" validate:"required,dive,uuid"`
+}
+
+// BuildPieceResponse represents the response format for a build piece link
+type BuildPieceResponse struct {
+	ID        uuid.UUID      `json:"id"`
+	BuildID   uuid.UUID      `json:"build_id"`
+	PieceID   uuid.UUID      `json:"piece_id"`
+	Role      *string        `json:"role,omitempty"`
+	Quantity  int            `json:"quantity"`
+	SortOrder int            `json:"sort_order"`
+	Piece     *PieceResponse `json:"piece,omitempty"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+}
+
+// ToResponse converts a BuildPiece model to BuildPieceResponse
+func (bp *BuildPiece) ToResponse() BuildPieceResponse {
+	response := BuildPieceResponse{
+		ID:        bp.ID,
+		BuildID:   bp.BuildID,
+		PieceID:   bp.PieceID,
+		Role:      bp.Role,
+		Quantity:  bp.Quantity,
+		SortOrder: bp.SortOrder,
+		CreatedAt: bp.CreatedAt,
+		UpdatedAt: bp.UpdatedAt,
+	}
+	if bp.Piece != nil {
+		piece := bp.Piece.ToResponse()
+		response.Piece = &piece
+	}
+	return response
+}
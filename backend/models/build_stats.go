@@ -0,0 +1,50 @@
+package models
+
+// BuildStats summarizes a user's builds for the dashboard
+type BuildStats struct {
+	TotalBuilds int `json:"total_builds"`
+	// ByStatus counts builds in each status, including statuses with none
+	ByStatus map[BuildStatus]int `json:"by_status"`
+	// UpcomingBuilds counts unfinished builds due within 30 days, including overdue ones
+	UpcomingBuilds int                 `json:"upcoming_builds"`
+	Budget         BuildBudgetStats    `json:"budget"`
+	Schedule       BuildScheduleStats  `json:"schedule"`
+	Completions    []MonthlyCompletion `json:"completions_by_month"`
+}
+
+// BuildBudgetStats compares what builds were budgeted with what was spent,
+// in the home currency
+type BuildBudgetStats struct {
+	// BudgetedBuilds counts builds with a budget set
+	BudgetedBuilds int   `json:"budgeted_builds"`
+	TotalBudget    Money `json:"total_budget"`
+	TotalSpent     Money `json:"total_spent"`
+	// The averages only cover builds with a budget or spend recorded
+	AverageBudget *Money `json:"average_budget"`
+	AverageSpent  *Money `json:"average_spent"`
+	// OverBudgetBuilds counts builds that spent more than their budget, and
+	// OverBudgetAmount is how much they overspent in total
+	OverBudgetBuilds int   `json:"over_budget_builds"`
+	OverBudgetAmount Money `json:"over_budget_amount"`
+	// UnconvertedBuilds counts builds whose budget or spend is left out of
+	// the amounts for lack of an exchange rate into the home currency
+	UnconvertedBuilds int `json:"unconverted_builds"`
+}
+
+// BuildScheduleStats compares completion dates with target dates
+type BuildScheduleStats struct {
+	// CompletedOnTime and CompletedLate count builds completed by or after
+	// their target date; builds missing either date count as neither
+	CompletedOnTime int `json:"completed_on_time"`
+	CompletedLate   int `json:"completed_late"`
+	// OverdueBuilds counts unfinished builds past their target date
+	OverdueBuilds int `json:"overdue_builds"`
+}
+
+// MonthlyCompletion counts the builds completed in one month
+type MonthlyCompletion struct {
+	Month     string `json:"month"` // YYYY-MM
+	Completed int    `json:"completed"`
+	OnTime    int    `json:"on_time"`
+	Late      int    `json:"late"`
+}
@@ -0,0 +1,120 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// buildTransitions lists the statuses a build may move to from each status.
+// Builds move along idea → sourcing → wip → complete a step at a time, and
+// may step back one. Any unfinished build can be put on hold or cancelled; a
+// build on hold resumes at any active stage, a cancelled build is revived as
+// an idea, and a complete build can be reopened.
+var buildTransitions = map[BuildStatus][]BuildStatus{
+	BuildStatusIdea:      {BuildStatusSourcing, BuildStatusOnHold, BuildStatusCancelled},
+	BuildStatusSourcing:  {BuildStatusIdea, BuildStatusWIP, BuildStatusOnHold, BuildStatusCancelled},
+	BuildStatusWIP:       {BuildStatusSourcing, BuildStatusComplete, BuildStatusOnHold, BuildStatusCancelled},
+	BuildStatusComplete:  {BuildStatusWIP},
+	BuildStatusOnHold:    {BuildStatusIdea, BuildStatusSourcing, BuildStatusWIP, BuildStatusCancelled},
+	BuildStatusCancelled: {BuildStatusIdea},
+}
+
+// Transitions returns the statuses a build may move to from s
+func (s BuildStatus) Transitions() []BuildStatus {
+	return buildTransitions[s]
+}
+
+// CanTransitionTo reports whether a build may move from s to next
+func (s BuildStatus) CanTransitionTo(next BuildStatus) bool {
+	for _, allowed := range buildTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// TransitionError is returned for a status change the state machine doesn't allow
+type TransitionError struct {
+	From BuildStatus
+	To   BuildStatus
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("a build can't move from %s to %s", e.From, e.To)
+}
+
+// TransitionTo moves the build to a new status, setting its completed date to
+// today when it is completed and clearing it when it is reopened. It returns
+// the change to record, or nil if the build already has the status.
+func (b *Build) TransitionTo(next BuildStatus, note *string, now time.Time) (*BuildStatusChange, error) {
+	if next == b.Status {
+		return nil, nil
+	}
+	if !b.Status.CanTransitionTo(next) {
+		return nil, &TransitionError{From: b.Status, To: next}
+	}
+
+	previous := b.Status
+	b.Status = next
+	if next == BuildStatusComplete {
+		today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+		b.CompletedDate = &today
+	} else {
+		b.CompletedDate = nil
+	}
+
+	return &BuildStatusChange{
+		ID:         uuid.New(),
+		BuildID:    b.ID,
+		FromStatus: &previous,
+		ToStatus:   next,
+		Note:       note,
+		ChangedAt:  now,
+	}, nil
+}
+
+// BuildStatusChange is one entry of a build's status history. FromStatus is
+// nil for the status the build was created in.
+type BuildStatusChange struct {
+	ID         uuid.UUID    `json:"id" db:"id"`
+	BuildID    uuid.UUID    `json:"build_id" db:"build_id"`
+	FromStatus *BuildStatus `json:"from_status" db:"from_status"`
+	ToStatus   BuildStatus  `json:"to_status" db:"to_status"`
+	Note       *string      `json:"note,omitempty" db:"note"`
+	ChangedAt  time.Time    `json:"changed_at" db:"changed_at"`
+}
+
+// BuildStatusPeriod is a status change with how long the build stayed in the
+// status it moved to. EndedAt is nil for the build's current status, whose
+// duration runs until now.
+type BuildStatusPeriod struct {
+	BuildStatusChange
+	EndedAt         *time.Time `json:"ended_at"`
+	DurationSeconds int64      `json:"duration_seconds"`
+}
+
+// BuildStatusPeriods pairs each change of a build's history, oldest first,
+// with how long it lasted, and totals the time spent in each status
+func BuildStatusPeriods(history []*BuildStatusChange, now time.Time) ([]BuildStatusPeriod, map[BuildStatus]int64) {
+	periods := make([]BuildStatusPeriod, 0, len(history))
+	totals := make(map[BuildStatus]int64)
+
+	for i, change := range history {
+		period := BuildStatusPeriod{BuildStatusChange: *change}
+		end := now
+		if i+1 < len(history) {
+			end = history[i+1].ChangedAt
+			period.EndedAt = &end
+		}
+		if end.After(change.ChangedAt) {
+			period.DurationSeconds = int64(end.Sub(change.ChangedAt).Seconds())
+		}
+		totals[change.ToStatus] += period.DurationSeconds
+		periods = append(periods, period)
+	}
+
+	return periods, totals
+}
@@ -0,0 +1,99 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// BuildTaskKind distinguishes a to-do item from a milestone on a build's checklist
+type BuildTaskKind string
+
+const (
+	BuildTaskKindTask      BuildTaskKind = "task"
+	BuildTaskKindMilestone BuildTaskKind = "milestone"
+)
+
+// IsValidTaskKind checks if a task kind string is valid
+func IsValidTaskKind(kind string) bool {
+	switch BuildTaskKind(kind) {
+	case BuildTaskKindTask, BuildTaskKindMilestone:
+		return true
+	default:
+		return false
+	}
+}
+
+// BuildTask is a task or milestone on a build, optionally about one of its pieces
+type BuildTask struct {
+	ID        uuid.UUID     `json:"id" db:"id"`
+	BuildID   uuid.UUID     `json:"build_id" db:"build_id"`
+	BuildName string        `json:"build_name" db:"-"` // so the task feed can say which build a task is for
+	Kind      BuildTaskKind `json:"kind" db:"kind"`
+	Title     string        `json:"title" db:"title"`
+	DueDate   *time.Time    `json:"due_date,omitempty" db:"due_date"`
+	Done      bool          `json:"done" db:"done"`
+	DoneAt    *time.Time    `json:"done_at,omitempty" db:"done_at"`
+	SortOrder int           `json:"sort_order" db:"sort_order"`
+	PieceID   *uuid.UUID    `json:"piece_id,omitempty" db:"piece_id"`
+	CreatedAt time.Time     `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time     `json:"updated_at" db:"updated_at"`
+}
+
+// SetDone marks the task done or not done, recording when it was done
+func (t *BuildTask) SetDone(done bool, now time.Time) {
+	if done == t.Done {
+		return
+	}
+	t.Done = done
+	if done {
+		t.DoneAt = &now
+	} else {
+		t.DoneAt = nil
+	}
+}
+
+// CreateBuildTaskRequest represents the request payload for adding a task to a build
+type CreateBuildTaskRequest struct {
+	Kind      *string `json:"kind,omitempty" validate:"omitempty,oneof=task milestone"`
+	Title     string  `json:"title" validate:"required,min=1,max=255"`
+	DueDate   *string `json:"due_date,omitempty" validate:"omitempty,datetime=2006-01-02"`
+	Done      *bool   `json:"done,omitempty"`
+	SortOrder *int    `json:"sort_order,omitempty" validate:"omitempty,min=0"`
+	PieceID   *string `json:"piece_id,omitempty" validate:"omitempty,uuid"`
+}
+
+// UpdateBuildTaskRequest represents the request payload for updating a build task.
+// An empty due date or piece ID clears it.
+type UpdateBuildTaskRequest struct {
+	Kind      *string `json:"kind,omitempty" validate:"omitempty,oneof=task milestone"`
+	Title     *string `json:"title,omitempty" validate:"omitempty,min=1,max=255"`
+	DueDate   *string `json:"due_date,omitempty"`
+	Done      *bool   `json:"done,omitempty"`
+	SortOrder *int    `json:"sort_order,omitempty" validate:"omitempty,min=0"`
+	PieceID   *string `json:"piece_id,omitempty"`
+}
+
+// ReorderBuildTasksRequest lists a build's task IDs in their new order
+type ReorderBuildTasksRequest struct {
+	TaskIDs []string `json:"task_ids" validate:"required,dive,uuid"`
+}
+
+// TaskProgress counts a build's tasks and how many are done
+type TaskProgress struct {
+	Total int
+	Done  int
+}
+
+// ApplyTaskProgress fills in the progress percent. A complete build is
+// always 100% and a build without tasks is 0% until it's complete.
+func (b *BuildResponse) ApplyTaskProgress(p TaskProgress) {
+	switch {
+	case b.Status == BuildStatusComplete:
+		b.ProgressPercent = 100
+	case p.Total > 0:
+		b.ProgressPercent = p.Done * 100 / p.Total
+	default:
+		b.ProgressPercent = 0
+	}
+}
@@ -0,0 +1,264 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ScheduleEntryType represents the kind of a convention schedule entry
+type ScheduleEntryType string
+
+const (
+	ScheduleEntryCosplay    ScheduleEntryType = "cosplay"
+	ScheduleEntryPhotoshoot ScheduleEntryType = "photoshoot"
+	ScheduleEntryMeetup     ScheduleEntryType = "meetup"
+	ScheduleEntryOther      ScheduleEntryType = "other"
+)
+
+// Valid reports whether t is a known schedule entry type
+func (t ScheduleEntryType) Valid() bool {
+	switch t {
+	case ScheduleEntryCosplay, ScheduleEntryPhotoshoot, ScheduleEntryMeetup, ScheduleEntryOther:
+		return true
+	}
+	return false
+}
+
+// Convention represents a convention the user is attending
+type Convention struct {
+	ID                uuid.UUID `json:"id" db:"id"`
+	UserID            uuid.UUID `json:"user_id" db:"user_id"`
+	Name              string    `json:"name" db:"name"`
+	Venue             *string   `json:"venue,omitempty" db:"venue"`
+	City              *string   `json:"city,omitempty" db:"city"`
+	StartDate         time.Time `json:"start_date" db:"start_date"`
+	EndDate           time.Time `json:"end_date" db:"end_date"`
+	Timezone          string    `json:"timezone" db:"timezone"` // IANA name
+	HotelName         *string   `json:"hotel_name,omitempty" db:"hotel_name"`
+	HotelAddress      *string   `json:"hotel_address,omitempty" db:"hotel_address"`
+	HotelConfirmation *string   `json:"hotel_confirmation,omitempty" db:"hotel_confirmation"`
+	Website           *string   `json:"website,omitempty" db:"website"`
+	Notes             *string   `json:"notes,omitempty" db:"notes"`
+	CreatedAt         time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// ScheduleEntry is a timed slot on one day of a convention. Times are
+// "HH:MM" in the convention's timezone.
+type ScheduleEntry struct {
+	ID           uuid.UUID         `json:"id" db:"id"`
+	ConventionID uuid.UUID         `json:"convention_id" db:"convention_id"`
+	EntryType    ScheduleEntryType `json:"entry_type" db:"entry_type"`
+	Day          time.Time         `json:"day" db:"day"`
+	StartTime    string            `json:"start_time" db:"start_time"`
+	EndTime      string            `json:"end_time" db:"end_time"`
+	BuildID      *uuid.UUID        `json:"build_id,omitempty" db:"build_id"`
+	CoordID      *uuid.UUID        `json:"coord_id,omitempty" db:"coord_id"`
+	Title        *string           `json:"title,omitempty" db:"title"`
+	Location     *string           `json:"location,omitempty" db:"location"`
+	Notes        *string           `json:"notes,omitempty" db:"notes"`
+	CreatedAt    time.Time         `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time         `json:"updated_at" db:"updated_at"`
+}
+
+// Outfit reports whether the entry has the user wearing a build or coord
+func (e *ScheduleEntry) Outfit() bool {
+	return e.BuildID != nil || e.CoordID != nil
+}
+
+// CreateConventionRequest represents the request payload for creating a convention
+type CreateConventionRequest struct {
+	Name              string  `json:"name" validate:"required,min=1,max=255"`
+	Venue             *string `json:"venue,omitempty" validate:"omitempty,max=255"`
+	City              *string `json:"city,omitempty" validate:"omitempty,max=120"`
+	StartDate         string  `json:"start_date" validate:"required,datetime=2006-01-02"`
+	EndDate           string  `json:"end_date" validate:"required,datetime=2006-01-02"`
+	Timezone          *string `json:"timezone,omitempty" validate:"omitempty,timezone"`
+	HotelName         *string `json:"hotel_name,omitempty" validate:"omitempty,max=255"`
+	HotelAddress      *string `json:"hotel_address,omitempty" validate:"omitempty,max=1000"`
+	HotelConfirmation *string `json:"hotel_confirmation,omitempty" validate:"omitempty,max=120"`
+	Website           *string `json:"website,omitempty" validate:"omitempty,url"`
+	Notes             *string `json:"notes,omitempty" validate:"omitempty,max=2000"`
+}
+
+// UpdateConventionRequest represents the request payload for updating a convention
+type UpdateConventionRequest struct {
+	Name              *string `json:"name,omitempty" validate:"omitempty,min=1,max=255"`
+	Venue             *string `json:"venue,omitempty" validate:"omitempty,max=255"`
+	City              *string `json:"city,omitempty" validate:"omitempty,max=120"`
+	StartDate         *string `json:"start_date,omitempty" validate:"omitempty,datetime=2006-01-02"`
+	EndDate           *string `json:"end_date,omitempty" validate:"omitempty,datetime=2006-01-02"`
+	Timezone          *string `json:"timezone,omitempty" validate:"omitempty,timezone"`
+	HotelName         *string `json:"hotel_name,omitempty" validate:"omitempty,max=255"`
+	HotelAddress      *string `json:"hotel_address,omitempty" validate:"omitempty,max=1000"`
+	HotelConfirmation *string `json:"hotel_confirmation,omitempty" validate:"omitempty,max=120"`
+	Website           *string `json:"website,omitempty" validate:"omitempty,url"`
+	Notes             *string `json:"notes,omitempty" validate:"omitempty,max=2000"`
+}
+
+// ValidateFields checks the convention doesn't end before it starts
+func (r CreateConventionRequest) ValidateFields() []FieldError {
+	if fe := checkDateStrings(&r.StartDate, &r.EndDate, "start_date", "end_date"); fe != nil {
+		return []FieldError{*fe}
+	}
+	return nil
+}
+
+// ValidateFields checks dates given together are in order. Dates are checked
+// against the saved convention once merged.
+func (r UpdateConventionRequest) ValidateFields() []FieldError {
+	if fe := checkDateStrings(r.StartDate, r.EndDate, "start_date", "end_date"); fe != nil {
+		return []FieldError{*fe}
+	}
+	return nil
+}
+
+// CreateScheduleEntryRequest represents the request payload for adding a schedule entry
+type CreateScheduleEntryRequest struct {
+	EntryType *string `json:"entry_type,omitempty" validate:"omitempty,oneof=cosplay photoshoot meetup other"`
+	Day       string  `json:"day" validate:"required,datetime=2006-01-02"`
+	StartTime string  `json:"start_time" validate:"required,datetime=15:04"`
+	EndTime   string  `json:"end_time" validate:"required,datetime=15:04"`
+	BuildID   *string `json:"build_id,omitempty" validate:"omitempty,uuid"`
+	CoordID   *string `json:"coord_id,omitempty" validate:"omitempty,uuid"`
+	Title     *string `json:"title,omitempty" validate:"omitempty,max=255"`
+	Location  *string `json:"location,omitempty" validate:"omitempty,max=255"`
+	Notes     *string `json:"notes,omitempty" validate:"omitempty,max=2000"`
+}
+
+// UpdateScheduleEntryRequest represents the request payload for updating a schedule entry.
+// An empty build_id or coord_id unlinks it.
+type UpdateScheduleEntryRequest struct {
+	EntryType *string `json:"entry_type,omitempty" validate:"omitempty,oneof=cosplay photoshoot meetup other"`
+	Day       *string `json:"day,omitempty" validate:"omitempty,datetime=2006-01-02"`
+	StartTime *string `json:"start_time,omitempty" validate:"omitempty,datetime=15:04"`
+	EndTime   *string `json:"end_time,omitempty" validate:"omitempty,datetime=15:04"`
+	BuildID   *string `json:"build_id,omitempty" validate:"omitempty,uuid"`
+	CoordID   *string `json:"coord_id,omitempty" validate:"omitempty,uuid"`
+	Title     *string `json:"title,omitempty" validate:"omitempty,max=255"`
+	Location  *string `json:"location,omitempty" validate:"omitempty,max=255"`
+	Notes     *string `json:"notes,omitempty" validate:"omitempty,max=2000"`
+}
+
+// ScheduleEntryResponse represents the response format for a schedule entry
+type ScheduleEntryResponse struct {
+	ID        uuid.UUID         `json:"id"`
+	EntryType ScheduleEntryType `json:"entry_type"`
+	Day       time.Time         `json:"day"`
+	StartTime string            `json:"start_time"`
+	EndTime   string            `json:"end_time"`
+	BuildID   *uuid.UUID        `json:"build_id,omitempty"`
+	CoordID   *uuid.UUID        `json:"coord_id,omitempty"`
+	Title     *string           `json:"title,omitempty"`
+	Location  *string           `json:"location,omitempty"`
+	Notes     *string           `json:"notes,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+}
+
+// ToResponse converts a ScheduleEntry model to ScheduleEntryResponse
+func (e *ScheduleEntry) ToResponse() ScheduleEntryResponse {
+	return ScheduleEntryResponse{
+		ID:        e.ID,
+		EntryType: e.EntryType,
+		Day:       e.Day,
+		StartTime: e.StartTime,
+		EndTime:   e.EndTime,
+		BuildID:   e.BuildID,
+		CoordID:   e.CoordID,
+		Title:     e.Title,
+		Location:  e.Location,
+		Notes:     e.Notes,
+		CreatedAt: e.CreatedAt,
+		UpdatedAt: e.UpdatedAt,
+	}
+}
+
+// ScheduleDay groups the schedule entries of one convention day
+type ScheduleDay struct {
+	Day     time.Time               `json:"day"`
+	Entries []ScheduleEntryResponse `json:"entries"`
+}
+
+// ConventionResponse represents the response format for convention data
+type ConventionResponse struct {
+	ID                uuid.UUID     `json:"id"`
+	UserID            uuid.UUID     `json:"user_id"`
+	Name              string        `json:"name"`
+	Venue             *string       `json:"venue,omitempty"`
+	City              *string       `json:"city,omitempty"`
+	StartDate         time.Time     `json:"start_date"`
+	EndDate           time.Time     `json:"end_date"`
+	Timezone          string        `json:"timezone"`
+	HotelName         *string       `json:"hotel_name,omitempty"`
+	HotelAddress      *string       `json:"hotel_address,omitempty"`
+	HotelConfirmation *string       `json:"hotel_confirmation,omitempty"`
+	Website           *string       `json:"website,omitempty"`
+	Notes             *string       `json:"notes,omitempty"`
+	Schedule          []ScheduleDay `json:"schedule,omitempty"`
+	CreatedAt         time.Time     `json:"created_at"`
+	UpdatedAt         time.Time     `json:"updated_at"`
+}
+
+// ToResponse converts a Convention model to ConventionResponse
+func (c *Convention) ToResponse() ConventionResponse {
+	return ConventionResponse{
+		ID:                c.ID,
+		UserID:            c.UserID,
+		Name:              c.Name,
+		Venue:             c.Venue,
+		City:              c.City,
+		StartDate:         c.StartDate,
+		EndDate:           c.EndDate,
+		Timezone:          c.Timezone,
+		HotelName:         c.HotelName,
+		HotelAddress:      c.HotelAddress,
+		HotelConfirmation: c.HotelConfirmation,
+		Website:           c.Website,
+		Notes:             c.Notes,
+		CreatedAt:         c.CreatedAt,
+		UpdatedAt:         c.UpdatedAt,
+	}
+}
+
+// Covers reports whether day falls within the convention's dates
+func (c *Convention) Covers(day time.Time) bool {
+	return !day.Before(c.StartDate) && !day.After(c.EndDate)
+}
+
+// Schedule groups entries, already sorted by day and time, into one
+// ScheduleDay per convention day. Days without entries are included.
+func (c *Convention) Schedule(entries []*ScheduleEntry) []ScheduleDay {
+	var days []ScheduleDay
+	for day := c.StartDate; !day.After(c.EndDate); day = day.AddDate(0, 0, 1) {
+		days = append(days, ScheduleDay{Day: day, Entries: []ScheduleEntryResponse{}})
+	}
+	for _, entry := range entries {
+		index := int(entry.Day.Sub(c.StartDate).Hours() / 24)
+		if index >= 0 && index < len(days) {
+			days[index].Entries = append(days[index].Entries, entry.ToResponse())
+		}
+	}
+	return days
+}
+
+// AtRiskBuild is a build scheduled for a convention that isn't complete in time
+type AtRiskBuild struct {
+	Build             *Build
+	FirstScheduledDay time.Time
+}
+
+// AtRiskBuildResponse represents the response format for an at-risk build
+type AtRiskBuildResponse struct {
+	Build             BuildResponse `json:"build"`
+	FirstScheduledDay time.Time     `json:"first_scheduled_day"`
+}
+
+// ToResponse converts an AtRiskBuild to AtRiskBuildResponse
+func (a *AtRiskBuild) ToResponse() AtRiskBuildResponse {
+	return AtRiskBuildResponse{
+		Build:             a.Build.ToResponse(),
+		FirstScheduledDay: a.FirstScheduledDay,
+	}
+}
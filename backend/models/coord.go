@@ -0,0 +1,147 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Coord represents an outfit composed of layered pieces on the coord builder canvas
+type Coord struct {
+	ID              uuid.UUID    `json:"id" db:"id"`
+	UserID          uuid.UUID    `json:"user_id" db:"user_id"`
+	Name            string       `json:"name" db:"name"`
+	Description     *string      `json:"description,omitempty" db:"description"`
+	BuildID         *uuid.UUID   `json:"build_id,omitempty" db:"build_id"`
+	CanvasWidth     *int         `json:"canvas_width,omitempty" db:"canvas_width"`
+	CanvasHeight    *int         `json:"canvas_height,omitempty" db:"canvas_height"`
+	BackgroundColor *string      `json:"background_color,omitempty" db:"background_color"`
+	Layers          []CoordLayer `json:"layers" db:"-"`
+	CreatedAt       time.Time    `json:"created_at" db:"created_at"`
+	UpdatedAt       time.Time    `json:"updated_at" db:"updated_at"`
+}
+
+// CoordLayer places a piece on a coord's canvas
+type CoordLayer struct {
+	ID        uuid.UUID `json:"id" db:"id"`
+	CoordID   uuid.UUID `json:"coord_id" db:"coord_id"`
+	PieceID   uuid.UUID `json:"piece_id" db:"piece_id"`
+	ZIndex    int       `json:"z_index" db:"z_index"` // higher layers are drawn on top
+	PositionX float64   `json:"position_x" db:"position_x"`
+	PositionY float64   `json:"position_y" db:"position_y"`
+	Scale     float64   `json:"scale" db:"scale"`
+	Rotation  float64   `json:"rotation" db:"rotation"` // degrees, clockwise
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+
+	// Piece is populated when the layer is loaded together with its piece
+	Piece *Piece `json:"piece,omitempty" db:"-"`
+}
+
+// CoordLayerRequest describes one layer in a create or update coord request
+type CoordLayerRequest struct {
+	PieceID   string   `json:"piece_id" validate:"required,uuid"`
+	ZIndex    *int     `json:"z_index,omitempty"`
+	PositionX float64  `json:"position_x"`
+	PositionY float64  `json:"position_y"`
+	Scale     *float64 `json:"scale,omitempty" validate:"omitempty,gt=0"`
+	Rotation  float64  `json:"rotation" validate:"min=-360,max=360"`
+}
+
+// CreateCoordRequest represents the request payload for creating a coord
+type CreateCoordRequest struct {
+	Name            string              `json:"name" validate:"required,min=1,max=255"`
+	Description     *string             `json:"description,omitempty" validate:"omitempty,max=1000"`
+	BuildID         *string             `json:"build_id,omitempty" validate:"omitempty,uuid"`
+	CanvasWidth     *int                `json:"canvas_width,omitempty" validate:"omitempty,min=1"`
+	CanvasHeight    *int                `json:"canvas_height,omitempty" validate:"omitempty,min=1"`
+	BackgroundColor *string             `json:"background_color,omitempty" validate:"omitempty,max=20"`
+	Layers          []CoordLayerRequest `json:"layers,omitempty" validate:"dive"`
+}
+
+// UpdateCoordRequest represents the request payload for updating a coord.
+// When Layers is present it replaces every existing layer.
+type UpdateCoordRequest struct {
+	Name            *string             `json:"name,omitempty" validate:"omitempty,min=1,max=255"`
+	Description     *string             `json:"description,omitempty" validate:"omitempty,max=1000"`
+	BuildID         *string             `json:"build_id,omitempty" validate:"omitempty,uuid"`
+	CanvasWidth     *int                `json:"canvas_width,omitempty" validate:"omitempty,min=1"`
+	CanvasHeight    *int                `json:"canvas_height,omitempty" validate:"omitempty,min=1"`
+	BackgroundColor *string             `json:"background_color,omitempty" validate:"omitempty,max=20"`
+	Layers          []CoordLayerRequest `json:"layers,omitempty" validate:"omitempty,dive"`
+}
+
+// CoordLayerResponse represents the response format for a coord layer
+type CoordLayerResponse struct {
+	ID        uuid.UUID      `json:"id"`
+	PieceID   uuid.UUID      `json:"piece_id"`
+	ZIndex    int            `json:"z_index"`
+	PositionX float64        `json:"position_x"`
+	PositionY float64        `json:"position_y"`
+	Scale     float64        `json:"scale"`
+	Rotation  float64        `json:"rotation"`
+	Piece     *PieceResponse `json:"piece,omitempty"`
+}
+
+// CoordResponse represents the response format for coord data
+type CoordResponse struct {
+	ID              uuid.UUID            `json:"id"`
+	UserID          uuid.UUID            `json:"user_id"`
+	Name            string               `json:"name"`
+	Description     *string              `json:"description,omitempty"`
+	BuildID         *uuid.UUID           `json:"build_id,omitempty"`
+	CanvasWidth     *int                 `json:"canvas_width,omitempty"`
+	CanvasHeight    *int                 `json:"canvas_height,omitempty"`
+	BackgroundColor *string              `json:"background_color,omitempty"`
+	Layers          []CoordLayerResponse `json:"layers"`
+	CreatedAt       time.Time            `json:"created_at"`
+	UpdatedAt       time.Time            `json:"updated_at"`
+}
+
+// ToResponse converts a Coord model to CoordResponse
+func (c *Coord) ToResponse() CoordResponse {
+	layers := make([]CoordLayerResponse, 0, len(c.Layers))
+	for _, layer := range c.Layers {
+		layerResponse := CoordLayerResponse{
+			ID:        layer.ID,
+			PieceID:   layer.PieceID,
+			ZIndex:    layer.ZIndex,
+			PositionX: layer.PositionX,
+			PositionY: layer.PositionY,
+			Scale:     layer.Scale,
+			Rotation:  layer.Rotation,
+		}
+		if layer.Piece != nil {
+			piece := layer.Piece.ToResponse()
+			layerResponse.Piece = &piece
+		}
+		layers = append(layers, layerResponse)
+	}
+
+	return CoordResponse{
+		ID:              c.ID,
+		UserID:          c.UserID,
+		Name:            c.Name,
+		Description:     c.Description,
+		BuildID:         c.BuildID,
+		CanvasWidth:     c.CanvasWidth,
+		CanvasHeight:    c.CanvasHeight,
+		BackgroundColor: c.BackgroundColor,
+		Layers:          layers,
+		CreatedAt:       c.CreatedAt,
+		UpdatedAt:       c.UpdatedAt,
+	}
+}
+
+// PieceIDs returns the distinct pieces referenced by the coord's layers
+func (c *Coord) PieceIDs() []uuid.UUID {
+	seen := make(map[uuid.UUID]bool, len(c.Layers))
+	ids := make([]uuid.UUID, 0, len(c.Layers))
+	for _, layer := range c.Layers {
+		if !seen[layer.PieceID] {
+			seen[layer.PieceID] = true
+			ids = append(ids, layer.PieceID)
+		}
+	}
+	return ids
+}
@@ -0,0 +1,95 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// JobStatus represents where a background job is in its lifecycle
+type JobStatus string
+
+const (
+	JobStatusQueued    JobStatus = "queued"
+	JobStatusRunning   JobStatus = "running"
+	JobStatusSucceeded JobStatus = "succeeded"
+	// JobStatusDead is the dead-letter state of a job that failed every attempt
+	JobStatusDead JobStatus = "dead"
+)
+
+// Done reports whether the job has finished, successfully or not
+func (s JobStatus) Done() bool {
+	return s == JobStatusSucceeded || s == JobStatusDead
+}
+
+// DefaultJobMaxAttempts is how many times a job runs before it's dead
+const DefaultJobMaxAttempts = 5
+
+// JobKindPieceImage removes the background of a piece's uploaded image
+const JobKindPieceImage = "piece_image"
+
+// Job represents a unit of background work
+type Job struct {
+	ID          uuid.UUID       `json:"id" db:"id"`
+	UserID      uuid.UUID       `json:"user_id" db:"user_id"`
+	Kind        string          `json:"kind" db:"kind"`
+	Status      JobStatus       `json:"status" db:"status"`
+	Payload     json.RawMessage `json:"payload" db:"payload"`
+	Result      json.RawMessage `json:"result,omitempty" db:"result"`
+	Progress    int             `json:"progress" db:"progress"` // 0-100
+	Attempts    int             `json:"attempts" db:"attempts"`
+	MaxAttempts int             `json:"max_attempts" db:"max_attempts"`
+	RunAt       time.Time       `json:"run_at" db:"run_at"`
+	LockedAt    *time.Time      `json:"locked_at,omitempty" db:"locked_at"`
+	LastError   *string         `json:"last_error,omitempty" db:"last_error"`
+	CompletedAt *time.Time      `json:"completed_at,omitempty" db:"completed_at"`
+	CreatedAt   time.Time       `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at" db:"updated_at"`
+}
+
+// PieceImageJob is the payload of a JobKindPieceImage job
+type PieceImageJob struct {
+	PieceID     uuid.UUID `json:"piece_id"`
+	ImageKey    string    `json:"image_key"`
+	ContentType string    `json:"content_type"`
+}
+
+// JobResponse represents the response format for job data
+type JobResponse struct {
+	ID          uuid.UUID       `json:"id"`
+	Kind        string          `json:"kind"`
+	Status      JobStatus       `json:"status"`
+	Progress    int             `json:"progress"`
+	Attempts    int             `json:"attempts"`
+	MaxAttempts int             `json:"max_attempts"`
+	Result      json.RawMessage `json:"result,omitempty"`
+	LastError   *string         `json:"last_error,omitempty"`
+	NextRunAt   *time.Time      `json:"next_run_at,omitempty"`
+	CompletedAt *time.Time      `json:"completed_at,omitempty"`
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+}
+
+// ToResponse converts a Job model to JobResponse
+func (j *Job) ToResponse() JobResponse {
+	response := JobResponse{
+		ID:          j.ID,
+		Kind:        j.Kind,
+		Status:      j.Status,
+		Progress:    j.Progress,
+		Attempts:    j.Attempts,
+		MaxAttempts: j.MaxAttempts,
+		Result:      j.Result,
+		LastError:   j.LastError,
+		CompletedAt: j.CompletedAt,
+		CreatedAt:   j.CreatedAt,
+		UpdatedAt:   j.UpdatedAt,
+	}
+	// A queued job that has already been attempted is waiting out its backoff
+	if j.Status == JobStatusQueued && j.Attempts > 0 {
+		runAt := j.RunAt
+		response.NextRunAt = &runAt
+	}
+	return response
+}
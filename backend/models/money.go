@@ -0,0 +1,236 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultCurrency is the home currency of new users, and the currency of
+// amounts recorded before amounts had one
+const DefaultCurrency = "USD"
+
+// MaxMinorUnits is the largest amount accepted, in minor units. It keeps
+// amounts clear of the sentinels NULLs sort as and of overflow on conversion.
+const MaxMinorUnits = 999_999_999_999_999
+
+// currencyExponents are the supported ISO 4217 currencies and how many
+// decimal places their minor unit has. They match the currencies table as
+// first seeded, and RegisterCurrencies adds whatever else it holds.
+var currencyExponents = map[string]int{
+	"AUD": 2, "BHD": 3, "BRL": 2, "CAD": 2, "CHF": 2, "CLP": 0,
+	"CNY": 2, "CZK": 2, "DKK": 2, "EUR": 2, "GBP": 2, "HKD": 2,
+	"HUF": 2, "IDR": 2, "ILS": 2, "INR": 2, "ISK": 0, "JPY": 0,
+	"KRW": 0, "KWD": 3, "MXN": 2, "MYR": 2, "NOK": 2, "NZD": 2,
+	"PHP": 2, "PLN": 2, "SEK": 2, "SGD": 2, "THB": 2, "TRY": 2,
+	"TWD": 2, "USD": 2, "VND": 0, "ZAR": 2,
+}
+
+var (
+	// ErrUnsupportedCurrency is returned for a currency code that isn't supported
+	ErrUnsupportedCurrency = errors.New("unsupported currency")
+	// ErrInvalidAmount is returned for an amount that isn't a plain decimal number
+	ErrInvalidAmount = errors.New("amount must be a decimal number such as 12.34")
+	// ErrAmountTooPrecise is returned for an amount with more decimal places
+	// than the minor unit of its currency
+	ErrAmountTooPrecise = errors.New("amount has more decimal places than its currency allows")
+	// ErrAmountOutOfRange is returned for an amount that is negative or too large
+	ErrAmountOutOfRange = errors.New("amount must not be negative or too large")
+)
+
+// RegisterCurrencies adds currencies, or changes their exponents. It's meant
+// to be called once at startup with the currencies table, before amounts are
+// parsed or formatted.
+func RegisterCurrencies(exponents map[string]int) {
+	for code, exponent := range exponents {
+		currencyExponents[code] = exponent
+	}
+}
+
+// IsSupportedCurrency checks if a currency code is supported
+func IsSupportedCurrency(code string) bool {
+	_, ok := currencyExponents[code]
+	return ok
+}
+
+// SupportedCurrencies lists the supported currencies in code order
+func SupportedCurrencies() []Currency {
+	currencies := make([]Currency, 0, len(currencyExponents))
+	for code, exponent := range currencyExponents {
+		currencies = append(currencies, Currency{Code: code, Exponent: exponent})
+	}
+	sort.Slice(currencies, func(i, j int) bool {
+		return currencies[i].Code < currencies[j].Code
+	})
+	return currencies
+}
+
+// NormalizeCurrency upper-cases a currency code and checks it's supported
+func NormalizeCurrency(code string) (string, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if !IsSupportedCurrency(code) {
+		return "", ErrUnsupportedCurrency
+	}
+	return code, nil
+}
+
+// Money is an exact amount counted in the minor unit of its currency: cents
+// for USD, yen for JPY. It's serialized as
+// {"amount": "12.34", "currency": "USD", "minor_units": 1234}.
+type Money struct {
+	MinorUnits int64
+	Currency   string // ISO 4217 code
+}
+
+// ParseMoney parses a decimal amount such as "12.34" in a currency. The
+// amount may not have more decimal places than the currency's minor unit,
+// beyond trailing zeros.
+func ParseMoney(amount, currency string) (Money, error) {
+	exponent, ok := currencyExponents[currency]
+	if !ok {
+		return Money{}, ErrUnsupportedCurrency
+	}
+
+	digits := strings.TrimPrefix(amount, "-")
+	negative := len(digits) < len(amount)
+	whole, frac, hasPoint := strings.Cut(digits, ".")
+	if whole == "" || (hasPoint && frac == "") || !isDigits(whole) || !isDigits(frac) {
+		return Money{}, ErrInvalidAmount
+	}
+	if len(frac) > exponent {
+		if strings.TrimRight(frac[exponent:], "0") != "" {
+			return Money{}, ErrAmountTooPrecise
+		}
+		frac = frac[:exponent]
+	}
+	frac += strings.Repeat("0", exponent-len(frac))
+
+	minor := strings.TrimLeft(whole+frac, "0")
+	if minor == "" {
+		return Money{Currency: currency}, nil
+	}
+	if len(minor) > len(strconv.Itoa(MaxMinorUnits)) {
+		return Money{}, ErrAmountOutOfRange
+	}
+	units, err := strconv.ParseInt(minor, 10, 64)
+	if err != nil || units > MaxMinorUnits {
+		return Money{}, ErrAmountOutOfRange
+	}
+	if negative {
+		units = -units
+	}
+
+	return Money{MinorUnits: units, Currency: currency}, nil
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// String formats the amount in major units with the currency's decimal
+// places, e.g. "12.34" or "1500"
+func (m Money) String() string {
+	exponent := currencyExponents[m.Currency]
+
+	units := m.MinorUnits
+	sign := ""
+	if units < 0 {
+		sign, units = "-", -units
+	}
+	digits := strconv.FormatInt(units, 10)
+	if exponent == 0 {
+		return sign + digits
+	}
+	if len(digits) <= exponent {
+		digits = strings.Repeat("0", exponent-len(digits)+1) + digits
+	}
+	split := len(digits) - exponent
+	return sign + digits[:split] + "." + digits[split:]
+}
+
+// MarshalJSON writes the amount as an exact decimal string alongside its
+// minor units
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Amount     string `json:"amount"`
+		Currency   string `json:"currency"`
+		MinorUnits int64  `json:"minor_units"`
+	}{m.String(), m.Currency, m.MinorUnits})
+}
+
+// Sub returns m less other, which must be in the same currency
+func (m Money) Sub(other Money) Money {
+	return Money{MinorUnits: m.MinorUnits - other.MinorUnits, Currency: m.Currency}
+}
+
+// MoneyInput is an amount in a request. It's either an object such as
+// {"amount": "12.34", "currency": "JPY"}, with the amount a string or a
+// number, or a bare amount in the fallback currency of its field.
+type MoneyInput struct {
+	Amount   json.Number `json:"amount"`
+	Currency *string     `json:"currency,omitempty" validate:"omitempty,iso4217"`
+}
+
+// UnmarshalJSON accepts the object form or a bare amount
+func (m *MoneyInput) UnmarshalJSON(data []byte) error {
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] != '{' {
+		return json.Unmarshal(trimmed, &m.Amount)
+	}
+	type plain MoneyInput
+	return json.Unmarshal(data, (*plain)(m))
+}
+
+// Money parses the input, in fallback when it names no currency. Negative
+// amounts are rejected.
+func (m MoneyInput) Money(fallback string) (Money, error) {
+	currency := fallback
+	if m.Currency != nil {
+		var err error
+		if currency, err = NormalizeCurrency(*m.Currency); err != nil {
+			return Money{}, err
+		}
+	}
+	if m.Amount == "" {
+		return Money{}, ErrInvalidAmount
+	}
+
+	money, err := ParseMoney(m.Amount.String(), currency)
+	if err != nil {
+		return Money{}, err
+	}
+	if money.MinorUnits < 0 {
+		return Money{}, ErrAmountOutOfRange
+	}
+	return money, nil
+}
+
+// ExchangeRate is how many units of Quote one unit of Base buys on a day.
+// Rate is kept as a decimal string so it's stored exactly.
+type ExchangeRate struct {
+	Base        string    `json:"base"`
+	Quote       string    `json:"quote"`
+	EffectiveOn time.Time `json:"effective_on"`
+	Rate        string    `json:"rate"`
+}
+
+// Currency is a supported currency and how many decimal places its minor
+// unit has
+type Currency struct {
+	Code     string `json:"code"`
+	Exponent int    `json:"exponent"`
+}
+
+// UpdateHomeCurrencyRequest represents the request payload for changing the
+// home currency
+type UpdateHomeCurrencyRequest struct {
+	HomeCurrency string `json:"home_currency" validate:"required,iso4217"`
+}
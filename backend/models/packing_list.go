@@ -0,0 +1,77 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UncategorizedPacking is the packing list group for pieces without a category
+const UncategorizedPacking = "uncategorized"
+
+// PackingItem is a piece that needs to be packed for a convention
+type PackingItem struct {
+	Piece    *Piece
+	Quantity int         // largest quantity any scheduled build needs
+	BuildIDs []uuid.UUID // scheduled builds that use the piece
+	Packed   bool
+	PackedAt *time.Time
+}
+
+// SetPackedRequest represents the request payload for ticking a packing list item
+type SetPackedRequest struct {
+	Packed bool `json:"packed"`
+}
+
+// PackingItemResponse represents the response format for a packing list item
+type PackingItemResponse struct {
+	Piece    PieceResponse `json:"piece"`
+	Quantity int           `json:"quantity"`
+	BuildIDs []uuid.UUID   `json:"build_ids"`
+	Packed   bool          `json:"packed"`
+	PackedAt *time.Time    `json:"packed_at,omitempty"`
+}
+
+// ToResponse converts a PackingItem to PackingItemResponse
+func (p *PackingItem) ToResponse() PackingItemResponse {
+	return PackingItemResponse{
+		Piece:    p.Piece.ToResponse(),
+		Quantity: p.Quantity,
+		BuildIDs: p.BuildIDs,
+		Packed:   p.Packed,
+		PackedAt: p.PackedAt,
+	}
+}
+
+// PackingCategory groups the packing list items of one piece category
+type PackingCategory struct {
+	Category    string                `json:"category"`
+	Items       []PackingItemResponse `json:"items"`
+	PackedCount int                   `json:"packed_count"`
+}
+
+// GroupPackingItems groups items, already sorted by category, into one
+// PackingCategory per category
+func GroupPackingItems(items []*PackingItem) []PackingCategory {
+	categories := []PackingCategory{}
+	index := make(map[string]int)
+	for _, item := range items {
+		category := UncategorizedPacking
+		if item.Piece.Category != nil && *item.Piece.Category != "" {
+			category = *item.Piece.Category
+		}
+
+		i, ok := index[category]
+		if !ok {
+			i = len(categories)
+			index[category] = i
+			categories = append(categories, PackingCategory{Category: category, Items: []PackingItemResponse{}})
+		}
+
+		categories[i].Items = append(categories[i].Items, item.ToResponse())
+		if item.Packed {
+			categories[i].PackedCount++
+		}
+	}
+	return categories
+}
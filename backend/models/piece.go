@@ -12,16 +12,33 @@ type Piece struct {
 	Name             string     `json:"name" db:"name"`
 	Description      *string    `json:"description,omitempty" db:"description"`
 	ImageURL         *string    `json:"image_url,omitempty" db:"image_url"`
+	CutoutURL        *string    `json:"cutout_url,omitempty" db:"image_bg_removed_url"`
 	ThumbnailURL     *string    `json:"thumbnail_url,omitempty" db:"thumbnail_url"`
+	// Storage keys of images uploaded through the backend. Responses carry
+	// signed URLs for these in place of the URL fields.
+	ImageKey         *string    `json:"-" db:"image_key"`
+	CutoutKey        *string    `json:"-" db:"image_bg_removed_key"`
+	ThumbnailKey     *string    `json:"-" db:"thumbnail_key"`
 	Category         *string    `json:"category,omitempty" db:"category"`
 	Tags             []string   `json:"tags,omitempty" db:"tags"`
 	SourceLink       *string    `json:"source_link,omitempty" db:"source_link"`
 	PurchaseDate     *time.Time `json:"purchase_date,omitempty" db:"purchase_date"`
-	Price            *float64   `json:"price,omitempty" db:"price"`
+	Price            *Money     `json:"price,omitempty" db:"price_minor"`
 	CreatedAt        time.Time  `json:"created_at" db:"created_at"`
 	UpdatedAt        time.Time  `json:"updated_at" db:"updated_at"`
 }
 
+// BlobKeys returns the storage keys of the piece's uploaded images
+func (p *Piece) BlobKeys() []string {
+	var keys []string
+	for _, key := range []*string{p.ImageKey, p.CutoutKey, p.ThumbnailKey} {
+		if key != nil {
+			keys = append(keys, *key)
+		}
+	}
+	return keys
+}
+
 // CreatePieceRequest represents the request payload for creating a piece
 type CreatePieceRequest struct {
 	Name         string    `json:"name" validate:"required,min=1,max=255"`
@@ -29,10 +46,10 @@ type CreatePieceRequest struct {
 	ImageURL     *string   `json:"image_url,omitempty" validate:"omitempty,url"`
 	ThumbnailURL *string   `json:"thumbnail_url,omitempty" validate:"omitempty,url"`
 	Category     *string   `json:"category,omitempty" validate:"omitempty,max=100"`
-	Tags         []string  `json:"tags,omitempty"`
+	Tags         []string  `json:"tags,omitempty" validate:"omitempty,dive,max=100"`
 	SourceLink   *string   `json:"source_link,omitempty" validate:"omitempty,url"`
 	PurchaseDate *string   `json:"purchase_date,omitempty" validate:"omitempty,datetime=2006-01-02"`
-	Price        *float64  `json:"price,omitempty" validate:"omitempty,min=0"`
+	Price        *MoneyInput `json:"price,omitempty"` // in the home currency without one
 }
 
 // UpdatePieceRequest represents the request payload for updating a piece
@@ -42,10 +59,10 @@ type UpdatePieceRequest struct {
 	ImageURL     *string   `json:"image_url,omitempty" validate:"omitempty,url"`
 	ThumbnailURL *string   `json:"thumbnail_url,omitempty" validate:"omitempty,url"`
 	Category     *string   `json:"category,omitempty" validate:"omitempty,max=100"`
-	Tags         []string  `json:"tags,omitempty"`
+	Tags         []string  `json:"tags,omitempty" validate:"omitempty,dive,max=100"`
 	SourceLink   *string   `json:"source_link,omitempty" validate:"omitempty,url"`
 	PurchaseDate *string   `json:"purchase_date,omitempty" validate:"omitempty,datetime=2006-01-02"`
-	Price        *float64  `json:"price,omitempty" validate:"omitempty,min=0"`
+	Price        *MoneyInput `json:"price,omitempty"` // in the home currency without one
 }
 
 // PieceResponse represents the response format for piece data
@@ -55,12 +72,15 @@ type PieceResponse struct {
 	Name         string     `json:"name"`
 	Description  *string    `json:"description,omitempty"`
 	ImageURL     *string    `json:"image_url,omitempty"`
+	CutoutURL    *string    `json:"cutout_url,omitempty"`
 	ThumbnailURL *string    `json:"thumbnail_url,omitempty"`
 	Category     *string    `json:"category,omitempty"`
 	Tags         []string   `json:"tags,omitempty"`
 	SourceLink   *string    `json:"source_link,omitempty"`
 	PurchaseDate *time.Time `json:"purchase_date,omitempty"`
-	Price        *float64   `json:"price,omitempty"`
+	Price        *Money     `json:"price,omitempty"`
+	TimesWorn    int        `json:"times_worn"`
+	LastWorn     *time.Time `json:"last_worn,omitempty"`
 	CreatedAt    time.Time  `json:"created_at"`
 	UpdatedAt    time.Time  `json:"updated_at"`
 }
@@ -73,6 +93,7 @@ func (p *Piece) ToResponse() PieceResponse {
 		Name:         p.Name,
 		Description:  p.Description,
 		ImageURL:     p.ImageURL,
+		CutoutURL:    p.CutoutURL,
 		ThumbnailURL: p.ThumbnailURL,
 		Category:     p.Category,
 		Tags:         p.Tags,
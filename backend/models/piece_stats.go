@@ -0,0 +1,56 @@
+package models
+
+import "github.com/google/uuid"
+
+// PieceStats summarizes a user's closet
+type PieceStats struct {
+	TotalPieces int `json:"total_pieces"`
+	// TotalSpent sums the price of every piece with one recorded, in the home
+	// currency. Each price is converted at the rate of its purchase date.
+	TotalSpent Money `json:"total_spent"`
+	// UnconvertedPieces counts priced pieces left out of spend for lack of an
+	// exchange rate into the home currency
+	UnconvertedPieces int `json:"unconverted_pieces"`
+	// ByCategory has a group with a null name for pieces without a category
+	ByCategory []PieceGroupStats `json:"by_category"`
+	// ByTag counts a piece under each of its tags
+	ByTag        []PieceGroupStats `json:"by_tag"`
+	SpendByMonth []MonthlySpend    `json:"spend_by_month"`
+	// MostReused and LeastReused rank pieces linked to at least one build by
+	// how many builds use them
+	MostReused  []PieceReuse `json:"most_reused"`
+	LeastReused []PieceReuse `json:"least_reused"`
+	// UnusedPieces counts pieces not linked to any build, and Unused lists
+	// the first of them by name
+	UnusedPieces int          `json:"unused_pieces"`
+	Unused       []PieceReuse `json:"unused"`
+}
+
+// PieceGroupStats counts the pieces in a category or with a tag, and what
+// they cost
+type PieceGroupStats struct {
+	Name   *string `json:"name"`
+	Pieces int     `json:"pieces"`
+	Spent  Money   `json:"spent"`
+}
+
+// MonthlySpend totals the pieces purchased in one month
+type MonthlySpend struct {
+	Month  string `json:"month"` // YYYY-MM
+	Pieces int    `json:"pieces"`
+	Spent  Money  `json:"spent"`
+}
+
+// PieceReuse is how many builds a piece is linked to
+type PieceReuse struct {
+	ID       uuid.UUID `json:"id"`
+	Name     string    `json:"name"`
+	Category *string   `json:"category,omitempty"`
+	Builds   int       `json:"builds"`
+}
+
+// CategoryCount is a category in use and how many pieces it has
+type CategoryCount struct {
+	Category string `json:"category"`
+	Count    int    `json:"count"`
+}
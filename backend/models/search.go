@@ -0,0 +1,14 @@
+package models
+
+import "github.com/google/uuid"
+
+// SearchHit is one result of a search across a user's closet
+type SearchHit struct {
+	Type  string    `json:"type"`
+	ID    uuid.UUID `json:"id"`
+	Title string    `json:"title"`
+	// Headline is an excerpt of the matching text, HTML-escaped, with matched
+	// words wrapped in <mark> tags
+	Headline string  `json:"headline"`
+	Rank     float64 `json:"rank"`
+}
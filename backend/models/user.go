@@ -0,0 +1,82 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// User represents an account
+type User struct {
+	ID           uuid.UUID `json:"id" db:"id"`
+	Email        string    `json:"email" db:"email"`
+	Username     *string   `json:"username,omitempty" db:"username"`
+	DisplayName  *string   `json:"display_name,omitempty" db:"display_name"`
+	AvatarURL    *string   `json:"avatar_url,omitempty" db:"avatar_url"`
+	PasswordHash *string   `json:"-" db:"password_hash"` // nil for accounts created by an external auth provider
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// RefreshToken is a server-side record of an issued refresh token
+type RefreshToken struct {
+	ID         uuid.UUID  `json:"id" db:"id"`
+	UserID     uuid.UUID  `json:"user_id" db:"user_id"`
+	FamilyID   uuid.UUID  `json:"family_id" db:"family_id"` // shared by every rotation of one login
+	TokenHash  string     `json:"-" db:"token_hash"`
+	ExpiresAt  time.Time  `json:"expires_at" db:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+	ReplacedBy *uuid.UUID `json:"replaced_by,omitempty" db:"replaced_by"`
+	UserAgent  *string    `json:"user_agent,omitempty" db:"user_agent"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+}
+
+// RegisterRequest represents the request payload for creating an account
+type RegisterRequest struct {
+	Email       string  `json:"email" validate:"required,email,max=255"`
+	Password    string  `json:"password" validate:"required,min=8,max=72"`
+	Username    *string `json:"username,omitempty" validate:"omitempty,min=1,max=50"`
+	DisplayName *string `json:"display_name,omitempty" validate:"omitempty,max=100"`
+}
+
+// LoginRequest represents the request payload for logging in
+type LoginRequest struct {
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password" validate:"required"`
+}
+
+// RefreshRequest represents the request payload for refreshing or revoking a session
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+// UserResponse represents the response format for user data
+type UserResponse struct {
+	ID          uuid.UUID `json:"id"`
+	Email       string    `json:"email"`
+	Username    *string   `json:"username,omitempty"`
+	DisplayName *string   `json:"display_name,omitempty"`
+	AvatarURL   *string   `json:"avatar_url,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// ToResponse converts a User model to UserResponse
+func (u *User) ToResponse() UserResponse {
+	return UserResponse{
+		ID:          u.ID,
+		Email:       u.Email,
+		Username:    u.Username,
+		DisplayName: u.DisplayName,
+		AvatarURL:   u.AvatarURL,
+		CreatedAt:   u.CreatedAt,
+	}
+}
+
+// AuthResponse is returned by register, login and refresh
+type AuthResponse struct {
+	AccessToken  string        `json:"access_token"`
+	RefreshToken string        `json:"refresh_token"`
+	TokenType    string        `json:"token_type"`
+	ExpiresIn    int           `json:"expires_in"` // access token lifetime in seconds
+	User         *UserResponse `json:"user,omitempty"`
+}
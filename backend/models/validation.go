@@ -0,0 +1,63 @@
+package models
+
+import "time"
+
+// FieldError is a request field that breaks one of its rules
+type FieldError struct {
+	// Field is the JSON path of the field, e.g. "name", "budget.currency" or "tags[2]"
+	Field string `json:"field"`
+	// Code names the rule broken, e.g. "required", "max" or "date_order"
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// ValidationError lists the fields of a request that break their rules. It's
+// sent as 422 Unprocessable Entity.
+type ValidationError struct {
+	Fields []FieldError `json:"fields"`
+}
+
+func (e *ValidationError) Error() string {
+	return "validation failed"
+}
+
+// NewFieldError returns a validation error for a single field
+func NewFieldError(field, code, message string) *ValidationError {
+	return &ValidationError{Fields: []FieldError{{Field: field, Code: code, Message: message}}}
+}
+
+// CrossFieldValidator is implemented by requests with rules spanning several
+// fields. They're checked once every field is valid on its own.
+type CrossFieldValidator interface {
+	ValidateFields() []FieldError
+}
+
+// CheckDateOrder reports a field error when end is before start. Either may
+// be nil, which passes.
+func CheckDateOrder(start, end *time.Time, startField, endField string) *FieldError {
+	if start == nil || end == nil || !end.Before(*start) {
+		return nil
+	}
+	return &FieldError{
+		Field:   endField,
+		Code:    "date_order",
+		Message: endField + " must not be before " + startField,
+	}
+}
+
+// checkDateStrings is CheckDateOrder for YYYY-MM-DD request fields. Empty or
+// malformed dates pass, as they're reported by their own rules.
+func checkDateStrings(start, end *string, startField, endField string) *FieldError {
+	if start == nil || end == nil {
+		return nil
+	}
+	startDate, err := time.Parse("2006-01-02", *start)
+	if err != nil {
+		return nil
+	}
+	endDate, err := time.Parse("2006-01-02", *end)
+	if err != nil {
+		return nil
+	}
+	return CheckDateOrder(&startDate, &endDate, startField, endField)
+}
@@ -0,0 +1,71 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Category is a piece category in a user's vocabulary. Pieces refer to it by
+// name, and any casing of the name is stored in the category's spelling.
+type Category struct {
+	ID        uuid.UUID  `json:"id" db:"id"`
+	UserID    uuid.UUID  `json:"user_id" db:"user_id"`
+	Name      string     `json:"name" db:"name"`
+	Color     *string    `json:"color,omitempty" db:"color"` // #RRGGBB
+	ParentID  *uuid.UUID `json:"parent_id,omitempty" db:"parent_id"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at" db:"updated_at"`
+
+	// PieceCount is how many pieces are in the category
+	PieceCount int `json:"piece_count" db:"-"`
+}
+
+// Tag is a piece or build tag in a user's vocabulary
+type Tag struct {
+	ID        uuid.UUID `json:"id" db:"id"`
+	UserID    uuid.UUID `json:"user_id" db:"user_id"`
+	Name      string    `json:"name" db:"name"`
+	Color     *string   `json:"color,omitempty" db:"color"` // #RRGGBB
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+
+	// PieceCount and BuildCount are how many pieces and builds have the tag
+	PieceCount int `json:"piece_count" db:"-"`
+	BuildCount int `json:"build_count" db:"-"`
+}
+
+// CreateCategoryRequest represents the request payload for creating a category
+type CreateCategoryRequest struct {
+	Name     string  `json:"name" validate:"required,min=1,max=100"`
+	Color    *string `json:"color,omitempty" validate:"omitempty,hexcolor"`
+	ParentID *string `json:"parent_id,omitempty" validate:"omitempty,uuid"`
+}
+
+// UpdateCategoryRequest represents the request payload for updating a category.
+// Renaming it renames the category of its pieces; an empty color or parent_id
+// clears it.
+type UpdateCategoryRequest struct {
+	Name     *string `json:"name,omitempty" validate:"omitempty,min=1,max=100"`
+	Color    *string `json:"color,omitempty"`
+	ParentID *string `json:"parent_id,omitempty"`
+}
+
+// CreateTagRequest represents the request payload for creating a tag
+type CreateTagRequest struct {
+	Name  string  `json:"name" validate:"required,min=1,max=100"`
+	Color *string `json:"color,omitempty" validate:"omitempty,hexcolor"`
+}
+
+// UpdateTagRequest represents the request payload for updating a tag.
+// Renaming it renames it on every piece and build; an empty color clears it.
+type UpdateTagRequest struct {
+	Name  *string `json:"name,omitempty" validate:"omitempty,min=1,max=100"`
+	Color *string `json:"color,omitempty"`
+}
+
+// MergeRequest represents the request payload for merging a category or tag
+// into another one
+type MergeRequest struct {
+	Into string `json:"into" validate:"required,uuid"`
+}
@@ -0,0 +1,114 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// WearLog records a day a piece and/or a build was worn
+type WearLog struct {
+	ID              uuid.UUID  `json:"id" db:"id"`
+	UserID          uuid.UUID  `json:"user_id" db:"user_id"`
+	PieceID         *uuid.UUID `json:"piece_id,omitempty" db:"piece_id"`
+	BuildID         *uuid.UUID `json:"build_id,omitempty" db:"build_id"`
+	WornOn          time.Time  `json:"worn_on" db:"worn_on"`
+	Location        *string    `json:"location,omitempty" db:"location"`
+	EventName       *string    `json:"event_name,omitempty" db:"event_name"`
+	DurationMinutes *int       `json:"duration_minutes,omitempty" db:"duration_minutes"`
+	Notes           *string    `json:"notes,omitempty" db:"notes"`
+	CreatedAt       time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// CreateWearLogRequest represents the request payload for creating a wear log.
+// At least one of PieceID and BuildID is required.
+type CreateWearLogRequest struct {
+	PieceID         *string `json:"piece_id,omitempty" validate:"omitempty,uuid"`
+	BuildID         *string `json:"build_id,omitempty" validate:"omitempty,uuid"`
+	WornOn          string  `json:"worn_on" validate:"required,datetime=2006-01-02"`
+	Location        *string `json:"location,omitempty" validate:"omitempty,max=160"`
+	EventName       *string `json:"event_name,omitempty" validate:"omitempty,max=160"`
+	DurationMinutes *int    `json:"duration_minutes,omitempty" validate:"omitempty,min=0"`
+	Notes           *string `json:"notes,omitempty" validate:"omitempty,max=2000"`
+}
+
+// ValidateFields checks the wear log links a piece or a build
+func (r CreateWearLogRequest) ValidateFields() []FieldError {
+	if (r.PieceID == nil || *r.PieceID == "") && (r.BuildID == nil || *r.BuildID == "") {
+		return []FieldError{WearLogTargetRequired()}
+	}
+	return nil
+}
+
+// WearLogTargetRequired is the field error for a wear log with neither a
+// piece nor a build
+func WearLogTargetRequired() FieldError {
+	return FieldError{
+		Field:   "piece_id",
+		Code:    "required_without",
+		Message: "piece_id is required without build_id",
+	}
+}
+
+// UpdateWearLogRequest represents the request payload for updating a wear log.
+// An empty piece_id or build_id unlinks it, as long as the other one remains.
+type UpdateWearLogRequest struct {
+	PieceID         *string `json:"piece_id,omitempty" validate:"omitempty,uuid"`
+	BuildID         *string `json:"build_id,omitempty" validate:"omitempty,uuid"`
+	WornOn          *string `json:"worn_on,omitempty" validate:"omitempty,datetime=2006-01-02"`
+	Location        *string `json:"location,omitempty" validate:"omitempty,max=160"`
+	EventName       *string `json:"event_name,omitempty" validate:"omitempty,max=160"`
+	DurationMinutes *int    `json:"duration_minutes,omitempty" validate:"omitempty,min=0"`
+	Notes           *string `json:"notes,omitempty" validate:"omitempty,max=2000"`
+}
+
+// WearLogResponse represents the response format for wear log data
+type WearLogResponse struct {
+	ID              uuid.UUID  `json:"id"`
+	UserID          uuid.UUID  `json:"user_id"`
+	PieceID         *uuid.UUID `json:"piece_id,omitempty"`
+	BuildID         *uuid.UUID `json:"build_id,omitempty"`
+	WornOn          time.Time  `json:"worn_on"`
+	Location        *string    `json:"location,omitempty"`
+	EventName       *string    `json:"event_name,omitempty"`
+	DurationMinutes *int       `json:"duration_minutes,omitempty"`
+	Notes           *string    `json:"notes,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+// ToResponse converts a WearLog model to WearLogResponse
+func (w *WearLog) ToResponse() WearLogResponse {
+	return WearLogResponse{
+		ID:              w.ID,
+		UserID:          w.UserID,
+		PieceID:         w.PieceID,
+		BuildID:         w.BuildID,
+		WornOn:          w.WornOn,
+		Location:        w.Location,
+		EventName:       w.EventName,
+		DurationMinutes: w.DurationMinutes,
+		Notes:           w.Notes,
+		CreatedAt:       w.CreatedAt,
+		UpdatedAt:       w.UpdatedAt,
+	}
+}
+
+// WearSummary aggregates the wear logs of a piece or build
+type WearSummary struct {
+	TimesWorn int
+	LastWorn  *time.Time
+}
+
+// ApplyWearSummary fills in the times worn and last worn fields
+func (p *PieceResponse) ApplyWearSummary(s WearSummary) {
+	p.TimesWorn = s.TimesWorn
+	p.LastWorn = s.LastWorn
+}
+
+// ApplyWearSummary fills in the times worn and last worn fields
+func (b *BuildResponse) ApplyWearSummary(s WearSummary) {
+	b.TimesWorn = s.TimesWorn
+	b.LastWorn = s.LastWorn
+}
@@ -0,0 +1,151 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// WishlistStatus represents the status of a wishlist item
+type WishlistStatus string
+
+const (
+	WishlistStatusActive   WishlistStatus = "active"
+	WishlistStatusAcquired WishlistStatus = "acquired"
+)
+
+// WishlistItem represents a piece the user wants to buy
+type WishlistItem struct {
+	ID              uuid.UUID      `json:"id" db:"id"`
+	UserID          uuid.UUID      `json:"user_id" db:"user_id"`
+	Name            string         `json:"name" db:"name"`
+	Description     *string        `json:"description,omitempty" db:"description"`
+	Category        *string        `json:"category,omitempty" db:"category"`
+	Tags            []string       `json:"tags,omitempty" db:"tags"`
+	SourceLink      *string        `json:"source_link,omitempty" db:"source_link"`
+	ImageURL        *string        `json:"image_url,omitempty" db:"image_url"`
+	TargetPrice     *Money         `json:"target_price,omitempty" db:"target_price_minor"`
+	CurrentPrice    *Money         `json:"current_price,omitempty" db:"current_price_minor"`
+	Priority        int            `json:"priority" db:"priority"` // 1-5 scale
+	BuildID         *uuid.UUID     `json:"build_id,omitempty" db:"build_id"`
+	Status          WishlistStatus `json:"status" db:"status"`
+	AcquiredPieceID *uuid.UUID     `json:"acquired_piece_id,omitempty" db:"acquired_piece_id"`
+	AcquiredAt      *time.Time     `json:"acquired_at,omitempty" db:"acquired_at"`
+	BelowTarget     bool           `json:"below_target" db:"-"` // worked out by the database, which converts between currencies
+	CreatedAt       time.Time      `json:"created_at" db:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at" db:"updated_at"`
+}
+
+// WishlistPrice is one observed price of a wishlist item
+type WishlistPrice struct {
+	ID             uuid.UUID `json:"id" db:"id"`
+	WishlistItemID uuid.UUID `json:"wishlist_item_id" db:"wishlist_item_id"`
+	Price          Money     `json:"price" db:"price_minor"`
+	RecordedAt     time.Time `json:"recorded_at" db:"recorded_at"`
+}
+
+// CreateWishlistItemRequest represents the request payload for creating a wishlist item
+type CreateWishlistItemRequest struct {
+	Name         string      `json:"name" validate:"required,min=1,max=255"`
+	Description  *string     `json:"description,omitempty" validate:"omitempty,max=1000"`
+	Category     *string     `json:"category,omitempty" validate:"omitempty,max=100"`
+	Tags         []string    `json:"tags,omitempty" validate:"omitempty,dive,max=100"`
+	SourceLink   *string     `json:"source_link,omitempty" validate:"omitempty,url"`
+	ImageURL     *string     `json:"image_url,omitempty" validate:"omitempty,url"`
+	TargetPrice  *MoneyInput `json:"target_price,omitempty"`  // in the home currency without one
+	CurrentPrice *MoneyInput `json:"current_price,omitempty"` // in the home currency without one
+	Priority     *int        `json:"priority,omitempty" validate:"omitempty,min=1,max=5"`
+	BuildID      *string     `json:"build_id,omitempty" validate:"omitempty,uuid"`
+}
+
+// UpdateWishlistItemRequest represents the request payload for updating a wishlist item.
+// A new current_price is appended to the item's price history.
+type UpdateWishlistItemRequest struct {
+	Name         *string     `json:"name,omitempty" validate:"omitempty,min=1,max=255"`
+	Description  *string     `json:"description,omitempty" validate:"omitempty,max=1000"`
+	Category     *string     `json:"category,omitempty" validate:"omitempty,max=100"`
+	Tags         []string    `json:"tags,omitempty" validate:"omitempty,dive,max=100"`
+	SourceLink   *string     `json:"source_link,omitempty" validate:"omitempty,url"`
+	ImageURL     *string     `json:"image_url,omitempty" validate:"omitempty,url"`
+	TargetPrice  *MoneyInput `json:"target_price,omitempty"`  // in the home currency without one
+	CurrentPrice *MoneyInput `json:"current_price,omitempty"` // in the home currency without one
+	Priority     *int        `json:"priority,omitempty" validate:"omitempty,min=1,max=5"`
+	BuildID      *string     `json:"build_id,omitempty" validate:"omitempty,uuid"`
+}
+
+// AcquireWishlistItemRequest represents the request payload for turning a
+// wishlist item into a closet piece
+type AcquireWishlistItemRequest struct {
+	Price        *MoneyInput `json:"price,omitempty"` // defaults to the current price; in the home currency without one
+	PurchaseDate *string     `json:"purchase_date,omitempty" validate:"omitempty,datetime=2006-01-02"`
+	Remove       bool        `json:"remove"` // delete the wishlist item instead of archiving it
+}
+
+// WishlistItemResponse represents the response format for wishlist item data
+type WishlistItemResponse struct {
+	ID              uuid.UUID       `json:"id"`
+	UserID          uuid.UUID       `json:"user_id"`
+	Name            string          `json:"name"`
+	Description     *string         `json:"description,omitempty"`
+	Category        *string         `json:"category,omitempty"`
+	Tags            []string        `json:"tags,omitempty"`
+	SourceLink      *string         `json:"source_link,omitempty"`
+	ImageURL        *string         `json:"image_url,omitempty"`
+	TargetPrice     *Money          `json:"target_price,omitempty"`
+	CurrentPrice    *Money          `json:"current_price,omitempty"`
+	Priority        int             `json:"priority"`
+	BuildID         *uuid.UUID      `json:"build_id,omitempty"`
+	Status          WishlistStatus  `json:"status"`
+	AcquiredPieceID *uuid.UUID      `json:"acquired_piece_id,omitempty"`
+	AcquiredAt      *time.Time      `json:"acquired_at,omitempty"`
+	BelowTarget     bool            `json:"below_target"`
+	PriceHistory    []WishlistPrice `json:"price_history,omitempty"`
+	CreatedAt       time.Time       `json:"created_at"`
+	UpdatedAt       time.Time       `json:"updated_at"`
+}
+
+// ToResponse converts a WishlistItem model to WishlistItemResponse
+func (w *WishlistItem) ToResponse() WishlistItemResponse {
+	return WishlistItemResponse{
+		ID:              w.ID,
+		UserID:          w.UserID,
+		Name:            w.Name,
+		Description:     w.Description,
+		Category:        w.Category,
+		Tags:            w.Tags,
+		SourceLink:      w.SourceLink,
+		ImageURL:        w.ImageURL,
+		TargetPrice:     w.TargetPrice,
+		CurrentPrice:    w.CurrentPrice,
+		Priority:        w.Priority,
+		BuildID:         w.BuildID,
+		Status:          w.Status,
+		AcquiredPieceID: w.AcquiredPieceID,
+		AcquiredAt:      w.AcquiredAt,
+		BelowTarget:     w.BelowTarget,
+		CreatedAt:       w.CreatedAt,
+		UpdatedAt:       w.UpdatedAt,
+	}
+}
+
+// ToPiece builds the closet piece an acquired wishlist item becomes. Without
+// a price it takes the current price, in the currency it was seen in.
+func (w *WishlistItem) ToPiece(price *Money, purchaseDate *time.Time) *Piece {
+	if price == nil {
+		price = w.CurrentPrice
+	}
+	return &Piece{
+		ID:           uuid.New(),
+		UserID:       w.UserID,
+		Name:         w.Name,
+		Description:  w.Description,
+		ImageURL:     w.ImageURL,
+		Category:     w.Category,
+		Tags:         w.Tags,
+		SourceLink:   w.SourceLink,
+		PurchaseDate: purchaseDate,
+		Price:        price,
+		CreatedAt:    time.Now(),
+		UpdatedAt:    time.Now(),
+	}
+}
@@ -0,0 +1,59 @@
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path"
+	"time"
+)
+
+// BlobStore keeps uploaded files such as piece and build images. Keys are
+// slash-separated paths, for example "users/<id>/<sha256>.png".
+type BlobStore interface {
+	// Put stores data under key, replacing anything already stored there
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	// Get reads the blob stored under key
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Exists reports whether a blob is stored under key
+	Exists(ctx context.Context, key string) (bool, error)
+	// Delete removes the blob stored under key. Missing blobs are not an error.
+	Delete(ctx context.Context, key string) error
+	// SignedURL returns a download URL for key that stops working after ttl
+	SignedURL(key string, ttl time.Duration) (string, error)
+}
+
+// ContentKey returns the content-addressed key for data under prefix, so
+// identical files always share a key
+func ContentKey(prefix string, data []byte, ext string) string {
+	sum := sha256.Sum256(data)
+	return path.Join(prefix, hex.EncodeToString(sum[:])+"."+ext)
+}
+
+// PutContent stores data under its content-addressed key and returns the key.
+// The upload is skipped when an identical blob is already stored.
+func PutContent(ctx context.Context, store BlobStore, prefix string, data []byte, contentType, ext string) (string, error) {
+	key := ContentKey(prefix, data, ext)
+
+	exists, err := store.Exists(ctx, key)
+	if err != nil {
+		return "", err
+	}
+	if exists {
+		return key, nil
+	}
+
+	if err := store.Put(ctx, key, data, contentType); err != nil {
+		return "", err
+	}
+	return key, nil
+}
+
+// validKey reports whether key is a clean relative path
+func validKey(key string) error {
+	if key == "" || path.Clean("/" + key)[1:] != key {
+		return fmt.Errorf("invalid storage key %q", key)
+	}
+	return nil
+}
@@ -0,0 +1,164 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrInvalidSignature is returned for download URLs that were tampered with
+	ErrInvalidSignature = errors.New("invalid signature")
+	// ErrURLExpired is returned for download URLs past their expiry
+	ErrURLExpired = errors.New("download URL has expired")
+)
+
+// LocalStore keeps blobs in a directory on disk. They are served by the
+// uploads handler, which only accepts URLs signed by SignedURL.
+type LocalStore struct {
+	dir        string
+	baseURL    string
+	signingKey []byte
+}
+
+// NewLocalStore creates the storage directory if needed. baseURL is where the
+// uploads handler is mounted, and signingKey signs download URLs.
+func NewLocalStore(dir, baseURL string, signingKey []byte) (*LocalStore, error) {
+	if len(signingKey) == 0 {
+		return nil, errors.New("local storage needs a signing key")
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create storage directory: %w", err)
+	}
+	return &LocalStore{
+		dir:        dir,
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		signingKey: signingKey,
+	}, nil
+}
+
+// Put writes data under key
+func (s *LocalStore) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	filePath, err := s.Path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(filePath), 0o755); err != nil {
+		return fmt.Errorf("failed to create storage directory: %w", err)
+	}
+
+	// Write to a temporary file first so a failed write never leaves a partial blob behind
+	tmp, err := os.CreateTemp(filepath.Dir(filePath), ".upload-*")
+	if err != nil {
+		return fmt.Errorf("failed to store blob: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to store blob: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to store blob: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return fmt.Errorf("failed to store blob: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), filePath); err != nil {
+		return fmt.Errorf("failed to store blob: %w", err)
+	}
+
+	return nil
+}
+
+// Get reads the blob stored under key
+func (s *LocalStore) Get(ctx context.Context, key string) ([]byte, error) {
+	filePath, err := s.Path(key)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read blob: %w", err)
+	}
+	return data, nil
+}
+
+// Exists reports whether a blob is stored under key
+func (s *LocalStore) Exists(ctx context.Context, key string) (bool, error) {
+	filePath, err := s.Path(key)
+	if err != nil {
+		return false, err
+	}
+	if _, err := os.Stat(filePath); err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to check blob: %w", err)
+	}
+	return true, nil
+}
+
+// Delete removes the blob stored under key
+func (s *LocalStore) Delete(ctx context.Context, key string) error {
+	filePath, err := s.Path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(filePath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete blob: %w", err)
+	}
+	return nil
+}
+
+// SignedURL returns a URL for key carrying its expiry and an HMAC of both
+func (s *LocalStore) SignedURL(key string, ttl time.Duration) (string, error) {
+	if err := validKey(key); err != nil {
+		return "", err
+	}
+
+	expires := strconv.FormatInt(time.Now().Add(ttl).Unix(), 10)
+	query := url.Values{}
+	query.Set("expires", expires)
+	query.Set("signature", s.sign(key, expires))
+
+	return s.baseURL + "/" + key + "?" + query.Encode(), nil
+}
+
+// Verify checks the expiry and signature of a download URL for key
+func (s *LocalStore) Verify(key, expires, signature string) error {
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	if !hmac.Equal([]byte(signature), []byte(s.sign(key, expires))) {
+		return ErrInvalidSignature
+	}
+	if time.Now().Unix() > expiresAt {
+		return ErrURLExpired
+	}
+	return nil
+}
+
+func (s *LocalStore) sign(key, expires string) string {
+	mac := hmac.New(sha256.New, s.signingKey)
+	mac.Write([]byte(key + "\n" + expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Path maps a key to its file inside the store's directory
+func (s *LocalStore) Path(key string) (string, error) {
+	if err := validKey(key); err != nil {
+		return "", err
+	}
+	return filepath.Join(s.dir, filepath.FromSlash(key)), nil
+}
@@ -25,7 +25,6 @@ BUILD_DATA='{
   "status": "idea",
   "priority": 3,
   "budget": 150.00,
-  "spent": 0.00,
   "start_date": "2024-01-15",
   "target_date": "2024-06-15",
   "tags": ["test", "cosplay", "build"],
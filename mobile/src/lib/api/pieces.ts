@@ -2,6 +2,16 @@ import axios from 'axios';
 
 const API_BASE_URL = process.env.EXPO_PUBLIC_API_URL || 'http://localhost:8080';
 
+// An exact amount; amount is a decimal string in the currency's major units
+export interface Money {
+  amount: string;
+  currency: string;
+  minor_units: number;
+}
+
+// A bare amount is in the user's home currency
+export type MoneyInput = number | string | { amount: number | string; currency?: string };
+
 export interface Piece {
   id: string;
   name: string;
@@ -12,7 +22,7 @@ export interface Piece {
   tags?: string[];
   source_link?: string;
   purchase_date?: string;
-  price?: number;
+  price?: Money;
   created_at: string;
   updated_at: string;
 }
@@ -26,7 +36,7 @@ export interface CreatePieceRequest {
   tags?: string[];
   source_link?: string;
   purchase_date?: string;
-  price?: number;
+  price?: MoneyInput;
 }
 
 export interface UpdatePieceRequest {
@@ -38,7 +48,7 @@ export interface UpdatePieceRequest {
   tags?: string[];
   source_link?: string;
   purchase_date?: string;
-  price?: number;
+  price?: MoneyInput;
 }
 
 export interface PiecesResponse {
@@ -48,8 +58,13 @@ export interface PiecesResponse {
   offset: number;
 }
 
+export interface CategoryCount {
+  category: string;
+  count: number;
+}
+
 export interface CategoriesResponse {
-  categories: string[];
+  categories: CategoryCount[];
 }
 
 class PiecesAPI {
@@ -57,7 +57,7 @@ export default function ClosetScreen() {
       image_url: 'https://via.placeholder.com/300x300/f8b4d1/ffffff?text=Pink+Wig',
       category: 'wig',
       tags: ['anime', 'pink', 'long'],
-      price: 45.99,
+      price: { amount: '45.99', currency: 'USD', minor_units: 4599 },
       created_at: '2024-01-15T10:30:00Z',
       updated_at: '2024-01-15T10:30:00Z',
     },
@@ -68,7 +68,7 @@ export default function ClosetScreen() {
       image_url: 'https://via.placeholder.com/300x300/fce7f3/ec4899?text=School+Dress',
       category: 'dress',
       tags: ['school', 'uniform', 'blue'],
-      price: 89.99,
+      price: { amount: '89.99', currency: 'USD', minor_units: 8999 },
       created_at: '2024-01-14T15:20:00Z',
       updated_at: '2024-01-14T15:20:00Z',
     },
@@ -79,7 +79,7 @@ export default function ClosetScreen() {
       image_url: 'https://via.placeholder.com/300x300/e0e7ff/8b5cf6?text=Magic+Wand',
       category: 'prop',
       tags: ['magic', 'sparkly', 'wand'],
-      price: 25.50,
+      price: { amount: '25.50', currency: 'USD', minor_units: 2550 },
       created_at: '2024-01-13T09:15:00Z',
       updated_at: '2024-01-13T09:15:00Z',
     },
@@ -158,7 +158,7 @@ export default function ClosetScreen() {
         )}
         {item.price && (
           <View style={styles.priceTag}>
-            <Text style={styles.priceText}>${item.price.toFixed(2)}</Text>
+            <Text style={styles.priceText}>{item.price.amount} {item.price.currency}</Text>
           </View>
         )}
       </View>
@@ -348,7 +348,7 @@ export default function ClosetScreen() {
                   {selectedPiece.price && (
                     <View style={styles.modalDetailItem}>
                       <Text style={styles.modalDetailLabel}>Price</Text>
-                      <Text style={styles.modalDetailValue}>${selectedPiece.price.toFixed(2)}</Text>
+                      <Text style={styles.modalDetailValue}>{selectedPiece.price.amount} {selectedPiece.price.currency}</Text>
                     </View>
                   )}
                   